@@ -1,33 +1,150 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"github.com/caarlos0/env/v6"
 	"github.com/sergeysynergy/hardtest/internal/api/handlers"
+	"github.com/sergeysynergy/hardtest/internal/api/rpc"
 	"github.com/sergeysynergy/hardtest/internal/api/server"
 	"github.com/sergeysynergy/hardtest/internal/db"
 	"log"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/sergeysynergy/hardtest/internal/gophermart"
 )
 
 type config struct {
-	Addr                 string `env:"RUN_ADDRESS"`
-	DatabaseURI          string `env:"DATABASE_URI"`
-	AccrualSystemAddress string `env:"ACCRUAL_SYSTEM_ADDRESS"`
+	Addr                 string            `env:"RUN_ADDRESS"`
+	GRPCAddr             string            `env:"GRPC_ADDRESS"`
+	DatabaseURI          string            `env:"DATABASE_URI"`
+	AccrualSystemAddress string            `env:"ACCRUAL_SYSTEM_ADDRESS"`
+	HoldTTL              time.Duration     `env:"HOLD_TTL"`
+	TransferDailyLimit   gophermart.Amount `env:"TRANSFER_DAILY_LIMIT"`
+	AdminTokens          string            `env:"ADMIN_TOKENS"`
+	JWTKeys              string            `env:"JWT_KEYS"`
+	AccessTokenTTL       time.Duration     `env:"JWT_ACCESS_TTL"`
+	RefreshTokenTTL      time.Duration     `env:"JWT_REFRESH_TTL"`
+	SessionIdleTTL       time.Duration     `env:"SESSION_IDLE_TTL"`
+	SessionAbsoluteTTL   time.Duration     `env:"SESSION_ABSOLUTE_TTL"`
+	RememberIdleTTL      time.Duration     `env:"REMEMBER_ME_IDLE_TTL"`
+	RememberAbsoluteTTL  time.Duration     `env:"REMEMBER_ME_ABSOLUTE_TTL"`
+	CookieSecure         bool              `env:"COOKIE_SECURE"`
+	CookieHostPrefix     bool              `env:"COOKIE_HOST_PREFIX"`
+	CookieSameSite       string            `env:"COOKIE_SAMESITE"`
+	CSRFOrigins          string            `env:"CSRF_ORIGINS"`
+	CSRFToken            bool              `env:"CSRF_TOKEN"`
+	TrustedProxies       string            `env:"TRUSTED_PROXIES"`
+	PasswordHash         string            `env:"PASSWORD_HASH"`
+	BcryptCost           int               `env:"BCRYPT_COST"`
+	Argon2Time           uint              `env:"ARGON2_TIME"`
+	Argon2Memory         uint              `env:"ARGON2_MEMORY"`
+	Argon2Threads        uint              `env:"ARGON2_THREADS"`
+	LoginMinLength       int               `env:"LOGIN_MIN_LENGTH"`
+	LoginMaxLength       int               `env:"LOGIN_MAX_LENGTH"`
+	PasswordMinLength    int               `env:"PASSWORD_MIN_LENGTH"`
+	PasswordMinClasses   int               `env:"PASSWORD_MIN_CLASSES"`
+	LoginFreeAttempts    int               `env:"LOGIN_FREE_ATTEMPTS"`
+	LoginLockoutAttempts int               `env:"LOGIN_LOCKOUT_ATTEMPTS"`
+	IPFreeAttempts       int               `env:"LOGIN_IP_FREE_ATTEMPTS"`
+	IPLockoutAttempts    int               `env:"LOGIN_IP_LOCKOUT_ATTEMPTS"`
+	LoginBaseDelay       time.Duration     `env:"LOGIN_BASE_DELAY"`
+	LoginMaxDelay        time.Duration     `env:"LOGIN_MAX_DELAY"`
+	LoginLockout         time.Duration     `env:"LOGIN_LOCKOUT"`
+	PasswordReset        string            `env:"PASSWORD_RESET_NOTIFIER"`
+	PasswordResetTTL     time.Duration     `env:"PASSWORD_RESET_TTL"`
+	WebhooksAllowPrivate bool              `env:"WEBHOOKS_ALLOW_PRIVATE"`
+}
+
+var sameSiteModes = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
 }
 
 func main() {
 	cfg := new(config)
 	flag.StringVar(&cfg.Addr, "a", ":8080", "Service run address")
+	flag.StringVar(&cfg.GRPCAddr, "g", ":3200", "gRPC service run address")
 	flag.StringVar(&cfg.DatabaseURI, "d", "", "Postgres URI")
 	flag.StringVar(&cfg.AccrualSystemAddress, "r", "http://localhost:8081", "Accrual system address")
+	flag.DurationVar(&cfg.HoldTTL, "hold-ttl", 15*time.Minute, "Points hold lifetime before automatic release")
+	cfg.TransferDailyLimit = gophermart.DefaultTransferDailyLimit
+	flag.Var(&cfg.TransferDailyLimit, "transfer-limit", "Daily limit of points transferred by one user, 0 for no limit")
+	flag.StringVar(&cfg.AdminTokens, "admin-tokens", "", "Admin API bearer tokens of operators `operator:token,...`, admin API is disabled if empty")
+	flag.StringVar(&cfg.JWTKeys, "jwt-keys", "", "JWT signing keys `kid:HS256|EdDSA:base64,...`, the first one signs; JWT mode is disabled if empty")
+	flag.DurationVar(&cfg.AccessTokenTTL, "jwt-access-ttl", 15*time.Minute, "JWT access token lifetime")
+	flag.DurationVar(&cfg.RefreshTokenTTL, "jwt-refresh-ttl", 30*24*time.Hour, "Refresh token family lifetime")
+	flag.DurationVar(&cfg.SessionIdleTTL, "session-idle-ttl", 10*time.Minute, "Session idle timeout, renewed on activity")
+	flag.DurationVar(&cfg.SessionAbsoluteTTL, "session-absolute-ttl", 24*time.Hour, "Session lifetime since login regardless of activity")
+	flag.DurationVar(&cfg.RememberIdleTTL, "remember-me-idle-ttl", 7*24*time.Hour, "Idle timeout of \"remember me\" sessions")
+	flag.DurationVar(&cfg.RememberAbsoluteTTL, "remember-me-absolute-ttl", 30*24*time.Hour, "Lifetime of \"remember me\" sessions")
+	flag.BoolVar(&cfg.CookieSecure, "cookie-secure", false, "Send session cookies over HTTPS only")
+	flag.BoolVar(&cfg.CookieHostPrefix, "cookie-host-prefix", false, "Prefix session cookie names with __Host-, requires -cookie-secure")
+	flag.StringVar(&cfg.CookieSameSite, "cookie-samesite", "lax", "SameSite mode of session cookies: lax, strict or none")
+	flag.StringVar(&cfg.CSRFOrigins, "csrf-origins", "", "Comma separated origins allowed to send requests with session cookie, the service host if empty")
+	flag.BoolVar(&cfg.CSRFToken, "csrf-token", false, "Require X-CSRF-Token header with csrf_token cookie value on state-changing requests")
+	flag.StringVar(&cfg.TrustedProxies, "trusted-proxies", "", "Comma separated reverse proxy addresses or CIDRs allowed to set X-Forwarded-For and X-Real-IP, client IP is the connection address if empty")
+	flag.StringVar(&cfg.PasswordHash, "password-hash", gophermart.HashBcrypt, "Password hashing algorithm: bcrypt or argon2id, stored hashes are upgraded on login")
+	flag.IntVar(&cfg.BcryptCost, "bcrypt-cost", 10, "bcrypt cost")
+	flag.UintVar(&cfg.Argon2Time, "argon2-time", 1, "argon2id number of passes")
+	flag.UintVar(&cfg.Argon2Memory, "argon2-memory", 64*1024, "argon2id memory in KiB")
+	flag.UintVar(&cfg.Argon2Threads, "argon2-threads", 4, "argon2id number of threads")
+	flag.IntVar(&cfg.LoginMinLength, "login-min-length", 3, "Minimal login length")
+	flag.IntVar(&cfg.LoginMaxLength, "login-max-length", 64, "Maximal login length")
+	flag.IntVar(&cfg.PasswordMinLength, "password-min-length", 8, "Minimal password length")
+	flag.IntVar(&cfg.PasswordMinClasses, "password-min-classes", 2, "Minimal number of character classes in password: lowercase, uppercase, digits, symbols")
+	flag.IntVar(&cfg.LoginFreeAttempts, "login-free-attempts", 3, "Failed login attempts per login before progressive delays")
+	flag.IntVar(&cfg.LoginLockoutAttempts, "login-lockout-attempts", 10, "Failed login attempts per login before temporary lockout, 0 to disable")
+	flag.IntVar(&cfg.IPFreeAttempts, "login-ip-free-attempts", 10, "Failed login attempts per client IP before progressive delays")
+	flag.IntVar(&cfg.IPLockoutAttempts, "login-ip-lockout-attempts", 50, "Failed login attempts per client IP before temporary lockout, 0 to disable")
+	flag.DurationVar(&cfg.LoginBaseDelay, "login-base-delay", time.Second, "Delay after the first extra failed login attempt, doubled on each next one")
+	flag.DurationVar(&cfg.LoginMaxDelay, "login-max-delay", 30*time.Second, "Maximal delay between failed login attempts")
+	flag.DurationVar(&cfg.LoginLockout, "login-lockout", 15*time.Minute, "Login lockout duration, failed attempts are also forgotten after it")
+	flag.StringVar(&cfg.PasswordReset, "password-reset", "", "Password reset token delivery: `log` or `file:<path>`, password reset is disabled if empty")
+	flag.DurationVar(&cfg.PasswordResetTTL, "password-reset-ttl", time.Hour, "Password reset token lifetime")
+	flag.BoolVar(&cfg.WebhooksAllowPrivate, "webhooks-allow-private", false, "Allow webhooks to loopback, private and link-local addresses, for local development only")
 	flag.Parse()
 
 	err := env.Parse(cfg)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	signingKeys, err := gophermart.ParseSigningKeys(cfg.JWTKeys)
+	if err != nil {
+		log.Fatalln("[FATAL] Failed to parse JWT signing keys - ", err)
+	}
+	adminTokens, err := parseAdminTokens(cfg.AdminTokens)
+	if err != nil {
+		log.Fatalln("[FATAL] Failed to parse admin tokens - ", err)
+	}
+	// ключи подписи и токены администраторов в журнал не пишем
+	cfg.JWTKeys = fmt.Sprintf("%d keys", len(signingKeys))
+	cfg.AdminTokens = fmt.Sprintf("%d tokens", len(adminTokens))
+	trustedProxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalln("[FATAL] Failed to parse trusted proxies - ", err)
+	}
+	sameSite, ok := sameSiteModes[strings.ToLower(cfg.CookieSameSite)]
+	if !ok {
+		log.Fatalf("[FATAL] Unknown cookie SameSite mode `%s`\n", cfg.CookieSameSite)
+	}
+	if cfg.CookieHostPrefix && !cfg.CookieSecure {
+		log.Fatalln("[FATAL] __Host- cookie prefix requires secure cookies")
+	}
+	hasher, err := passwordHasher(cfg)
+	if err != nil {
+		log.Fatalln("[FATAL] Failed to configure password hashing - ", err)
+	}
+	notifier, err := resetNotifier(cfg.PasswordReset)
+	if err != nil {
+		log.Fatalln("[FATAL] Failed to configure password reset - ", err)
+	}
 	log.Printf("[DEBUG] Receive config: %#v\n", cfg)
 
 	//st := basicstorage.New()
@@ -36,10 +153,51 @@ func main() {
 		log.Fatalln("[FATAL] Postgres initialization failed - ", err)
 	}
 
-	gm := gophermart.New(st)
+	gm := gophermart.New(st,
+		gophermart.WithHoldTTL(cfg.HoldTTL),
+		gophermart.WithTransferDailyLimit(cfg.TransferDailyLimit),
+		gophermart.WithWebhooksAllowPrivate(cfg.WebhooksAllowPrivate),
+		gophermart.WithJWT(signingKeys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL),
+		gophermart.WithSessionTTL(cfg.SessionIdleTTL, cfg.SessionAbsoluteTTL),
+		gophermart.WithRememberMeTTL(cfg.RememberIdleTTL, cfg.RememberAbsoluteTTL),
+		gophermart.WithPasswordHasher(hasher),
+		gophermart.WithCredentialsPolicy(gophermart.CredentialsPolicy{
+			LoginMinLength:     cfg.LoginMinLength,
+			LoginMaxLength:     cfg.LoginMaxLength,
+			PasswordMinLength:  cfg.PasswordMinLength,
+			PasswordMinClasses: cfg.PasswordMinClasses,
+		}),
+		gophermart.WithLoginLimits(
+			loginLimits(cfg, cfg.LoginFreeAttempts, cfg.LoginLockoutAttempts),
+			loginLimits(cfg, cfg.IPFreeAttempts, cfg.IPLockoutAttempts),
+		),
+		gophermart.WithPasswordReset(notifier, cfg.PasswordResetTTL),
+	)
+
+	// освобождаем просроченные резервы баллов в фоне
+	go gm.Holds.Sweep(context.Background(), time.Minute)
+
+	// удаляем истёкшие сессии в фоне
+	go gm.Sessions.Sweep(context.Background(), time.Minute)
+
+	// удаляем устаревшие счётчики неудачных попыток входа в фоне
+	go gm.Logins.Sweep(context.Background(), time.Minute)
+
+	// рассылаем события подписчикам вебхуков в фоне
+	dispatcher := gophermart.NewDispatcher(gm)
+	go dispatcher.Start(context.Background())
 
 	// подключим обработчики запросов
-	h := handlers.New(gm)
+	handlerOpts := []handlers.Option{
+		handlers.WithAdminTokens(adminTokens),
+		handlers.WithCookieSameSite(sameSite),
+		handlers.WithCSRF(cfg.CSRFToken, splitList(cfg.CSRFOrigins)...),
+		handlers.WithTrustedProxies(trustedProxies),
+	}
+	if cfg.CookieSecure {
+		handlerOpts = append(handlerOpts, handlers.WithSecureCookies(cfg.CookieHostPrefix))
+	}
+	h := handlers.New(gm, handlerOpts...)
 
 	// проиницилизируем сервер с использованием ранее объявленных обработчиков и файлового хранилища
 	s := server.New(h.GetRouter(),
@@ -48,6 +206,113 @@ func main() {
 	// запустим сервер
 	go s.Serve()
 
-	queue := gophermart.NewQueue(st, cfg.AccrualSystemAddress)
+	// gRPC API работает поверх тех же сценариев на отдельном порту
+	rs := rpc.New(gm,
+		rpc.WithAddress(cfg.GRPCAddr),
+	)
+	go rs.Serve()
+
+	queue := gophermart.NewQueue(gm, cfg.AccrualSystemAddress)
 	queue.Start()
 }
+
+// splitList разбирает список через запятую, пропуская пустые элементы
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseAdminTokens разбирает токены администраторов `operator:token,...`; у каждого оператора свой токен,
+// чтобы корректировки баланса записывались на того, кто их сделал
+func parseAdminTokens(s string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	seen := make(map[string]struct{})
+	for _, item := range splitList(s) {
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("wrong admin token format, `operator:token` needed")
+		}
+		if _, ok := tokens[parts[0]]; ok {
+			return nil, fmt.Errorf("duplicate operator `%s`", parts[0])
+		}
+		if _, ok := seen[parts[1]]; ok {
+			return nil, fmt.Errorf("operator `%s` shares a token with another operator", parts[0])
+		}
+		tokens[parts[0]] = parts[1]
+		seen[parts[1]] = struct{}{}
+	}
+
+	return tokens, nil
+}
+
+// parseTrustedProxies разбирает адреса и подсети доверенных прокси через запятую; адрес без маски — подсеть из одного адреса
+func parseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, item := range splitList(s) {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("wrong proxy address `%s`", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, proxy, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("wrong proxy subnet `%s` - %w", item, err)
+		}
+		proxies = append(proxies, proxy)
+	}
+
+	return proxies, nil
+}
+
+// loginLimits ограничения попыток входа: по логину и по адресу отличаются только числом попыток
+func loginLimits(cfg *config, freeAttempts, lockoutAttempts int) gophermart.LoginLimits {
+	return gophermart.LoginLimits{
+		FreeAttempts:    freeAttempts,
+		BaseDelay:       cfg.LoginBaseDelay,
+		MaxDelay:        cfg.LoginMaxDelay,
+		LockoutAttempts: lockoutAttempts,
+		LockoutDuration: cfg.LoginLockout,
+		Window:          cfg.LoginLockout,
+	}
+}
+
+func passwordHasher(cfg *config) (*gophermart.PasswordHasher, error) {
+	switch cfg.PasswordHash {
+	case gophermart.HashBcrypt:
+		return gophermart.NewBcryptHasher(cfg.BcryptCost)
+	case gophermart.HashArgon2id:
+		if cfg.Argon2Threads > math.MaxUint8 {
+			return nil, fmt.Errorf("too many argon2id threads")
+		}
+		return gophermart.NewArgon2idHasher(uint32(cfg.Argon2Time), uint32(cfg.Argon2Memory), uint8(cfg.Argon2Threads))
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm `%s`", cfg.PasswordHash)
+	}
+}
+
+// resetNotifier способ доставки токенов сброса пароля: `log`, `file:<путь>` или пустая строка — сброс выключен
+func resetNotifier(s string) (gophermart.ResetNotifier, error) {
+	switch {
+	case s == "":
+		return nil, nil
+	case s == "log":
+		return gophermart.LogResetNotifier{}, nil
+	case strings.HasPrefix(s, "file:") && len(s) > len("file:"):
+		return gophermart.NewFileResetNotifier(strings.TrimPrefix(s, "file:")), nil
+	default:
+		return nil, fmt.Errorf("unknown password reset notifier `%s`", s)
+	}
+}
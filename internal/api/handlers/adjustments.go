@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (h *handler) postAdjustment(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	ct := r.Header.Get("Content-Type")
+	if ct != ContentTypeApplicationJSON {
+		err = fmt.Errorf("wrong content type, %s needed", ContentTypeApplicationJSON)
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to read request body - %w", err), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	apr := &gophermart.AdjustmentProxy{}
+	err = json.Unmarshal(reqBody, &apr)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+	// оператора определяет токен администратора, а не тело запроса
+	apr.OperatorID = currentOperator(r)
+
+	apr, err = h.gm.PostAdjustment(apr)
+	if err != nil {
+		switch {
+		// 402 — на счету недостаточно средств для списания
+		case errors.Is(err, gophermart.ErrNotEnoughFunds):
+			h.error(w, r, err, http.StatusPaymentRequired)
+		// 404 — пользователь не найден
+		case errors.Is(err, gophermart.ErrUserNotFound):
+			h.error(w, r, err, http.StatusNotFound)
+		// 422 — не указаны причина, тип или сумма корректировки
+		case errors.Is(err, gophermart.ErrInvalidAdjustmentType),
+			errors.Is(err, gophermart.ErrAdjustmentReasonRequired),
+			errors.Is(err, gophermart.ErrAdjustmentOperatorRequired),
+			errors.Is(err, gophermart.ErrInvalidAmount):
+			h.error(w, r, err, http.StatusUnprocessableEntity)
+		// 500 — внутренняя ошибка сервера
+		default:
+			h.error(w, r, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	body, err := json.Marshal(apr)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write(body)
+}
+
+func (h *handler) getAdjustments(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	asPr, err := h.gm.GetAdjustments(u.ID)
+	if err != nil {
+		// 204 — нет ни одной корректировки
+		if errors.Is(err, gophermart.ErrNoContent) {
+			h.error(w, r, gophermart.ErrNoContent, http.StatusNoContent)
+			return
+		}
+
+		// 500 — внутренняя ошибка сервера
+		h.error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(&asPr)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write(body)
+}
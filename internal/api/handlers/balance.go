@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
 )
 
 func (h *handler) getBalance(w http.ResponseWriter, r *http.Request) {
@@ -17,24 +20,59 @@ func (h *handler) getBalance(w http.ResponseWriter, r *http.Request) {
 	//	return
 	//}
 
-	c, err := h.authCheck(w, r)
+	user := currentUser(r)
+
+	balanceProxy, err := h.gm.GetBalance(user.ID)
 	if err != nil {
-		// 401 — пользователь не авторизован
+		h.error(w, r, fmt.Errorf("failed to get balance for user `%s` - %w", user.Login, err), http.StatusInternalServerError)
 		return
 	}
-	user, err := h.gm.Users.Get(c.UserID)
+
+	body, err := json.Marshal(&balanceProxy)
 	if err != nil {
-		h.error(w, r, fmt.Errorf("failed to get user by ID - %w", err), http.StatusInternalServerError)
+		h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
 		return
 	}
 
-	balanceProxy, err := h.gm.GetBalance(user.ID)
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write(body)
+}
+
+func (h *handler) getStatement(w http.ResponseWriter, r *http.Request) {
+	var err error
+	u := currentUser(r)
+
+	filter := &gophermart.StatementFilter{}
+	if filter.From, filter.To, err = queryPeriod(r); err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if filter.Limit, err = queryUint(r, "limit"); err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if filter.Offset, err = queryUint(r, "offset"); err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	sePr, err := h.gm.GetStatement(u.ID, filter)
 	if err != nil {
-		h.error(w, r, fmt.Errorf("failed to get balance for user `%s` - %w", user.Login, err), http.StatusInternalServerError)
+		switch {
+		// 204 — нет ни одной операции за период
+		case errors.Is(err, gophermart.ErrNoContent):
+			h.error(w, r, err, http.StatusNoContent)
+		// 400 — конец периода раньше начала
+		case errors.Is(err, gophermart.ErrInvalidPeriod):
+			h.error(w, r, err, http.StatusBadRequest)
+		// 500 — внутренняя ошибка сервера
+		default:
+			h.error(w, r, err, http.StatusInternalServerError)
+		}
 		return
 	}
 
-	body, err := json.Marshal(&balanceProxy)
+	body, err := json.Marshal(&sePr)
 	if err != nil {
 		h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
 		return
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+const (
+	cookieSessionToken = "session_token"
+	cookieCSRFToken    = "csrf_token"
+	// браузер принимает куку с префиксом `__Host-` только с Secure и Path=/ и без Domain:
+	// такую куку нельзя подменить с поддомена или по HTTP
+	cookieHostPrefix = "__Host-"
+)
+
+// cookieConfig атрибуты кук сессии; HttpOnly и Path=/ задаются всегда
+type cookieConfig struct {
+	secure     bool
+	hostPrefix bool
+	sameSite   http.SameSite
+}
+
+// WithSecureCookies выдаёт куки только для HTTPS; hostPrefix добавляет к именам кук префикс `__Host-`
+func WithSecureCookies(hostPrefix bool) Option {
+	return func(h *handler) {
+		h.cookies.secure = true
+		h.cookies.hostPrefix = hostPrefix
+	}
+}
+
+// WithCookieSameSite режим SameSite кук сессии, по умолчанию Lax
+func WithCookieSameSite(mode http.SameSite) Option {
+	return func(h *handler) {
+		h.cookies.sameSite = mode
+	}
+}
+
+func (h *handler) cookieName(name string) string {
+	if h.cookies.hostPrefix {
+		return cookieHostPrefix + name
+	}
+
+	return name
+}
+
+func (h *handler) newCookie(name, value string, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     h.cookieName(name),
+		Value:    value,
+		Path:     "/",
+		HttpOnly: httpOnly,
+		Secure:   h.cookies.secure,
+		SameSite: h.cookies.sameSite,
+	}
+}
+
+// setSessionCookie выдаёт куку сессии и доступную скриптам куку с CSRF-токеном этой сессии:
+// с «запомнить меня» куки живут до срока сессии, иначе это сессионные куки браузера,
+// которые удаляются при его закрытии
+func (h *handler) setSessionCookie(w http.ResponseWriter, session *gophermart.Session) {
+	for _, c := range []*http.Cookie{
+		h.newCookie(cookieSessionToken, session.Token, true),
+		h.newCookie(cookieCSRFToken, csrfToken(session.Token), false),
+	} {
+		if session.Remember {
+			c.Expires = session.Expiry
+		}
+		http.SetCookie(w, c)
+	}
+}
+
+// clearSessionCookie удаляет куки сессии у клиента
+func (h *handler) clearSessionCookie(w http.ResponseWriter) {
+	for _, name := range []string{cookieSessionToken, cookieCSRFToken} {
+		c := h.newCookie(name, "", name == cookieSessionToken)
+		c.MaxAge = -1
+		http.SetCookie(w, c)
+	}
+}
+
+// sessionCookie токен сессии из куки, пустой, если куки нет
+func (h *handler) sessionCookie(r *http.Request) string {
+	c, err := r.Cookie(h.cookieName(cookieSessionToken))
+	if err != nil {
+		return ""
+	}
+
+	return c.Value
+}
+
+// csrfToken CSRF-токен сессии: выводится из токена сессии, поэтому его не нужно хранить,
+// а подложенная с поддомена кука с чужим значением проверку не пройдёт
+func csrfToken(sessionToken string) string {
+	sum := sha256.Sum256([]byte("csrf:" + sessionToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const HeaderCSRFToken = "X-CSRF-Token"
+
+var ErrCSRFCheckFailed = errors.New("CSRF check failed")
+
+// csrfConfig защита от подделки запросов: источник запроса проверяется всегда,
+// CSRF-токен в заголовке — если он обязателен
+type csrfConfig struct {
+	origins      map[string]bool // разрешённые источники `scheme://host`; пустой — только хост самого сервиса
+	requireToken bool
+}
+
+// WithCSRF задаёт разрешённые источники запросов браузера и требует ли сервис заголовок `X-CSRF-Token`
+// со значением куки `csrf_token` (double-submit)
+func WithCSRF(requireToken bool, origins ...string) Option {
+	return func(h *handler) {
+		h.csrf.requireToken = requireToken
+		h.csrf.origins = make(map[string]bool, len(origins))
+		for _, origin := range origins {
+			h.csrf.origins[strings.TrimSuffix(origin, "/")] = true
+		}
+	}
+}
+
+// csrfProtect миделвара маршрутов пользователя: изменяющие запросы с кукой сессии должны прийти
+// с разрешённого источника и, если токен обязателен или источник неизвестен, нести CSRF-токен сессии.
+// Запросы с токеном в заголовке Authorization браузер сам не подставит, их миделвара не проверяет
+func (h *handler) csrfProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionToken := h.sessionCookie(r)
+		if isSafeMethod(r.Method) || sessionToken == "" || r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		origin, ok := requestOrigin(r)
+		if !ok || (origin != "" && !h.allowedOrigin(r, origin)) {
+			// 403 — запрос пришёл со стороннего сайта
+			h.error(w, r, ErrCSRFCheckFailed, http.StatusForbidden)
+			return
+		}
+		// источник неизвестен — так выглядят и клиенты не из браузера, и браузеры, скрывающие Referer:
+		// без CSRF-токена сессии такой запрос не пропускаем, даже если токен не обязателен
+		if (h.csrf.requireToken || origin == "") &&
+			subtle.ConstantTimeCompare([]byte(r.Header.Get(HeaderCSRFToken)), []byte(csrfToken(sessionToken))) != 1 {
+			// 403 — нет CSRF-токена сессии
+			h.error(w, r, ErrCSRFCheckFailed, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestOrigin источник запроса `scheme://host` из заголовка Origin, а без него из Referer;
+// пустой источник — ни одного из заголовков нет, ok == false — Referer не разобрать
+func requestOrigin(r *http.Request) (origin string, ok bool) {
+	if origin = r.Header.Get("Origin"); origin != "" {
+		return origin, true
+	}
+
+	referer, err := url.Parse(r.Header.Get("Referer"))
+	if err != nil {
+		return "", false
+	}
+	if referer.Host == "" {
+		return "", true
+	}
+
+	return referer.Scheme + "://" + referer.Host, true
+}
+
+// allowedOrigin разрешён ли источник: из списка WithCSRF, а если список пуст — только хост самого сервиса
+func (h *handler) allowedOrigin(r *http.Request, origin string) bool {
+	if len(h.csrf.origins) > 0 {
+		return h.csrf.origins[origin]
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return u.Host == r.Host
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestCSRFProtect(t *testing.T) {
+	gm := gophermart.New(basicstorage.New())
+	session, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		method     string
+		headers    map[string]string
+		statusCode int
+	}{
+		{name: "safe method", method: http.MethodGet, headers: map[string]string{"Origin": "https://evil.example"}, statusCode: http.StatusOK},
+		{name: "unknown origin", method: http.MethodPost, statusCode: http.StatusForbidden},
+		{
+			name:       "unknown origin with token",
+			method:     http.MethodPost,
+			headers:    map[string]string{HeaderCSRFToken: csrfToken(session.Token)},
+			statusCode: http.StatusOK,
+		},
+		{name: "referer without host", method: http.MethodPost, headers: map[string]string{"Referer": "/page"}, statusCode: http.StatusForbidden},
+		{name: "same origin referer", method: http.MethodPost, headers: map[string]string{"Referer": "http://example.com/page"}, statusCode: http.StatusOK},
+		{name: "same origin", method: http.MethodPost, headers: map[string]string{"Origin": "http://example.com"}, statusCode: http.StatusOK},
+		{name: "cross origin", method: http.MethodPost, headers: map[string]string{"Origin": "https://evil.example"}, statusCode: http.StatusForbidden},
+		{name: "cross origin referer", method: http.MethodPost, headers: map[string]string{"Referer": "https://evil.example/page"}, statusCode: http.StatusForbidden},
+		{name: "bearer token is not checked", method: http.MethodPost, headers: map[string]string{"Origin": "https://evil.example", "Authorization": "Bearer " + session.Token}, statusCode: http.StatusOK},
+		{
+			name:       "allowed origin",
+			opts:       []Option{WithCSRF(false, "https://shop.example")},
+			method:     http.MethodPost,
+			headers:    map[string]string{"Origin": "https://shop.example"},
+			statusCode: http.StatusOK,
+		},
+		{name: "token required", opts: []Option{WithCSRF(true)}, method: http.MethodPost, statusCode: http.StatusForbidden},
+		{
+			name:       "wrong token",
+			opts:       []Option{WithCSRF(true)},
+			method:     http.MethodDelete,
+			headers:    map[string]string{HeaderCSRFToken: csrfToken("another session")},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "session token",
+			opts:       []Option{WithCSRF(true)},
+			method:     http.MethodPost,
+			headers:    map[string]string{HeaderCSRFToken: csrfToken(session.Token)},
+			statusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(gm, tt.opts...)
+			req := httptest.NewRequest(tt.method, "http://example.com/api/user/orders", nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.csrfProtect(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.statusCode, rec.Code)
+		})
+	}
+}
+
+func TestSecureCookies(t *testing.T) {
+	h := New(gophermart.New(basicstorage.New()), WithSecureCookies(true), WithCookieSameSite(http.SameSiteStrictMode))
+	rec := httptest.NewRecorder()
+	h.setSessionCookie(rec, &gophermart.Session{Token: "token"})
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 2)
+	for _, c := range cookies {
+		assert.True(t, c.Secure)
+		assert.Equal(t, "/", c.Path)
+		assert.Equal(t, http.SameSiteStrictMode, c.SameSite)
+	}
+	assert.Equal(t, "__Host-session_token", cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, "__Host-csrf_token", cookies[1].Name)
+	assert.False(t, cookies[1].HttpOnly, "csrf token must be readable by scripts")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	assert.Equal(t, "token", h.sessionCookie(req))
+}
@@ -9,6 +9,11 @@ import (
 )
 
 func (h *handler) error(w http.ResponseWriter, r *http.Request, err error, statusCode int) {
+	if apiVersion(r) == apiV2 {
+		h.errorV2(w, r, err, statusCode)
+		return
+	}
+
 	reqID := middleware.GetReqID(r.Context())
 
 	type errorJSON struct {
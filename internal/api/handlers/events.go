@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+const (
+	ContentTypeTextEventStream = "text/event-stream"
+
+	eventsHeartbeat = 15 * time.Second // интервал комментариев, не дающих прокси закрыть простаивающее соединение
+)
+
+// getEvents поток Server-Sent Events с изменениями заказов и баланса пользователя
+func (h *handler) getEvents(w http.ResponseWriter, r *http.Request) {
+	session := currentSession(r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.error(w, r, fmt.Errorf("streaming is not supported"), http.StatusInternalServerError)
+		return
+	}
+
+	// поток живёт дольше таймаута записи сервера: снимем ограничение для этого соединения
+	if err := clearWriteDeadline(w); err != nil {
+		h.log(r, LogLvlWarning, fmt.Sprintf("failed to reset write deadline, stream will be cut by server timeout - %s", err))
+	}
+
+	notifications, unsubscribe := h.gm.Notifications.Subscribe(session.UserID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", ContentTypeTextEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// первым событием отправим текущий баланс, чтобы клиенту не нужен был отдельный запрос
+	balance, err := h.gm.GetBalance(session.UserID)
+	if err != nil {
+		h.log(r, LogLvlError, fmt.Sprintf("failed to get balance - %s", err))
+		return
+	}
+	if err = writeEvent(w, &gophermart.Notification{Type: gophermart.NotificationBalance, Balance: balance}); err != nil {
+		return
+	}
+	flusher.Flush()
+	h.log(r, LogLvlDebug, "events stream opened")
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	// по истечении срока сессии поток закрывается, переподключиться можно только с новой сессией;
+	// срок бездействия сессии по куке сдвигают другие запросы клиента, поэтому при срабатывании таймера он перечитывается
+	expiry := time.NewTimer(time.Until(session.Expiry))
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			h.log(r, LogLvlDebug, "events stream closed by client")
+			return
+		case <-expiry.C:
+			until, ok := h.streamExpiry(session)
+			if !ok {
+				h.log(r, LogLvlDebug, "events stream closed: session has expired")
+				return
+			}
+			expiry.Reset(time.Until(until))
+			continue
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case n := <-notifications:
+			if err = writeEvent(w, n); err != nil {
+				h.log(r, LogLvlError, fmt.Sprintf("failed to write event - %s", err))
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// streamExpiry актуальный срок сессии потока; ok == false, если сессия истекла или удалена.
+// Токен доступа продлить нельзя, его срок известен при подключении; сессию по куке находим заново
+func (h *handler) streamExpiry(session *gophermart.Session) (until time.Time, ok bool) {
+	if session.Family != "" {
+		return session.Expiry, time.Now().Before(session.Expiry)
+	}
+
+	current, err := h.gm.Sessions.Get(session.Token)
+	if err != nil || current.IsExpired() {
+		return time.Time{}, false
+	}
+
+	return current.Expiry, true
+}
+
+func writeEvent(w http.ResponseWriter, n *gophermart.Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", n.Type, data)
+	return err
+}
+
+// clearWriteDeadline снимает таймаут записи с соединения. Обёртки миделвар раскрываем через Unwrap, пока не дойдём
+// до ответа сервера: если он не умеет менять таймаут, поток будет закрыт по таймауту и клиент переподключится
+func clearWriteDeadline(w http.ResponseWriter) error {
+	for {
+		switch rw := w.(type) {
+		case interface{ SetWriteDeadline(time.Time) error }:
+			return rw.SetWriteDeadline(time.Time{})
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return fmt.Errorf("response writer %T does not support write deadlines", w)
+		}
+	}
+}
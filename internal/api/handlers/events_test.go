@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestEventsSessionExpiry(t *testing.T) {
+	const idle = 600 * time.Millisecond
+	gm := gophermart.New(basicstorage.New(), gophermart.WithSessionTTL(idle, time.Minute))
+	h := New(gm)
+	srv := httptest.NewServer(h.GetRouter())
+	defer srv.Close()
+
+	session, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+	get := func(target string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+target, nil)
+		require.NoError(t, err)
+		req.AddCookie(&http.Cookie{Name: h.cookieName(cookieSessionToken), Value: session.Token})
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	start := time.Now()
+	stream := get("/api/user/events")
+	defer stream.Body.Close()
+	require.Equal(t, http.StatusOK, stream.StatusCode)
+	assert.Equal(t, ContentTypeTextEventStream, stream.Header.Get("Content-Type"))
+
+	// первым событием приходит текущий баланс
+	reader := bufio.NewReader(stream.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: "+gophermart.NotificationBalance, strings.TrimSpace(line))
+
+	closed := make(chan time.Time, 1)
+	go func() {
+		_, _ = io.Copy(io.Discard, reader)
+		closed <- time.Now()
+	}()
+
+	// запрос клиента продлевает сессию: поток не должен закрыться по сроку, известному при подключении
+	time.Sleep(idle * 2 / 3)
+	resp := get("/api/user/balance")
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	select {
+	case <-closed:
+		t.Fatal("events stream closed while session is still active")
+	case <-time.After(time.Until(start.Add(idle * 4 / 3))):
+	}
+
+	// без активности продлённая сессия истекает, и поток закрывается
+	select {
+	case at := <-closed:
+		assert.True(t, at.After(start.Add(idle*3/2)), "stream closed at %s", at.Sub(start))
+	case <-time.After(5 * time.Second):
+		t.Fatal("events stream was not closed after session expiry")
+	}
+}
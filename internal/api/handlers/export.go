@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+const (
+	ContentTypeTextCSV = "text/csv"
+
+	exportFlushEvery = 100 // через сколько записей сбрасывать буфер клиенту
+)
+
+// exporter пишет записи истории в выбранном формате по одной
+type exporter interface {
+	begin() error
+	write(*gophermart.HistoryRecordProxy) error
+	flush()
+	end() error
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvExporter) begin() error {
+	return e.w.Write([]string{"type", "login", "number", "status", "sum", "processed_at"})
+}
+
+func (e *csvExporter) write(hr *gophermart.HistoryRecordProxy) error {
+	return e.w.Write([]string{hr.Type, hr.Login, hr.Number, hr.Status, hr.Sum.String(), hr.ProcessedAt})
+}
+
+func (e *csvExporter) flush() {
+	e.w.Flush()
+}
+
+func (e *csvExporter) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonExporter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func (e *jsonExporter) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExporter) write(hr *gophermart.HistoryRecordProxy) error {
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+
+	return e.enc.Encode(hr)
+}
+
+func (e *jsonExporter) flush() {}
+
+func (e *jsonExporter) end() error {
+	_, err := io.WriteString(e.w, "]")
+	return err
+}
+
+func (h *handler) exportUser(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, currentUser(r).ID)
+}
+
+func (h *handler) exportAdmin(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, 0)
+}
+
+// export потоково выгружает историю заказов и списаний пользователя, а для userID = 0 — всех пользователей
+func (h *handler) export(w http.ResponseWriter, r *http.Request, userID uint64) {
+	var err error
+	filter := &gophermart.HistoryFilter{UserID: userID}
+	if filter.From, filter.To, err = queryPeriod(r); err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	var e exporter
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", ContentTypeTextCSV)
+		e = &csvExporter{w: csv.NewWriter(w)}
+	case "json", "":
+		format = "json"
+		w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+		e = &jsonExporter{w: w, enc: json.NewEncoder(w)}
+	default:
+		h.error(w, r, fmt.Errorf("unknown export format `%s`, csv or json needed", format), http.StatusBadRequest)
+		return
+	}
+	filename := fmt.Sprintf("gophermart-history-%s.%s", time.Now().Format(dateLayout), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	count := 0
+	err = h.gm.ExportHistory(filter, func(hr *gophermart.HistoryRecordProxy) error {
+		if !started {
+			if err := e.begin(); err != nil {
+				return err
+			}
+			started = true
+		}
+		if err := e.write(hr); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 && flusher != nil {
+			e.flush()
+			flusher.Flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		if !started {
+			w.Header().Del("Content-Disposition")
+			if errors.Is(err, gophermart.ErrInvalidPeriod) {
+				h.error(w, r, err, http.StatusBadRequest)
+				return
+			}
+			h.error(w, r, fmt.Errorf("failed to export history - %w", err), http.StatusInternalServerError)
+			return
+		}
+		// заголовки уже отправлены, сменить код ответа нельзя: прервём выгрузку и запишем ошибку в лог
+		h.log(r, LogLvlError, fmt.Sprintf("export interrupted after %d records - %s", count, err))
+		return
+	}
+
+	if !started {
+		if err = e.begin(); err != nil {
+			h.log(r, LogLvlError, fmt.Sprintf("failed to write export - %s", err))
+			return
+		}
+	}
+	if err = e.end(); err != nil {
+		h.log(r, LogLvlError, fmt.Sprintf("failed to write export - %s", err))
+		return
+	}
+
+	h.log(r, LogLvlInfo, fmt.Sprintf("%d history records exported as %s", count, format))
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// exportStorage хранилище в памяти с заданной историей: basicstorage выгрузку не поддерживает
+type exportStorage struct {
+	*basicstorage.Storage
+	history []*gophermart.HistoryRecord
+	err     error // ошибка после выдачи всей истории
+}
+
+func (s *exportStorage) ExportHistory(_ *gophermart.HistoryFilter, fn func(*gophermart.HistoryRecord) error) error {
+	for _, hr := range s.history {
+		if err := fn(hr); err != nil {
+			return err
+		}
+	}
+
+	return s.err
+}
+
+func TestExport(t *testing.T) {
+	processedAt := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	history := []*gophermart.HistoryRecord{
+		{Type: gophermart.HistoryOrder, Login: "gopher", Number: 12345678903,
+			Status: gophermart.StatusProcessed, Sum: 500_50, ProcessedAt: processedAt},
+		{Type: gophermart.HistoryWithdrawal, Login: "gopher", Number: 2377225624, Sum: 100_00, ProcessedAt: processedAt},
+	}
+	errStorage := errors.New("connection reset")
+
+	tests := []struct {
+		name        string
+		history     []*gophermart.HistoryRecord
+		err         error
+		query       string
+		statusCode  int
+		contentType string
+		want        string
+	}{
+		{
+			name:        "json by default",
+			history:     history,
+			statusCode:  http.StatusOK,
+			contentType: ContentTypeApplicationJSON,
+			want: `[{"type":"order","login":"gopher","number":"12345678903","status":"PROCESSED","sum":500.5,"processed_at":"2022-01-02T03:04:05Z"},` +
+				`{"type":"withdrawal","login":"gopher","number":"2377225624","sum":100,"processed_at":"2022-01-02T03:04:05Z"}]`,
+		},
+		{
+			name:        "csv",
+			history:     history,
+			query:       "?format=csv",
+			statusCode:  http.StatusOK,
+			contentType: ContentTypeTextCSV,
+			want: "type,login,number,status,sum,processed_at\n" +
+				"order,gopher,12345678903,PROCESSED,500.5,2022-01-02T03:04:05Z\n" +
+				"withdrawal,gopher,2377225624,,100,2022-01-02T03:04:05Z\n",
+		},
+		{name: "empty json", query: "?format=json", statusCode: http.StatusOK, contentType: ContentTypeApplicationJSON, want: `[]`},
+		{name: "empty csv", query: "?format=csv", statusCode: http.StatusOK, contentType: ContentTypeTextCSV,
+			want: "type,login,number,status,sum,processed_at\n"},
+		{name: "unknown format", query: "?format=xml", statusCode: http.StatusBadRequest},
+		{name: "invalid date", query: "?from=yesterday", statusCode: http.StatusBadRequest},
+		{name: "reversed period", query: "?from=2022-01-02&to=2021-12-31", statusCode: http.StatusBadRequest},
+		{name: "storage error", err: errStorage, statusCode: http.StatusInternalServerError},
+		// заголовки уже отправлены: выгрузка обрывается без закрывающей скобки, и клиент видит неполный ответ
+		{
+			name:        "interrupted",
+			history:     history[:1],
+			err:         errStorage,
+			statusCode:  http.StatusOK,
+			contentType: ContentTypeApplicationJSON,
+			want:        `[{"type":"order","login":"gopher","number":"12345678903","status":"PROCESSED","sum":500.5,"processed_at":"2022-01-02T03:04:05Z"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &exportStorage{Storage: basicstorage.New(), history: tt.history, err: tt.err}
+			gm := gophermart.New(st)
+			session, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/export"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+			rec := httptest.NewRecorder()
+			New(gm).GetRouter().ServeHTTP(rec, req)
+
+			require.Equal(t, tt.statusCode, rec.Code, rec.Body.String())
+			if tt.statusCode != http.StatusOK {
+				assert.Empty(t, rec.Header().Get("Content-Disposition"))
+				return
+			}
+			assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Header().Get("Content-Disposition"), "attachment; filename=\"gophermart-history-")
+			if tt.contentType == ContentTypeApplicationJSON && json.Valid([]byte(tt.want)) {
+				assert.JSONEq(t, tt.want, rec.Body.String())
+				return
+			}
+			assert.Equal(t, tt.want, rec.Body.String())
+		})
+	}
+}
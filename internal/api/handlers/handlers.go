@@ -5,6 +5,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"log"
+	"net"
 	"net/http"
 
 	"github.com/sergeysynergy/hardtest/internal/gophermart"
@@ -13,6 +14,7 @@ import (
 const (
 	ContentTypeApplicationJSON = "application/json"
 	ContentTypeTextPlain       = "text/plain"
+	HeaderNextCursor           = "X-Next-Cursor"
 	LogLvlDebug                = "[DEBUG]"
 	LogLvlInfo                 = "[INFO]"
 	LogLvlWarning              = "[WARNING]"
@@ -23,14 +25,21 @@ const (
 type handler struct {
 	r  chi.Router
 	gm *gophermart.GopherMart
+	// токены доступа к административному API и идентификаторы операторов, которым они выданы; без токенов API отключено
+	adminTokens map[string]string
+	cookies     cookieConfig
+	csrf        csrfConfig
+	// подсети обратных прокси, которым доверяем адрес клиента из заголовков
+	trustedProxies []*net.IPNet
 }
 
 type Option func(*handler)
 
 func New(gm *gophermart.GopherMart, opts ...Option) *handler {
 	h := &handler{
-		r:  chi.NewRouter(),
-		gm: gm,
+		r:       chi.NewRouter(),
+		gm:      gm,
+		cookies: cookieConfig{sameSite: http.SameSiteLaxMode},
 	}
 	// применяем в цикле каждую опцию
 	for _, opt := range opts {
@@ -40,7 +49,7 @@ func New(gm *gophermart.GopherMart, opts ...Option) *handler {
 	// Общая для всех роутеров миделвара
 	h.r.Use(middleware.Compress(3, "gzip"))
 	h.r.Use(middleware.RequestID)
-	h.r.Use(middleware.RealIP)
+	h.r.Use(h.realIP)
 	h.r.Use(middleware.Logger)
 	h.r.Use(middleware.Recoverer)
 
@@ -51,6 +60,17 @@ func New(gm *gophermart.GopherMart, opts ...Option) *handler {
 	return h
 }
 
+// WithAdminTokens токены административного API по идентификаторам операторов: оператор корректировки баланса
+// определяется по предъявленному токену
+func WithAdminTokens(tokens map[string]string) Option {
+	return func(h *handler) {
+		h.adminTokens = make(map[string]string, len(tokens))
+		for operatorID, token := range tokens {
+			h.adminTokens[token] = operatorID
+		}
+	}
+}
+
 func (h *handler) GetRouter() chi.Router {
 	return h.r
 }
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (h *handler) postHold(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	ct := r.Header.Get("Content-Type")
+	if ct != ContentTypeApplicationJSON {
+		err = fmt.Errorf("wrong content type, %s needed", ContentTypeApplicationJSON)
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	u := currentUser(r)
+
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to read request body - %w", err), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	hpr := &gophermart.HoldProxy{}
+	err = json.Unmarshal(reqBody, &hpr)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+
+	hpr.UserID = u.ID
+	hpr, err = h.gm.PostHold(hpr)
+	if err != nil {
+		h.holdError(w, r, err)
+		return
+	}
+
+	h.writeHold(w, r, hpr)
+	msg := fmt.Sprintf("new hold #%d has been made for order ID %s", hpr.ID, hpr.Order)
+	h.log(r, LogLvlInfo, msg)
+}
+
+func (h *handler) captureHold(w http.ResponseWriter, r *http.Request) {
+	h.finishHold(w, r, h.gm.CaptureHold)
+}
+
+func (h *handler) releaseHold(w http.ResponseWriter, r *http.Request) {
+	h.finishHold(w, r, h.gm.ReleaseHold)
+}
+
+// finishHold общая часть завершения резерва: списание или возврат баллов
+func (h *handler) finishHold(w http.ResponseWriter, r *http.Request, finish func(holdID, userID uint64) (*gophermart.HoldProxy, error)) {
+	u := currentUser(r)
+
+	holdID, err := strconv.ParseUint(chi.URLParam(r, "holdID"), 10, 64)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("%s - %w", gophermart.ErrHoldNotFound, err), http.StatusNotFound)
+		return
+	}
+
+	hpr, err := finish(holdID, u.ID)
+	if err != nil {
+		h.holdError(w, r, err)
+		return
+	}
+
+	h.writeHold(w, r, hpr)
+	msg := fmt.Sprintf("hold #%d for order ID %s is %s now", hpr.ID, hpr.Order, hpr.Status)
+	h.log(r, LogLvlInfo, msg)
+}
+
+func (h *handler) holdError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	// 402 — на счету недостаточно средств
+	case errors.Is(err, gophermart.ErrNotEnoughFunds):
+		h.error(w, r, err, http.StatusPaymentRequired)
+	// 404 — резерв не найден
+	case errors.Is(err, gophermart.ErrHoldNotFound):
+		h.error(w, r, err, http.StatusNotFound)
+	// 409 — по заказу уже есть списание или резерв, либо резерв уже завершён
+	case errors.Is(err, gophermart.ErrWithdrawAlreadyRecorded),
+		errors.Is(err, gophermart.ErrHoldAlreadyExists),
+		errors.Is(err, gophermart.ErrHoldNotActive):
+		h.error(w, r, err, http.StatusConflict)
+	// 410 — резерв просрочен, баллы возвращены на счёт
+	case errors.Is(err, gophermart.ErrHoldExpired):
+		h.error(w, r, err, http.StatusGone)
+	// 422 — неверный формат номера заказа или сумма
+	case errors.Is(err, gophermart.ErrOrderInvalidFormat), errors.Is(err, gophermart.ErrInvalidAmount):
+		h.error(w, r, err, http.StatusUnprocessableEntity)
+	// 500 — внутренняя ошибка сервера
+	default:
+		h.error(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+func (h *handler) writeHold(w http.ResponseWriter, r *http.Request, hpr *gophermart.HoldProxy) {
+	body, err := json.Marshal(hpr)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write(body)
+}
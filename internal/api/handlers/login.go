@@ -5,10 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/sergeysynergy/hardtest/internal/gophermart"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 )
 
+// logoutGetSunset дата отключения устаревшего выхода через GET /api/user/logout
+var logoutGetSunset = time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC)
+
 func (h *handler) login(w http.ResponseWriter, r *http.Request) {
 	var creds *gophermart.Credentials
 	err := json.NewDecoder(r.Body).Decode(&creds)
@@ -18,14 +23,11 @@ func (h *handler) login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// извлечём токен для обнуления сессии
-	var sessionToken string
-	c, err := r.Cookie("session_token")
-	if err == nil {
-		sessionToken = c.Value
-	}
-
-	session, err := h.gm.Login(creds, sessionToken)
+	session, err := h.gm.Login(creds, h.sessionCookie(r), sessionClient(r))
 	if err != nil {
+		if h.loginThrottled(w, r, err) {
+			return
+		}
 		if errors.Is(err, gophermart.ErrInvalidPair) || errors.Is(err, gophermart.ErrUserNotFound) {
 			h.error(w, r, gophermart.ErrInvalidPair, http.StatusUnauthorized)
 			return
@@ -38,53 +40,47 @@ func (h *handler) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// создадим куку со сроком годности
-	http.SetCookie(w, &http.Cookie{
-		Name:    "session_token",
-		Value:   session.Token,
-		Expires: session.Expiry,
-	})
+	h.setSessionCookie(w, session)
 	msg := fmt.Sprintf("session for user `%s` successfully created", creds.Login)
 	h.log(r, LogLvlDebug, msg)
 }
 
+// loginThrottled отвечает 429 с заголовком Retry-After, если вход, проверка пароля или запрос его сброса
+// временно закрыты после череды попыток
+func (h *handler) loginThrottled(w http.ResponseWriter, r *http.Request, err error) bool {
+	var throttled *gophermart.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	// 429 — слишком много попыток
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	h.error(w, r, err, http.StatusTooManyRequests)
+	return true
+}
+
 func (h *handler) logout(w http.ResponseWriter, r *http.Request) {
-	c, err := r.Cookie("session_token")
-	if err != nil {
-		if err == http.ErrNoCookie {
-			h.error(w, r, gophermart.ErrUnauthorizedAccess, http.StatusUnauthorized)
-			return
-		}
-		h.error(w, r, err, http.StatusBadRequest)
+	token := h.sessionCookie(r)
+	if token == "" {
+		h.error(w, r, gophermart.ErrUnauthorizedAccess, http.StatusUnauthorized)
 		return
 	}
 
-	err = h.gm.Logout(c.Value)
+	err := h.gm.Logout(token)
 	if err != nil {
 		h.log(r, LogLvlError, fmt.Sprintf("failed to delete session - %s", err))
 	}
 
-	// установим протухший срок действия куки клиента
-	http.SetCookie(w, &http.Cookie{
-		Name:    "session_token",
-		Value:   "",
-		Expires: time.Now(),
-	})
+	// удалим куки сессии у клиента
+	h.clearSessionCookie(w)
 
-	msg := fmt.Sprintf("logout session %s", c.Value)
-	h.log(r, LogLvlDebug, msg)
+	// токен сессии в журнал не пишем
+	h.log(r, LogLvlDebug, "session closed")
 }
 
 func (h *handler) welcome(w http.ResponseWriter, r *http.Request) {
-	session, err := h.authCheck(w, r)
-	if err != nil {
-		return
-	}
-
-	u, err := h.gm.Users.Get(session.UserID)
-	if err != nil {
-		return
-	}
+	u := currentUser(r)
 
+	w.Header().Set("Content-Type", ContentTypeTextPlain)
 	w.Write([]byte(fmt.Sprintf("Welcome, #%d %s!", u.ID, u.Login)))
 }
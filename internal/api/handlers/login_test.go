@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestLoginThrottled(t *testing.T) {
+	limits := gophermart.LoginLimits{FreeAttempts: 1, BaseDelay: 90 * time.Second, Window: time.Hour}
+	gm := gophermart.New(basicstorage.New(), gophermart.WithLoginLimits(limits, limits))
+	router := New(gm).GetRouter()
+
+	tests := []struct {
+		name       string
+		target     string
+		password   string
+		statusCode int
+	}{
+		{name: "first failure", target: "/api/user/login", password: "wrong", statusCode: http.StatusUnauthorized},
+		{name: "second failure", target: "/api/user/login", password: "wrong", statusCode: http.StatusUnauthorized},
+		{name: "throttled", target: "/api/user/login", password: "wrong", statusCode: http.StatusTooManyRequests},
+		{name: "throttled v2", target: "/api/v2/user/login", password: "wrong", statusCode: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"login":"gopher","password":"` + tt.password + `"}`
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(body))
+			req.Header.Set("Content-Type", ContentTypeApplicationJSON)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusTooManyRequests {
+				retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+				require.NoError(t, err)
+				assert.InDelta(t, 90, retryAfter, 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// getMetrics отдаёт счётчики сервиса в JSON: только свои, без стандартных cmdline и memstats пакета expvar —
+// в аргументах запуска передаются секреты
+func (h *handler) getMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write([]byte(gophermart.Metrics.String()))
+}
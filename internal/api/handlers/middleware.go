@@ -1,39 +1,138 @@
 package handlers
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
-	"github.com/sergeysynergy/hardtest/internal/gophermart"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
 )
 
-func (h *handler) authCheck(w http.ResponseWriter, r *http.Request) (*gophermart.Session, error) {
-	// извлечём токен сессии
-	c, err := r.Cookie("session_token")
-	if err != nil {
-		if err == http.ErrNoCookie {
-			h.error(w, r, gophermart.ErrUnauthorizedAccess, http.StatusUnauthorized)
-			return nil, gophermart.ErrUnauthorizedAccess
+type (
+	userKey     struct{}
+	sessionKey  struct{}
+	operatorKey struct{}
+)
+
+// authenticate миделвара защищённых маршрутов: проверяет сессию и кладёт пользователя и сессию в контекст запроса,
+// на любую ошибку аутентификации отвечает 401 и до обработчика запрос не доходит.
+// Продлённая при активности сессия по куке получает куку с новым сроком
+func (h *handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, renewedCookie, err := h.sessionFromRequest(r)
+		if err != nil {
+			// 401 — пользователь не авторизован
+			h.error(w, r, err, http.StatusUnauthorized)
+			return
+		}
+		if renewedCookie {
+			h.setSessionCookie(w, session)
+		}
+
+		user, err := h.gm.Users.Get(session.UserID)
+		if err != nil {
+			// сессия пережила удалённого пользователя
+			if errors.Is(err, gophermart.ErrUserNotFound) {
+				h.error(w, r, gophermart.ErrUnauthorizedAccess, http.StatusUnauthorized)
+				return
+			}
+			h.error(w, r, fmt.Errorf("failed to get user by ID - %w", err), http.StatusInternalServerError)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), sessionKey{}, session)
+		ctx = context.WithValue(ctx, userKey{}, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// sessionFromRequest находит действующую сессию по токену доступа из заголовка `Authorization: Bearer <token>`
+// или по куке `session_token`; renewedCookie сообщает, что сессия по куке продлена и куку нужно обновить
+func (h *handler) sessionFromRequest(r *http.Request) (session *gophermart.Session, renewedCookie bool, err error) {
+	token, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !bearer {
+		if token = h.sessionCookie(r); token == "" {
+			return nil, false, gophermart.ErrUnauthorizedAccess
 		}
-		h.error(w, r, err, http.StatusBadRequest)
-		return nil, err
 	}
-	sessionToken := c.Value
 
-	// получим сессию из хранилища по токену
-	session, err := h.gm.Sessions.Get(sessionToken)
+	session, renewed, err := h.gm.Authenticate(token)
 	if err != nil {
-		err = fmt.Errorf("session token is not present")
-		h.error(w, r, err, http.StatusUnauthorized)
-		return nil, err
+		return nil, false, err
 	}
 
-	// Удаляем сессию и выходим, если прошёл срок годности
-	if session.IsExpired() {
-		h.gm.Sessions.Delete(sessionToken)
-		err = fmt.Errorf("session has expired")
-		h.error(w, r, err, http.StatusUnauthorized)
-		return nil, err
+	return session, renewed && !bearer, nil
+}
+
+// currentUser пользователь, аутентифицированный миделварой authenticate
+func currentUser(r *http.Request) *gophermart.User {
+	u, _ := r.Context().Value(userKey{}).(*gophermart.User)
+	return u
+}
+
+// currentSession сессия, проверенная миделварой authenticate
+func currentSession(r *http.Request) *gophermart.Session {
+	s, _ := r.Context().Value(sessionKey{}).(*gophermart.Session)
+	return s
+}
+
+// currentOperator идентификатор оператора, чей токен проверила миделвара adminOnly
+func currentOperator(r *http.Request) string {
+	operatorID, _ := r.Context().Value(operatorKey{}).(string)
+	return operatorID
+}
+
+// adminOnly миделвара административного API: токен оператора передаётся в заголовке `Authorization: Bearer <token>`,
+// идентификатор оператора кладётся в контекст запроса
+func (h *handler) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(h.adminTokens) == 0 {
+			// 403 — административное API отключено
+			h.error(w, r, fmt.Errorf("admin API is disabled"), http.StatusForbidden)
+			return
+		}
+
+		token, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		operatorID := h.adminOperator(token)
+		if !bearer || operatorID == "" {
+			// 401 — неверный токен администратора
+			h.error(w, r, gophermart.ErrUnauthorizedAccess, http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), operatorKey{}, operatorID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// adminOperator сравнивает токен со всеми токенами операторов за постоянное время, чтобы по времени ответа
+// нельзя было подбирать токен
+func (h *handler) adminOperator(token string) string {
+	var operatorID string
+	for adminToken, id := range h.adminTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+			operatorID = id
+		}
 	}
 
-	return session, nil
+	return operatorID
+}
+
+// deprecated миделвара устаревших маршрутов: заголовки Deprecation и Sunset (RFC 8594) сообщают клиенту дату
+// отключения маршрута, а журнал показывает, кто им ещё пользуется
+func (h *handler) deprecated(sunset time.Time) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Sunset", sunset.Format(http.TimeFormat))
+			h.log(r, LogLvlWarning, fmt.Sprintf("deprecated route %s %s, will be removed on %s",
+				r.Method, r.URL.Path, sunset.Format(dateLayout)))
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestAuthenticate(t *testing.T) {
+	gm := gophermart.New(basicstorage.New())
+	h := New(gm)
+	session, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+
+	var login string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		login = currentUser(r).Login
+		assert.Equal(t, session.Token, currentSession(r).Token)
+	})
+
+	tests := []struct {
+		name       string
+		token      string
+		statusCode int
+	}{
+		{name: "no cookie", statusCode: http.StatusUnauthorized},
+		{name: "unknown session", token: "unknown", statusCode: http.StatusUnauthorized},
+		{name: "valid session", token: session.Token, statusCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login = ""
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.token})
+			}
+			rec := httptest.NewRecorder()
+			h.authenticate(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Equal(t, "gopher", login)
+			} else {
+				assert.Empty(t, login)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRenewsCookie(t *testing.T) {
+	const idle = 100 * time.Millisecond
+	gm := gophermart.New(basicstorage.New(), gophermart.WithRememberMeTTL(idle, time.Hour))
+	h := New(gm)
+	session, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33", Remember: true}, nil)
+	require.NoError(t, err)
+
+	serve := func(bearer bool) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if bearer {
+			req.Header.Set("Authorization", "Bearer "+session.Token)
+		} else {
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+		}
+		rec := httptest.NewRecorder()
+		h.authenticate(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Result()
+	}
+
+	// пока не прошла половина срока бездействия, сессия не продлевается и кука не выдаётся
+	assert.Empty(t, serve(false).Cookies())
+
+	time.Sleep(idle * 3 / 4)
+	cookies := serve(false).Cookies()
+	require.Len(t, cookies, 2)
+	assert.Equal(t, session.Token, cookies[0].Value)
+	assert.False(t, cookies[0].Expires.IsZero(), "remember me cookie must be persistent")
+
+	// сессия по заголовку продлевается без куки
+	time.Sleep(idle * 3 / 4)
+	assert.Empty(t, serve(true).Cookies())
+}
+
+func TestAdminOnly(t *testing.T) {
+	h := New(gophermart.New(basicstorage.New()), WithAdminTokens(map[string]string{"alice": "alice-token", "bob": "bob-token"}))
+
+	var operatorID string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operatorID = currentOperator(r)
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		statusCode    int
+		operatorID    string
+	}{
+		{name: "no token", statusCode: http.StatusUnauthorized},
+		{name: "no bearer prefix", authorization: "bob-token", statusCode: http.StatusUnauthorized},
+		{name: "unknown token", authorization: "Bearer unknown", statusCode: http.StatusUnauthorized},
+		{name: "operator token", authorization: "Bearer bob-token", statusCode: http.StatusOK, operatorID: "bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operatorID = ""
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.adminOnly(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.statusCode, rec.Code)
+			assert.Equal(t, tt.operatorID, operatorID)
+		})
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{
+			name: "headers from untrusted peer are ignored", remoteAddr: "192.0.2.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.1"}, want: "192.0.2.1",
+		},
+		{
+			name: "trusted proxy", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1"}, want: "198.51.100.1",
+		},
+		{
+			name: "spoofed hop before client", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7, 198.51.100.1, 10.0.0.2"}, want: "198.51.100.1",
+		},
+		{
+			name: "real ip from trusted proxy", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"X-Real-IP": "198.51.100.1"}, want: "198.51.100.1",
+		},
+		{
+			name: "malformed forwarded address", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "unknown"}, want: "10.0.0.1",
+		},
+	}
+	h := New(gophermart.New(basicstorage.New()), WithTrustedProxies([]*net.IPNet{proxies}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = sessionClient(r).IP
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			h.realIP(next).ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDeprecatedLogout(t *testing.T) {
+	gm := gophermart.New(basicstorage.New())
+	router := New(gm).GetRouter()
+	session, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		target     string
+		statusCode int
+		sunset     string
+	}{
+		{name: "v1 announces sunset", target: "/api/user/logout", statusCode: http.StatusOK, sunset: "Mon, 01 Mar 2027 00:00:00 GMT"},
+		{name: "v2 has no GET", target: "/api/v2/user/logout", statusCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.statusCode, rec.Code)
+			assert.Equal(t, tt.sunset, rec.Header().Get("Sunset"))
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec контракт API в формате OpenAPI 3: при изменении маршрутов в setRoutes его нужно обновлять,
+// соответствие ответов обработчиков спецификации проверяют тесты
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (h *handler) getOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write(openAPISpec)
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func loadSpec(t *testing.T) (*openapi3.T, routers.Router) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+
+	router, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+
+	return doc, router
+}
+
+// specValidator проверяет запросы и ответы обработчиков на соответствие спецификации
+func specValidator(t *testing.T, router routers.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := router.FindRoute(r)
+		if err != nil {
+			t.Errorf("%s %s: route is missing in spec - %s", r.Method, r.URL.Path, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		opts := &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+		reqInput := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    opts,
+		}
+		reqErr := openapi3filter.ValidateRequest(r.Context(), reqInput)
+
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+
+		// запрос, противоречащий спецификации, обработчик обязан отклонить
+		if reqErr != nil && rec.Code < http.StatusBadRequest {
+			t.Errorf("%s %s: request does not match spec but accepted - %s", r.Method, r.URL.Path, reqErr)
+		}
+
+		respInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: reqInput,
+			Status:                 rec.Code,
+			Header:                 rec.Header(),
+			Options:                opts,
+		}
+		respInput.SetBodyBytes(rec.Body.Bytes())
+		if err = openapi3filter.ValidateResponse(r.Context(), respInput); err != nil {
+			t.Errorf("%s %s: response %d does not match spec - %s", r.Method, r.URL.Path, rec.Code, err)
+		}
+
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	})
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	doc, _ := loadSpec(t)
+	h := New(gophermart.New(basicstorage.New()))
+
+	routes := make(map[string]bool)
+	err := chi.Walk(h.GetRouter(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes[method+" "+route] = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	documented := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method := range item.Operations() {
+			documented[method+" "+path] = true
+		}
+	}
+
+	assert.Equal(t, routes, documented)
+}
+
+func TestHandlersMatchSpec(t *testing.T) {
+	_, router := loadSpec(t)
+	gm := gophermart.New(basicstorage.New())
+	srv := specValidator(t, router, New(gm).GetRouter())
+
+	var cookies []*http.Cookie
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		header      map[string]string
+		noAuth      bool
+		statusCode  int
+	}{
+		{name: "spec", method: http.MethodGet, target: "/api/openapi.json", statusCode: http.StatusOK},
+		{
+			name: "register", method: http.MethodPost, target: "/api/user/register",
+			contentType: ContentTypeApplicationJSON, body: `{"login":"gopher","password":"Passw0rd33"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "register: weak password", method: http.MethodPost, target: "/api/user/register",
+			contentType: ContentTypeApplicationJSON, body: `{"login":"rabbit","password":"password"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "register: login already taken", method: http.MethodPost, target: "/api/user/register",
+			contentType: ContentTypeApplicationJSON, body: `{"login":"gopher","password":"Passw0rd33"}`,
+			statusCode: http.StatusConflict,
+		},
+		{
+			name: "login: invalid pair", method: http.MethodPost, target: "/api/user/login",
+			contentType: ContentTypeApplicationJSON, body: `{"login":"gopher","password":"wrong"}`,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "login", method: http.MethodPost, target: "/api/user/login",
+			contentType: ContentTypeApplicationJSON, body: `{"login":"gopher","password":"Passw0rd33"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "token: JWT mode disabled", method: http.MethodPost, target: "/api/user/token",
+			contentType: ContentTypeApplicationJSON, body: `{"login":"gopher","password":"Passw0rd33"}`,
+			statusCode: http.StatusNotFound,
+		},
+		{name: "welcome", method: http.MethodGet, target: "/api/user/welcome", statusCode: http.StatusOK},
+		{name: "balance: unauthorized", method: http.MethodGet, target: "/api/user/balance", noAuth: true, statusCode: http.StatusUnauthorized},
+		{
+			name: "post order", method: http.MethodPost, target: "/api/user/orders",
+			contentType: ContentTypeTextPlain, body: "12345678903", statusCode: http.StatusAccepted,
+		},
+		{
+			name: "post order: already uploaded", method: http.MethodPost, target: "/api/user/orders",
+			contentType: ContentTypeTextPlain, body: "12345678903", statusCode: http.StatusOK,
+		},
+		{
+			name: "post order: invalid number", method: http.MethodPost, target: "/api/user/orders",
+			contentType: ContentTypeTextPlain, body: "12345678901", statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "post orders batch", method: http.MethodPost, target: "/api/user/orders/batch",
+			contentType: ContentTypeApplicationJSON, body: `["12345678903", 4561261212345467, "1"]`,
+			statusCode: http.StatusOK,
+		},
+		{name: "get orders", method: http.MethodGet, target: "/api/user/orders?limit=1&sort=desc", statusCode: http.StatusOK},
+		{name: "get orders: invalid sort", method: http.MethodGet, target: "/api/user/orders?sort=up", statusCode: http.StatusBadRequest},
+		{name: "balance", method: http.MethodGet, target: "/api/user/balance", statusCode: http.StatusOK},
+		{
+			name: "withdraw: not enough funds", method: http.MethodPost, target: "/api/user/balance/withdraw",
+			contentType: ContentTypeApplicationJSON, body: `{"order":"2377225624","sum":751}`,
+			statusCode: http.StatusPaymentRequired,
+		},
+		{
+			name: "withdrawals: no content", method: http.MethodGet, target: "/api/user/balance/withdrawals",
+			header: map[string]string{"Content-Length": "0"}, statusCode: http.StatusNoContent,
+		},
+		{name: "v2 balance", method: http.MethodGet, target: "/api/v2/user/balance", statusCode: http.StatusOK},
+		{name: "v2 balance: unauthorized", method: http.MethodGet, target: "/api/v2/user/balance", noAuth: true, statusCode: http.StatusUnauthorized},
+		{name: "v2 orders", method: http.MethodGet, target: "/api/v2/user/orders?limit=1", statusCode: http.StatusOK},
+		{name: "v2 orders: invalid cursor", method: http.MethodGet, target: "/api/v2/user/orders?cursor=bad", statusCode: http.StatusBadRequest},
+		{
+			name: "v2 withdraw: sum as number", method: http.MethodPost, target: "/api/v2/user/balance/withdraw",
+			contentType: ContentTypeApplicationJSON, body: `{"order":"2377225624","sum":751}`,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "v2 withdraw: not enough funds", method: http.MethodPost, target: "/api/v2/user/balance/withdraw",
+			contentType: ContentTypeApplicationJSON, body: `{"order":"2377225624","sum":"751.00"}`,
+			statusCode: http.StatusPaymentRequired,
+		},
+		{name: "v2 withdrawals: empty page", method: http.MethodGet, target: "/api/v2/user/balance/withdrawals", statusCode: http.StatusOK},
+		{name: "v2 withdrawals: invalid cursor", method: http.MethodGet, target: "/api/v2/user/balance/withdrawals?cursor=bad", statusCode: http.StatusBadRequest},
+		{name: "sessions", method: http.MethodGet, target: "/api/user/sessions", statusCode: http.StatusOK},
+		{name: "delete session: not found", method: http.MethodDelete, target: "/api/user/sessions/unknown", statusCode: http.StatusNotFound},
+		{
+			name: "password: wrong old password", method: http.MethodPost, target: "/api/user/password",
+			contentType: ContentTypeApplicationJSON, body: `{"old_password":"wrong","new_password":"Passw0rd33"}`,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name: "password: same as current", method: http.MethodPost, target: "/api/user/password",
+			contentType: ContentTypeApplicationJSON, body: `{"old_password":"Passw0rd33","new_password":"Passw0rd33"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "password", method: http.MethodPost, target: "/api/user/password",
+			contentType: ContentTypeApplicationJSON, body: `{"old_password":"Passw0rd33","new_password":"N3wPassw0rd"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "password reset: disabled", method: http.MethodPost, target: "/api/user/password/reset",
+			contentType: ContentTypeApplicationJSON, body: `{"login":"gopher"}`, noAuth: true,
+			statusCode: http.StatusNotFound,
+		},
+		{name: "logout: deprecated GET", method: http.MethodGet, target: "/api/user/logout", noAuth: true, statusCode: http.StatusUnauthorized},
+		{name: "logout", method: http.MethodPost, target: "/api/user/logout", statusCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			// сценарии играют роль браузерного клиента самого сервиса
+			req.Header.Set("Origin", "http://example.com")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			if !tt.noAuth {
+				for _, c := range cookies {
+					req.AddCookie(c)
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+			if len(res.Cookies()) > 0 {
+				cookies = res.Cookies()
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 func (h *handler) postOrders(w http.ResponseWriter, r *http.Request) {
@@ -20,17 +21,7 @@ func (h *handler) postOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c, err := h.authCheck(w, r)
-	if err != nil {
-		// 401 — пользователь не авторизован
-		return
-	}
-
-	u, err := h.gm.Users.Get(c.UserID)
-	if err != nil {
-		h.error(w, r, fmt.Errorf("failed to get user by ID - %w", err), http.StatusInternalServerError)
-		return
-	}
+	u := currentUser(r)
 
 	reqBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -80,16 +71,7 @@ func (h *handler) postOrders(w http.ResponseWriter, r *http.Request) {
 func (h *handler) getOrders(w http.ResponseWriter, r *http.Request) {
 	var err error
 
-	c, err := h.authCheck(w, r)
-	if err != nil {
-		// 401 — пользователь не авторизован
-		return
-	}
-	u, err := h.gm.Users.Get(c.UserID)
-	if err != nil {
-		h.error(w, r, fmt.Errorf("failed to get user by ID - %w", err), http.StatusInternalServerError)
-		return
-	}
+	u := currentUser(r)
 
 	userID := u.ID
 
@@ -101,8 +83,19 @@ func (h *handler) getOrders(w http.ResponseWriter, r *http.Request) {
 	//	return
 	//}
 
-	proxyOrders, err := h.gm.GetOrders(userID)
+	filter, err := ordersFilter(r)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	proxyOrders, nextCursor, err := h.gm.GetOrders(userID, filter)
 	if err != nil {
+		// 400 — неверные параметры фильтрации
+		if errors.Is(err, gophermart.ErrInvalidOrdersFilter) || errors.Is(err, gophermart.ErrInvalidPeriod) {
+			h.error(w, r, err, http.StatusBadRequest)
+			return
+		}
 		h.error(w, r, fmt.Errorf("failed to get all orders - %w", err), http.StatusInternalServerError)
 		return
 	}
@@ -118,6 +111,99 @@ func (h *handler) getOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// тело ответа остаётся списком заказов, курсор следующей страницы передаём заголовком
+	if nextCursor != "" {
+		w.Header().Set(HeaderNextCursor, nextCursor)
+	}
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write(body)
+}
+
+// ordersFilter собирает фильтр списка заказов из параметров запроса:
+// status (через запятую), from, to, sort (asc или desc), limit и cursor
+func ordersFilter(r *http.Request) (*gophermart.OrdersFilter, error) {
+	var err error
+	q := r.URL.Query()
+	filter := &gophermart.OrdersFilter{
+		Sort: strings.ToLower(q.Get("sort")),
+	}
+
+	if statuses := q.Get("status"); statuses != "" {
+		for _, status := range strings.Split(statuses, ",") {
+			filter.Statuses = append(filter.Statuses, strings.ToUpper(strings.TrimSpace(status)))
+		}
+	}
+	if filter.From, filter.To, err = queryPeriod(r); err != nil {
+		return nil, err
+	}
+	if filter.Limit, err = queryUint(r, "limit"); err != nil {
+		return nil, err
+	}
+	if cursor := q.Get("cursor"); cursor != "" {
+		if filter.Cursor, err = gophermart.DecodeOrdersCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	return filter, nil
+}
+
+// postOrdersBatch загрузка пакета номеров заказов: JSON-массивом или списком через перевод строки
+func (h *handler) postOrdersBatch(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to read request body - %w", err), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var numbers []string
+	switch r.Header.Get("Content-Type") {
+	case ContentTypeApplicationJSON:
+		// номера принимаем как строками, так и числами
+		var raw []json.RawMessage
+		if err = json.Unmarshal(reqBody, &raw); err != nil {
+			h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+			return
+		}
+		for _, v := range raw {
+			numbers = append(numbers, strings.Trim(string(v), `"`))
+		}
+	case ContentTypeTextPlain:
+		for _, line := range strings.Split(string(reqBody), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				numbers = append(numbers, line)
+			}
+		}
+	default:
+		err = fmt.Errorf("wrong content type, %s or %s needed", ContentTypeApplicationJSON, ContentTypeTextPlain)
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.gm.PostOrdersBatch(numbers, u.ID)
+	if err != nil {
+		// 400 — пустой или слишком большой пакет
+		if errors.Is(err, gophermart.ErrInvalidBatchSize) {
+			h.error(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		// 500 — внутренняя ошибка сервера
+		h.error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(&results)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
 	w.Write(body)
+	msg := fmt.Sprintf("batch of %d order numbers has been processed", len(results))
+	h.log(r, LogLvlInfo, msg)
 }
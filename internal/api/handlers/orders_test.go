@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestPostOrdersBatch(t *testing.T) {
+	gm := gophermart.New(basicstorage.New())
+	router := New(gm).GetRouter()
+	session, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		statusCode  int
+		want        string
+	}{
+		{
+			name:        "json strings and numbers",
+			contentType: ContentTypeApplicationJSON,
+			body:        `["12345678903", 2377225624, "12345678900"]`,
+			statusCode:  http.StatusOK,
+			want: `[{"number":"12345678903","status":"accepted"},{"number":"2377225624","status":"accepted"},` +
+				`{"number":"12345678900","status":"invalid_format"}]`,
+		},
+		{
+			name:        "text lines",
+			contentType: ContentTypeTextPlain,
+			body:        "79927398713\n\n  12345678903  \n",
+			statusCode:  http.StatusOK,
+			want:        `[{"number":"79927398713","status":"accepted"},{"number":"12345678903","status":"already_uploaded"}]`,
+		},
+		{name: "empty batch", contentType: ContentTypeApplicationJSON, body: `[]`, statusCode: http.StatusBadRequest},
+		{name: "empty text", contentType: ContentTypeTextPlain, body: "\n", statusCode: http.StatusBadRequest},
+		{name: "not an array", contentType: ContentTypeApplicationJSON, body: `{"order":"12345678903"}`, statusCode: http.StatusBadRequest},
+		{name: "wrong content type", contentType: "application/xml", body: `<orders/>`, statusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/api/user/orders/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Origin", "http://example.com")
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.statusCode, rec.Code, rec.Body.String())
+			if tt.want != "" {
+				assert.JSONEq(t, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type passwordResetRequest struct {
+	Login string `json:"login"`
+}
+
+type passwordResetConfirmRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// postPassword меняет пароль пользователя; остальные сессии пользователя завершаются, текущая остаётся
+func (h *handler) postPassword(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	req := &changePasswordRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+
+	err := h.gm.ChangePassword(u.ID, req.OldPassword, req.NewPassword, currentSession(r).ID)
+	if err != nil {
+		h.passwordError(w, r, err)
+		return
+	}
+
+	h.log(r, LogLvlInfo, fmt.Sprintf("password of user #%d changed", u.ID))
+}
+
+// postPasswordReset отправляет пользователю токен сброса пароля; ответ одинаков для известного и неизвестного логина
+func (h *handler) postPasswordReset(w http.ResponseWriter, r *http.Request) {
+	req := &passwordResetRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.gm.RequestPasswordReset(req.Login); err != nil {
+		h.passwordError(w, r, err)
+		return
+	}
+
+	// 202 — запрос принят, токен отправлен, если логин существует
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// postPasswordResetConfirm задаёт новый пароль по токену сброса и завершает все сессии пользователя
+func (h *handler) postPasswordResetConfirm(w http.ResponseWriter, r *http.Request) {
+	req := &passwordResetConfirmRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.gm.ResetPassword(req.Token, req.NewPassword); err != nil {
+		h.passwordError(w, r, err)
+		return
+	}
+
+	h.log(r, LogLvlInfo, "password reset")
+}
+
+func (h *handler) passwordError(w http.ResponseWriter, r *http.Request, err error) {
+	if h.loginThrottled(w, r, err) {
+		return
+	}
+
+	switch {
+	// 403 — неверный текущий пароль
+	case errors.Is(err, gophermart.ErrWrongPassword):
+		h.error(w, r, err, http.StatusForbidden)
+	// 404 — сброс пароля выключен
+	case errors.Is(err, gophermart.ErrPasswordResetDisabled):
+		h.error(w, r, err, http.StatusNotFound)
+	// 401 — токен сброса неизвестен, уже использован или истёк
+	case errors.Is(err, gophermart.ErrInvalidResetToken):
+		h.error(w, r, err, http.StatusUnauthorized)
+	// 400 — новый пароль не отвечает требованиям или совпадает с текущим
+	case errors.Is(err, gophermart.ErrInvalidPassword):
+		h.error(w, r, err, http.StatusBadRequest)
+	// 500 — внутренняя ошибка сервера
+	default:
+		h.error(w, r, err, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// WithTrustedProxies подсети обратных прокси, от которых принимаются заголовки X-Forwarded-For и X-Real-IP;
+// без доверенных прокси адрес клиента — адрес соединения, и подделать его заголовком нельзя
+func WithTrustedProxies(proxies []*net.IPNet) Option {
+	return func(h *handler) {
+		h.trustedProxies = proxies
+	}
+}
+
+// realIP миделвара подставляет в r.RemoteAddr адрес клиента из заголовков прокси, но только если соединение
+// пришло от доверенного прокси: по этому адресу считаются неудачные попытки входа и показывается список сессий
+func (h *handler) realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := h.forwardedIP(r); ok {
+			r.RemoteAddr = ip
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedIP адрес клиента из заголовков доверенного прокси. Начало X-Forwarded-For задаёт сам клиент,
+// поэтому цепочка разбирается с конца: адрес клиента — первый адрес, не принадлежащий доверенным прокси
+func (h *handler) forwardedIP(r *http.Request) (string, bool) {
+	peer := net.ParseIP(remoteHost(r.RemoteAddr))
+	if !h.trustedProxy(peer) {
+		return "", false
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		var client net.IP
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				break
+			}
+			client = hop
+			if !h.trustedProxy(hop) {
+				break
+			}
+		}
+		if client == nil {
+			return "", false
+		}
+		return client.String(), true
+	}
+
+	if real := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); real != nil {
+		return real.String(), true
+	}
+
+	return "", false
+}
+
+func (h *handler) trustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range h.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remoteHost адрес из r.RemoteAddr без порта; после realIP порта в нём нет
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// queryTime разбирает параметр запроса с датой в формате RFC3339 или YYYY-MM-DD,
+// для отсутствующего параметра возвращается нулевое время
+func queryTime(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(dateLayout, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid `%s` parameter: RFC3339 or %s date needed", name, dateLayout)
+	}
+
+	return t, nil
+}
+
+// queryPeriod разбирает параметры периода `from` и `to`; конец периода не включается,
+// но дата без времени в `to` означает весь этот день целиком
+func queryPeriod(r *http.Request) (from, to time.Time, err error) {
+	if from, err = queryTime(r, "from"); err != nil {
+		return
+	}
+	if to, err = queryTime(r, "to"); err != nil {
+		return
+	}
+	if _, errDate := time.Parse(dateLayout, r.URL.Query().Get("to")); errDate == nil {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	return
+}
+
+// queryUint разбирает целочисленный параметр запроса, для отсутствующего параметра возвращается 0
+func queryUint(r *http.Request, name string) (uint64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid `%s` parameter: non-negative integer needed", name)
+	}
+
+	return n, nil
+}
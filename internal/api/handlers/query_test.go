@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPeriod(t *testing.T) {
+	day := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{name: "empty"},
+		{name: "dates", query: "from=2022-01-02&to=2022-01-02", wantFrom: day, wantTo: day.AddDate(0, 0, 1)},
+		{name: "RFC3339", query: "from=2022-01-02T00:00:00Z&to=2022-01-02T12:00:00Z",
+			wantFrom: day, wantTo: day.Add(12 * time.Hour)},
+		{name: "invalid from", query: "from=02.01.2022", wantErr: true},
+		{name: "invalid to", query: "to=yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/user/balance/statement?"+tt.query, nil)
+			from, to, err := queryPeriod(r)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.wantFrom.Equal(from), "from: %s", from)
+			assert.True(t, tt.wantTo.Equal(to), "to: %s", to)
+		})
+	}
+}
+
+func TestQueryUint(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    uint64
+		wantErr bool
+	}{
+		{query: "", want: 0},
+		{query: "limit=25", want: 25},
+		{query: "limit=-1", wantErr: true},
+		{query: "limit=ten", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/user/balance/statement?"+tt.query, nil)
+			n, err := queryUint(r, "limit")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, n)
+		})
+	}
+}
@@ -32,13 +32,18 @@ func (h *handler) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, err := h.gm.Register(&creds)
+	session, err := h.gm.Register(&creds, sessionClient(r))
 	if err != nil {
 		msg := "failed to register new user"
 		if errors.Is(err, gophermart.ErrLoginAlreadyTaken) {
 			h.error(w, r, fmt.Errorf("%s - %w", msg, err), http.StatusConflict)
 			return
 		}
+		// 400 — логин или пароль не отвечают требованиям
+		if errors.Is(err, gophermart.ErrInvalidLogin) || errors.Is(err, gophermart.ErrInvalidPassword) {
+			h.error(w, r, fmt.Errorf("%s - %w", msg, err), http.StatusBadRequest)
+			return
+		}
 		h.error(w, r, fmt.Errorf("%s - %w", msg, err), http.StatusInternalServerError)
 		return
 	}
@@ -47,12 +52,7 @@ func (h *handler) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// создадим куку со сроком годности
-	http.SetCookie(w, &http.Cookie{
-		Name:    "session_token",
-		Value:   session.Token,
-		Expires: session.Expiry,
-	})
+	h.setSessionCookie(w, session)
 
 	msg := fmt.Sprintf("session for user `%s` successfully created", creds.Login)
 	h.log(r, LogLvlDebug, msg)
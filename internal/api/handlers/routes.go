@@ -4,17 +4,99 @@ import "github.com/go-chi/chi/v5"
 
 // GetRoutes объявим роуты, используя маршрутизатор chi
 func (h *handler) setRoutes() {
+	h.r.Get("/api/openapi.json", h.getOpenAPI)
+
 	h.r.Route("/api/user", func(r chi.Router) {
+		r.Use(h.csrfProtect)
+
 		r.Post("/register", h.register)
 		r.Post("/login", h.login)
-		r.Get("/logout", h.logout)
-		r.Get("/welcome", h.welcome)
+		r.Post("/logout", h.logout)
+		// выход меняет состояние, поэтому идёт через POST под защитой от CSRF; GET оставлен для старых клиентов
+		// до даты отключения, в API v2 его нет
+		r.With(h.deprecated(logoutGetSunset)).Get("/logout", h.logout)
+
+		r.Post("/token", h.postToken)
+		r.Post("/token/refresh", h.postTokenRefresh)
+		r.Post("/token/revoke", h.postTokenRevoke)
+
+		r.Post("/password/reset", h.postPasswordReset)
+		r.Post("/password/reset/confirm", h.postPasswordResetConfirm)
+
+		// маршруты группы доступны только аутентифицированному пользователю — по куке сессии или токену доступа,
+		// обработчики берут пользователя из контекста
+		r.Group(func(r chi.Router) {
+			r.Use(h.authenticate)
+
+			r.Get("/welcome", h.welcome)
+
+			r.Post("/password", h.postPassword)
+
+			r.Get("/sessions", h.getSessions)
+			r.Delete("/sessions", h.deleteSessions)
+			r.Delete("/sessions/{sessionID}", h.deleteSession)
+
+			r.Post("/orders", h.postOrders)
+			r.Get("/orders", h.getOrders)
+			r.Post("/orders/batch", h.postOrdersBatch)
+
+			r.Get("/balance", h.getBalance)
+			r.Post("/balance/withdraw", h.postWithdraw)
+			r.Get("/balance/withdrawals", h.getWithdrawals)
+
+			r.Post("/balance/transfer", h.postTransfer)
+			r.Get("/balance/transfers", h.getTransfers)
+
+			r.Get("/balance/adjustments", h.getAdjustments)
+			r.Get("/balance/statement", h.getStatement)
+
+			r.Get("/export", h.exportUser)
+			r.Get("/events", h.getEvents)
+
+			r.Post("/webhooks", h.postWebhook(userOwner))
+			r.Get("/webhooks", h.getWebhooks(userOwner))
+			r.Delete("/webhooks/{webhookID}", h.deleteWebhook(userOwner))
+			r.Get("/webhooks/{webhookID}/deliveries", h.getWebhookDeliveries(userOwner))
+
+			r.Post("/balance/holds", h.postHold)
+			r.Post("/balance/holds/{holdID}/capture", h.captureHold)
+			r.Post("/balance/holds/{holdID}/release", h.releaseHold)
+		})
+	})
+
+	// v2 меняет только формат ответов: маршруты с прежним ответом используют обработчики v1,
+	// а ошибки в них всё равно отдаются в формате v2
+	h.r.Route("/api/v2/user", func(r chi.Router) {
+		r.Use(withAPIVersion(apiV2))
+		r.Use(h.csrfProtect)
+
+		r.Post("/register", h.register)
+		r.Post("/login", h.login)
+		r.Post("/logout", h.logout)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.authenticate)
+
+			r.Post("/orders", h.postOrders)
+			r.Get("/orders", h.getOrdersV2)
+
+			r.Get("/balance", h.getBalanceV2)
+			r.Post("/balance/withdraw", h.postWithdrawV2)
+			r.Get("/balance/withdrawals", h.getWithdrawalsV2)
+			r.Get("/balance/statement", h.getStatementV2)
+		})
+	})
+
+	h.r.Route("/api/admin", func(r chi.Router) {
+		r.Use(h.adminOnly)
 
-		r.Post("/orders", h.postOrders)
-		r.Get("/orders", h.getOrders)
+		r.Post("/balance/adjustments", h.postAdjustment)
+		r.Get("/export", h.exportAdmin)
+		r.Get("/metrics", h.getMetrics)
 
-		r.Get("/balance", h.getBalance)
-		r.Post("/balance/withdraw", h.postWithdraw)
-		r.Get("/balance/withdrawals", h.getWithdrawals)
+		r.Post("/webhooks", h.postWebhook(adminOwner))
+		r.Get("/webhooks", h.getWebhooks(adminOwner))
+		r.Delete("/webhooks/{webhookID}", h.deleteWebhook(adminOwner))
+		r.Get("/webhooks/{webhookID}/deliveries", h.getWebhookDeliveries(adminOwner))
 	})
 }
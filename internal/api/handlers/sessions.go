@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// sessionClient адрес и браузер клиента для списка сессий и ограничения попыток входа; адрес из заголовков
+// доверенного прокси уже подставила миделвара realIP
+func sessionClient(r *http.Request) *gophermart.SessionClient {
+	return &gophermart.SessionClient{IP: remoteHost(r.RemoteAddr), UserAgent: r.UserAgent()}
+}
+
+// getSessions действующие сессии пользователя: по кукам на разных устройствах и семейства refresh-токенов
+func (h *handler) getSessions(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	sessions, err := h.gm.GetSessions(u.ID, currentSession(r).ID)
+	if err != nil {
+		// 204 — нет ни одной сессии, например при входе по токену доступа после отзыва семейства
+		if errors.Is(err, gophermart.ErrNoContent) {
+			h.error(w, r, gophermart.ErrNoContent, http.StatusNoContent)
+			return
+		}
+
+		// 500 — внутренняя ошибка сервера
+		h.error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(&sessions)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write(body)
+}
+
+// deleteSession завершает сессию на другом устройстве или текущую — тогда удаляются и куки
+func (h *handler) deleteSession(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+	id := chi.URLParam(r, "sessionID")
+
+	err := h.gm.RevokeSession(u.ID, id)
+	if err != nil {
+		// 404 — нет такой сессии у пользователя
+		if errors.Is(err, gophermart.ErrSessionNotFound) {
+			h.error(w, r, err, http.StatusNotFound)
+			return
+		}
+
+		// 500 — внутренняя ошибка сервера
+		h.error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	if id == currentSession(r).ID {
+		h.clearSessionCookie(w)
+	}
+	h.log(r, LogLvlInfo, fmt.Sprintf("session %s of user #%d revoked", id, u.ID))
+}
+
+// deleteSessions выход на всех устройствах, включая текущее
+func (h *handler) deleteSessions(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	if err := h.gm.RevokeAllSessions(u.ID); err != nil {
+		// 500 — внутренняя ошибка сервера
+		h.error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.clearSessionCookie(w)
+	h.log(r, LogLvlInfo, fmt.Sprintf("all sessions of user #%d revoked", u.ID))
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// postToken выдаёт токен доступа и refresh-токен по логину и паролю
+func (h *handler) postToken(w http.ResponseWriter, r *http.Request) {
+	creds := &gophermart.Credentials{}
+	if err := json.NewDecoder(r.Body).Decode(creds); err != nil {
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+
+	pair, err := h.gm.IssueTokens(creds, sessionClient(r))
+	if err != nil {
+		if h.loginThrottled(w, r, err) {
+			return
+		}
+		if errors.Is(err, gophermart.ErrInvalidPair) || errors.Is(err, gophermart.ErrUserNotFound) {
+			h.error(w, r, gophermart.ErrInvalidPair, http.StatusUnauthorized)
+			return
+		}
+		h.tokenError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, r, pair)
+	h.log(r, LogLvlDebug, fmt.Sprintf("tokens for user `%s` successfully issued", creds.Login))
+}
+
+// postTokenRefresh меняет refresh-токен на новую пару токенов; использованный токен больше не действует
+func (h *handler) postTokenRefresh(w http.ResponseWriter, r *http.Request) {
+	req := &refreshTokenRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+
+	pair, err := h.gm.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		h.tokenError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, r, pair)
+}
+
+// postTokenRevoke отзывает семейство refresh-токенов, к которому относится переданный токен
+func (h *handler) postTokenRevoke(w http.ResponseWriter, r *http.Request) {
+	req := &refreshTokenRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.gm.RevokeTokens(req.RefreshToken); err != nil {
+		h.tokenError(w, r, err)
+		return
+	}
+
+	h.log(r, LogLvlDebug, "refresh token family revoked")
+}
+
+func (h *handler) tokenError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	// 404 — режим JWT выключен
+	case errors.Is(err, gophermart.ErrJWTDisabled):
+		h.error(w, r, err, http.StatusNotFound)
+	// 401 — refresh-токен неизвестен, уже использован или истёк
+	case errors.Is(err, gophermart.ErrInvalidRefreshToken), errors.Is(err, gophermart.ErrSessionExpired):
+		h.error(w, r, err, http.StatusUnauthorized)
+	// 500 — внутренняя ошибка сервера
+	default:
+		h.error(w, r, err, http.StatusInternalServerError)
+	}
+}
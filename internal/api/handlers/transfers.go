@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (h *handler) postTransfer(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	ct := r.Header.Get("Content-Type")
+	if ct != ContentTypeApplicationJSON {
+		err = fmt.Errorf("wrong content type, %s needed", ContentTypeApplicationJSON)
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	u := currentUser(r)
+
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to read request body - %w", err), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	tpr := &gophermart.TransferProxy{}
+	err = json.Unmarshal(reqBody, &tpr)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+
+	tpr.UserID = u.ID
+	err = h.gm.PostTransfer(tpr)
+	if err != nil {
+		switch {
+		// 402 — на счету недостаточно средств
+		case errors.Is(err, gophermart.ErrNotEnoughFunds):
+			h.error(w, r, err, http.StatusPaymentRequired)
+		// 403 — превышен суточный лимит переводов
+		case errors.Is(err, gophermart.ErrTransferLimitExceeded):
+			h.error(w, r, err, http.StatusForbidden)
+		// 404 — получатель не найден
+		case errors.Is(err, gophermart.ErrUserNotFound):
+			h.error(w, r, err, http.StatusNotFound)
+		// 422 — перевод самому себе или нулевая сумма
+		case errors.Is(err, gophermart.ErrTransferToSelf), errors.Is(err, gophermart.ErrInvalidAmount):
+			h.error(w, r, err, http.StatusUnprocessableEntity)
+		// 500 — внутренняя ошибка сервера
+		default:
+			h.error(w, r, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// 200 — успешная обработка запроса
+	w.WriteHeader(http.StatusOK)
+	msg := fmt.Sprintf("%s points have been transferred from `%s` to `%s`", tpr.Sum, u.Login, tpr.Login)
+	h.log(r, LogLvlInfo, msg)
+}
+
+func (h *handler) getTransfers(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	tsPr, err := h.gm.GetTransfers(u.ID)
+	if err != nil {
+		// 204 — нет ни одного перевода
+		if errors.Is(err, gophermart.ErrNoContent) {
+			h.error(w, r, gophermart.ErrNoContent, http.StatusNoContent)
+			return
+		}
+
+		// 500 — внутренняя ошибка сервера
+		h.error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(&tsPr)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write(body)
+}
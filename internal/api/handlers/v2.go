@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// API v1 (`/api/user`) зафиксировано спецификацией и не меняется. API v2 (`/api/v2/user`) отличается форматом:
+// суммы передаются строками с ровно двумя знаками после точки, списки — страницами с курсором,
+// ошибки — объектом с машиночитаемым кодом. Сценарии обработки общие: обработчики обеих версий
+// вызывают одни и те же методы GopherMart.
+
+const (
+	apiV1 = 1
+	apiV2 = 2
+
+	defaultPageSizeV2 = 100
+)
+
+type apiVersionKey struct{}
+
+// withAPIVersion миделвара, запоминающая версию API в контексте запроса: от неё зависит формат ошибок
+func withAPIVersion(version int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), apiVersionKey{}, version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func apiVersion(r *http.Request) int {
+	if v, ok := r.Context().Value(apiVersionKey{}).(int); ok {
+		return v
+	}
+
+	return apiV1
+}
+
+// money точная сумма API v2: строка `"751.50"`, числа не принимаются, чтобы клиент не терял копейки на float
+type money gophermart.Amount
+
+func (m money) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"%d.%02d"`, uint64(m)/100, uint64(m)%100)), nil
+}
+
+func (m *money) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("%w: sum must be a string", gophermart.ErrInvalidAmount)
+	}
+
+	amount, err := gophermart.ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*m = money(amount)
+
+	return nil
+}
+
+// page страница списка API v2; пустой список — тоже ответ 200, а не 204
+type page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+type errorV2 struct {
+	Error errorBodyV2 `json:"error"`
+}
+
+type errorBodyV2 struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// errorCodesV2 коды ошибок сценариев; остальные ошибки получают код по статусу ответа
+var errorCodesV2 = []struct {
+	err  error
+	code string
+}{
+	{gophermart.ErrLoginAlreadyTaken, "login_already_taken"},
+	{gophermart.ErrInvalidLogin, "invalid_login"},
+	{gophermart.ErrInvalidPassword, "invalid_password"},
+	{gophermart.ErrInvalidPair, "invalid_credentials"},
+	{gophermart.ErrTooManyLoginAttempts, "too_many_login_attempts"},
+	{gophermart.ErrUserNotFound, "user_not_found"},
+	{gophermart.ErrOrderAlreadyLoadedByAnotherUser, "order_uploaded_by_another_user"},
+	{gophermart.ErrOrderInvalidFormat, "invalid_order_number"},
+	{gophermart.ErrInvalidOrdersFilter, "invalid_filter"},
+	{gophermart.ErrInvalidCursor, "invalid_cursor"},
+	{gophermart.ErrInvalidPeriod, "invalid_period"},
+	{gophermart.ErrInvalidAmount, "invalid_amount"},
+	{gophermart.ErrNotEnoughFunds, "not_enough_funds"},
+	{gophermart.ErrWithdrawAlreadyRecorded, "withdrawal_already_recorded"},
+	{gophermart.ErrHoldAlreadyExists, "hold_already_exists"},
+	{ErrCSRFCheckFailed, "csrf_check_failed"},
+}
+
+func errorCodeV2(err error, statusCode int) string {
+	for _, ec := range errorCodesV2 {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+
+	// Unauthorized -> unauthorized, Bad Request -> bad_request
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(statusCode)), " ", "_")
+}
+
+func (h *handler) errorV2(w http.ResponseWriter, r *http.Request, err error, statusCode int) {
+	e := errorV2{Error: errorBodyV2{
+		Code:      errorCodeV2(err, statusCode),
+		Message:   err.Error(),
+		RequestID: middleware.GetReqID(r.Context()),
+	}}
+
+	body, errMarshal := json.Marshal(e)
+	if errMarshal != nil {
+		body = []byte(`{"error":{"code":"internal_server_error","message":"failed to marshal error"}}`)
+	}
+
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.WriteHeader(statusCode)
+	w.Write(body)
+	h.log(r, LogLvlError, fmt.Sprintf("%s: %s", e.Error.Code, e.Error.Message))
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+	w.Write(body)
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+type orderV2 struct {
+	Number     string `json:"number"`
+	Status     string `json:"status"`
+	Accrual    *money `json:"accrual,omitempty"` // только для обработанных заказов, в том числе нулевое
+	UploadedAt string `json:"uploaded_at"`
+}
+
+func newOrderV2(o *gophermart.OrderProxy) *orderV2 {
+	ov := &orderV2{
+		Number:     o.Number,
+		Status:     o.Status,
+		UploadedAt: o.UploadedAt,
+	}
+	if o.Status == gophermart.StatusProcessed {
+		accrual := money(o.Accrual)
+		ov.Accrual = &accrual
+	}
+
+	return ov
+}
+
+type balanceV2 struct {
+	Current   money `json:"current"`
+	Withdrawn money `json:"withdrawn"`
+}
+
+type withdrawRequestV2 struct {
+	Order string `json:"order"`
+	Sum   money  `json:"sum"`
+}
+
+type withdrawalV2 struct {
+	Order       string `json:"order"`
+	Sum         money  `json:"sum"`
+	ProcessedAt string `json:"processed_at"`
+}
+
+type statementEntryV2 struct {
+	Type        string `json:"type"`
+	Reference   string `json:"reference"`
+	Direction   string `json:"direction"`
+	Sum         money  `json:"sum"`
+	Balance     money  `json:"balance"`
+	ProcessedAt string `json:"processed_at"`
+}
+
+// getOrdersV2 страница заказов: курсор следующей страницы в теле ответа, размер страницы по умолчанию ограничен
+func (h *handler) getOrdersV2(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	filter, err := ordersFilter(r)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSizeV2
+	}
+
+	proxyOrders, nextCursor, err := h.gm.GetOrders(u.ID, filter)
+	if err != nil {
+		// 400 — неверные параметры фильтрации
+		if errors.Is(err, gophermart.ErrInvalidOrdersFilter) || errors.Is(err, gophermart.ErrInvalidPeriod) {
+			h.error(w, r, err, http.StatusBadRequest)
+			return
+		}
+		h.error(w, r, fmt.Errorf("failed to get all orders - %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]*orderV2, 0, len(proxyOrders))
+	for _, o := range proxyOrders {
+		items = append(items, newOrderV2(o))
+	}
+
+	h.writeJSON(w, r, &page{Items: items, NextCursor: nextCursor})
+}
+
+func (h *handler) getBalanceV2(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	bpr, err := h.gm.GetBalance(u.ID)
+	if err != nil {
+		h.error(w, r, fmt.Errorf("failed to get balance - %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, r, &balanceV2{Current: money(bpr.Current), Withdrawn: money(bpr.Withdrawn)})
+}
+
+func (h *handler) postWithdrawV2(w http.ResponseWriter, r *http.Request) {
+	if ct := r.Header.Get("Content-Type"); ct != ContentTypeApplicationJSON {
+		h.error(w, r, fmt.Errorf("wrong content type, %s needed", ContentTypeApplicationJSON), http.StatusBadRequest)
+		return
+	}
+
+	u := currentUser(r)
+
+	req := &withdrawRequestV2{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		// 422 — сумма передана числом или с точностью больше копейки
+		if errors.Is(err, gophermart.ErrInvalidAmount) {
+			h.error(w, r, err, http.StatusUnprocessableEntity)
+			return
+		}
+		h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+		return
+	}
+
+	err := h.gm.PostWithdraw(&gophermart.WithdrawProxy{
+		Order:  req.Order,
+		Sum:    gophermart.Amount(req.Sum),
+		UserID: u.ID,
+	})
+	if err != nil {
+		switch {
+		// 402 — на счету недостаточно средств
+		case errors.Is(err, gophermart.ErrNotEnoughFunds):
+			h.error(w, r, err, http.StatusPaymentRequired)
+		// 409 — списание по заказу уже было или по заказу есть активный резерв
+		case errors.Is(err, gophermart.ErrWithdrawAlreadyRecorded), errors.Is(err, gophermart.ErrHoldAlreadyExists):
+			h.error(w, r, err, http.StatusConflict)
+		// 422 — неверный номер заказа или сумма
+		case errors.Is(err, gophermart.ErrOrderInvalidFormat), errors.Is(err, gophermart.ErrInvalidAmount):
+			h.error(w, r, err, http.StatusUnprocessableEntity)
+		// 500 — внутренняя ошибка сервера
+		default:
+			h.error(w, r, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.log(r, LogLvlInfo, fmt.Sprintf("new withdraw has been made for order ID %s", req.Order))
+}
+
+// offsetPage размер страницы и смещение из параметров limit и cursor; курсор — смещение следующей страницы
+func offsetPage(r *http.Request) (limit, offset uint64, err error) {
+	if limit, err = queryUint(r, "limit"); err != nil {
+		return 0, 0, err
+	}
+	if limit == 0 {
+		limit = defaultPageSizeV2
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		if offset, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return 0, 0, gophermart.ErrInvalidCursor
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// getWithdrawalsV2 списания страницами, курсор устроен так же, как у выписки
+func (h *handler) getWithdrawalsV2(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	limit, offset, err := offsetPage(r)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	wsPr, err := h.gm.GetWithdrawals(u.ID)
+	if err != nil && !errors.Is(err, gophermart.ErrNoContent) {
+		h.error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	var next string
+	if offset >= uint64(len(wsPr)) {
+		wsPr = nil
+	} else {
+		wsPr = wsPr[offset:]
+		if uint64(len(wsPr)) > limit {
+			wsPr = wsPr[:limit]
+			next = strconv.FormatUint(offset+limit, 10)
+		}
+	}
+
+	items := make([]*withdrawalV2, 0, len(wsPr))
+	for _, wpr := range wsPr {
+		items = append(items, &withdrawalV2{Order: wpr.Order, Sum: money(wpr.Sum), ProcessedAt: wpr.ProcessedAt})
+	}
+
+	h.writeJSON(w, r, &page{Items: items, NextCursor: next})
+}
+
+// getStatementV2 выписка страницами: курсор — смещение следующей страницы, для клиента непрозрачное
+func (h *handler) getStatementV2(w http.ResponseWriter, r *http.Request) {
+	var err error
+	u := currentUser(r)
+
+	filter := &gophermart.StatementFilter{}
+	if filter.From, filter.To, err = queryPeriod(r); err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+	var limit uint64
+	if limit, filter.Offset, err = offsetPage(r); err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+	// запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	filter.Limit = limit + 1
+
+	sePr, err := h.gm.GetStatement(u.ID, filter)
+	if err != nil && !errors.Is(err, gophermart.ErrNoContent) {
+		// 400 — конец периода раньше начала
+		if errors.Is(err, gophermart.ErrInvalidPeriod) {
+			h.error(w, r, err, http.StatusBadRequest)
+			return
+		}
+		h.error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	var next string
+	if uint64(len(sePr)) > limit {
+		sePr = sePr[:limit]
+		next = strconv.FormatUint(filter.Offset+limit, 10)
+	}
+
+	items := make([]*statementEntryV2, 0, len(sePr))
+	for _, e := range sePr {
+		items = append(items, &statementEntryV2{
+			Type:        e.Type,
+			Reference:   e.Reference,
+			Direction:   e.Direction,
+			Sum:         money(e.Sum),
+			Balance:     money(e.Balance),
+			ProcessedAt: e.ProcessedAt,
+		})
+	}
+
+	h.writeJSON(w, r, &page{Items: items, NextCursor: next})
+}
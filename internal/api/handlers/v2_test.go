@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    money
+		wantErr bool
+	}{
+		{name: "string", json: `"751.5"`, want: 751_50},
+		{name: "integer string", json: `"751"`, want: 751_00},
+		{name: "number rejected", json: `751.5`, wantErr: true},
+		{name: "fraction of kopeck", json: `"0.001"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m money
+			err := json.Unmarshal([]byte(tt.json), &m)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, gophermart.ErrInvalidAmount)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, m)
+		})
+	}
+
+	b, err := json.Marshal(money(751_50))
+	assert.NoError(t, err)
+	assert.Equal(t, `"751.50"`, string(b))
+}
+
+func TestErrorCodeV2(t *testing.T) {
+	wrapped := fmt.Errorf("failed to withdraw - %w", gophermart.ErrNotEnoughFunds)
+	assert.Equal(t, "not_enough_funds", errorCodeV2(wrapped, http.StatusPaymentRequired))
+	assert.Equal(t, "unauthorized", errorCodeV2(fmt.Errorf("session has expired"), http.StatusUnauthorized))
+	assert.Equal(t, "internal_server_error", errorCodeV2(fmt.Errorf("db is down"), http.StatusInternalServerError))
+}
+
+func TestGetWithdrawalsV2Pages(t *testing.T) {
+	st := basicstorage.New()
+	srv := httptest.NewServer(New(gophermart.New(st)).GetRouter())
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	c := &http.Client{Jar: jar}
+	resp, err := c.Post(srv.URL+"/api/v2/user/register", ContentTypeApplicationJSON,
+		strings.NewReader(`{"login":"gopher","password":"Passw0rd33"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	u, err := st.GetUser("gopher")
+	require.NoError(t, err)
+	require.NoError(t, st.UpdateOrder(&gophermart.Order{
+		ID: 12345678903, UserID: u.ID, Status: gophermart.StatusProcessed, Accrual: 1000_00,
+	}))
+	for _, order := range []string{"2377225624", "79927398713", "4561261212345467"} {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v2/user/balance/withdraw",
+			strings.NewReader(fmt.Sprintf(`{"order":%q,"sum":"100"}`, order)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeApplicationJSON)
+		req.Header.Set("Origin", srv.URL)
+		resp, err = c.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	var orders []string
+	target := "/api/v2/user/balance/withdrawals?limit=2"
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3, "too many pages")
+		resp, err = c.Get(srv.URL + target)
+		require.NoError(t, err)
+		var p struct {
+			Items      []withdrawalV2 `json:"items"`
+			NextCursor string         `json:"next_cursor"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+		resp.Body.Close()
+		assert.LessOrEqual(t, len(p.Items), 2)
+		for _, w := range p.Items {
+			orders = append(orders, w.Order)
+		}
+		if p.NextCursor == "" {
+			break
+		}
+		target = "/api/v2/user/balance/withdrawals?limit=2&cursor=" + p.NextCursor
+	}
+	assert.ElementsMatch(t, []string{"2377225624", "79927398713", "4561261212345467"}, orders)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// ownerFunc определяет владельца подписок: пользователя сессии или, для администратора, глобальные подписки (0);
+// доступ к маршрутам уже проверен миделварой
+type ownerFunc func(r *http.Request) uint64
+
+func userOwner(r *http.Request) uint64 {
+	return currentUser(r).ID
+}
+
+func adminOwner(_ *http.Request) uint64 {
+	return 0
+}
+
+func (h *handler) postWebhook(owner ownerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+
+		ct := r.Header.Get("Content-Type")
+		if ct != ContentTypeApplicationJSON {
+			err = fmt.Errorf("wrong content type, %s needed", ContentTypeApplicationJSON)
+			h.error(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		userID := owner(r)
+
+		reqBody, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.error(w, r, fmt.Errorf("failed to read request body - %w", err), http.StatusInternalServerError)
+			return
+		}
+		defer r.Body.Close()
+
+		whpr := &gophermart.WebhookProxy{}
+		err = json.Unmarshal(reqBody, &whpr)
+		if err != nil {
+			h.error(w, r, fmt.Errorf("failed to unmarshal body - %w", err), http.StatusBadRequest)
+			return
+		}
+
+		whpr, err = h.gm.PostWebhook(whpr, userID)
+		if err != nil {
+			// 422 — неверный адрес или неизвестное событие
+			if errors.Is(err, gophermart.ErrInvalidWebhookURL) || errors.Is(err, gophermart.ErrInvalidWebhookEvent) {
+				h.error(w, r, err, http.StatusUnprocessableEntity)
+				return
+			}
+
+			// 500 — внутренняя ошибка сервера
+			h.error(w, r, err, http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(whpr)
+		if err != nil {
+			h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+		h.log(r, LogLvlInfo, fmt.Sprintf("webhook #%d to %s has been created", whpr.ID, whpr.URL))
+	}
+}
+
+func (h *handler) getWebhooks(owner ownerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := owner(r)
+
+		whsPr, err := h.gm.GetWebhooks(userID)
+		if err != nil {
+			// 204 — нет ни одной подписки
+			if errors.Is(err, gophermart.ErrNoContent) {
+				h.error(w, r, err, http.StatusNoContent)
+				return
+			}
+
+			// 500 — внутренняя ошибка сервера
+			h.error(w, r, err, http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(&whsPr)
+		if err != nil {
+			h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+		w.Write(body)
+	}
+}
+
+func (h *handler) deleteWebhook(owner ownerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := owner(r)
+
+		webhookID, err := strconv.ParseUint(chi.URLParam(r, "webhookID"), 10, 64)
+		if err != nil {
+			h.error(w, r, gophermart.ErrWebhookNotFound, http.StatusNotFound)
+			return
+		}
+
+		err = h.gm.DeleteWebhook(webhookID, userID)
+		if err != nil {
+			// 404 — подписка не найдена
+			if errors.Is(err, gophermart.ErrWebhookNotFound) {
+				h.error(w, r, err, http.StatusNotFound)
+				return
+			}
+
+			// 500 — внутренняя ошибка сервера
+			h.error(w, r, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		h.log(r, LogLvlInfo, fmt.Sprintf("webhook #%d has been deleted", webhookID))
+	}
+}
+
+func (h *handler) getWebhookDeliveries(owner ownerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := owner(r)
+
+		webhookID, err := strconv.ParseUint(chi.URLParam(r, "webhookID"), 10, 64)
+		if err != nil {
+			h.error(w, r, gophermart.ErrWebhookNotFound, http.StatusNotFound)
+			return
+		}
+
+		attemptsPr, err := h.gm.GetWebhookAttempts(webhookID, userID)
+		if err != nil {
+			switch {
+			// 204 — попыток доставки ещё не было
+			case errors.Is(err, gophermart.ErrNoContent):
+				h.error(w, r, err, http.StatusNoContent)
+			// 404 — подписка не найдена
+			case errors.Is(err, gophermart.ErrWebhookNotFound):
+				h.error(w, r, err, http.StatusNotFound)
+			// 500 — внутренняя ошибка сервера
+			default:
+				h.error(w, r, err, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		body, err := json.Marshal(&attemptsPr)
+		if err != nil {
+			h.error(w, r, fmt.Errorf("failed to marshal JSON - %w", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentTypeApplicationJSON)
+		w.Write(body)
+	}
+}
@@ -19,16 +19,7 @@ func (h *handler) postWithdraw(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c, err := h.authCheck(w, r)
-	if err != nil {
-		// 401 — пользователь не авторизован
-		return
-	}
-	u, err := h.gm.Users.Get(c.UserID)
-	if err != nil {
-		h.error(w, r, fmt.Errorf("failed to get user by ID - %w", err), http.StatusInternalServerError)
-		return
-	}
+	u := currentUser(r)
 
 	reqBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -53,6 +44,12 @@ func (h *handler) postWithdraw(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// 409 — по заказу есть активный резерв
+		if errors.Is(err, gophermart.ErrHoldAlreadyExists) {
+			h.error(w, r, err, http.StatusConflict)
+			return
+		}
+
 		// 422 — неверный формат номера заказа
 		if errors.Is(err, gophermart.ErrOrderInvalidFormat) {
 			h.error(w, r, gophermart.ErrOrderInvalidFormat, http.StatusUnprocessableEntity)
@@ -80,16 +77,7 @@ func (h *handler) getWithdrawals(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c, err := h.authCheck(w, r)
-	if err != nil {
-		// 401 — пользователь не авторизован
-		return
-	}
-	u, err := h.gm.Users.Get(c.UserID)
-	if err != nil {
-		h.error(w, r, fmt.Errorf("failed to get user by ID - %w", err), http.StatusInternalServerError)
-		return
-	}
+	u := currentUser(r)
 
 	wsPr, err := h.gm.GetWithdrawals(u.ID)
 	if err != nil {
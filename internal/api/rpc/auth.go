@@ -0,0 +1,93 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/sergeysynergy/hardtest/internal/api/rpc/pb"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+const metadataAuthorization = "authorization"
+
+// методы, доступные без сессии
+var publicMethods = map[string]bool{
+	pb.GopherMart_Register_FullMethodName: true,
+	pb.GopherMart_Login_FullMethodName:    true,
+}
+
+type sessionKey struct{}
+
+// tokenFromContext извлекает токен сессии или токен доступа из метаданных `authorization: Bearer <token>`
+func tokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(metadataAuthorization)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return strings.TrimPrefix(values[0], "Bearer ")
+}
+
+// clientFromContext адрес и клиент вызова для списка сессий
+func clientFromContext(ctx context.Context) *gophermart.SessionClient {
+	client := &gophermart.SessionClient{}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(client.IP); err == nil {
+			client.IP = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			client.UserAgent = values[0]
+		}
+	}
+
+	return client
+}
+
+// authInterceptor аналог authenticate HTTP-обработчиков: проверяет и продлевает сессию и кладёт её в контекст вызова
+func (s *Server) authInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	token := tokenFromContext(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, gophermart.ErrUnauthorizedAccess.Error())
+	}
+
+	// токен сессии или, в режиме JWT, токен доступа
+	session, _, err := s.gm.Authenticate(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return handler(context.WithValue(ctx, sessionKey{}, session), req)
+}
+
+// userID возвращает пользователя сессии, проверенной authInterceptor
+func userID(ctx context.Context) uint64 {
+	session, _ := ctx.Value(sessionKey{}).(*gophermart.Session)
+	if session == nil {
+		return 0
+	}
+
+	return session.UserID
+}
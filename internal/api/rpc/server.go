@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/sergeysynergy/hardtest/internal/api/rpc/pb"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+type Server struct {
+	pb.UnimplementedGopherMartServer
+
+	gm           *gophermart.GopherMart
+	addr         string
+	server       *grpc.Server
+	graceTimeout time.Duration // время на штатное завершения работы сервера
+}
+
+type Option func(server *Server)
+
+func New(gm *gophermart.GopherMart, opts ...Option) *Server {
+	const (
+		defaultAddress      = ":3200"
+		defaultGraceTimeout = 20 * time.Second
+	)
+	s := &Server{
+		gm:           gm,
+		addr:         defaultAddress,
+		graceTimeout: defaultGraceTimeout,
+	}
+	// Применяем в цикле каждую опцию
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.server = grpc.NewServer(grpc.UnaryInterceptor(s.authInterceptor))
+	pb.RegisterGopherMartServer(s.server, s)
+
+	return s
+}
+
+func WithAddress(addr string) Option {
+	return func(s *Server) {
+		if addr != "" {
+			s.addr = addr
+		}
+	}
+}
+
+func (s *Server) Serve() {
+	go func() {
+		// штатное завершение по сигналам: syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		<-sig
+
+		// GracefulStop дожидается завершения активных вызовов, поэтому ограничим его по времени
+		timer := time.AfterFunc(s.graceTimeout, func() {
+			log.Println("[ERROR] gRPC graceful shutdown timed out! Forcing stop.")
+			s.server.Stop()
+		})
+		defer timer.Stop()
+		s.server.GracefulStop()
+	}()
+
+	listen, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		log.Fatal("[ERROR] Failed to listen gRPC address - ", err)
+	}
+
+	// запустим сервер
+	log.Printf("[INFO] starting gRPC-server at %s\n", s.addr)
+	if err = s.server.Serve(listen); err != nil {
+		log.Fatal("[ERROR] Failed to run gRPC-server - ", err)
+	}
+}
@@ -0,0 +1,196 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sergeysynergy/hardtest/internal/api/rpc/pb"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// statusError переводит ошибки бизнес-логики в коды gRPC по аналогии с HTTP-статусами обработчиков
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, gophermart.ErrLoginAlreadyTaken),
+		errors.Is(err, gophermart.ErrOrderAlreadyLoadedByAnotherUser),
+		errors.Is(err, gophermart.ErrWithdrawAlreadyRecorded),
+		errors.Is(err, gophermart.ErrHoldAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, gophermart.ErrInvalidPair), errors.Is(err, gophermart.ErrUserNotFound):
+		return status.Error(codes.Unauthenticated, gophermart.ErrInvalidPair.Error())
+	case errors.Is(err, gophermart.ErrInvalidLogin),
+		errors.Is(err, gophermart.ErrInvalidPassword),
+		errors.Is(err, gophermart.ErrOrderInvalidFormat),
+		errors.Is(err, gophermart.ErrInvalidOrdersFilter),
+		errors.Is(err, gophermart.ErrInvalidCursor),
+		errors.Is(err, gophermart.ErrInvalidPeriod),
+		errors.Is(err, gophermart.ErrInvalidAmount):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, gophermart.ErrTooManyLoginAttempts):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, gophermart.ErrNotEnoughFunds):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	log.Println("[ERROR] gRPC call failed -", err)
+	return status.Error(codes.Internal, "internal server error")
+}
+
+func newSession(session *gophermart.Session) *pb.Session {
+	return &pb.Session{
+		Token:  session.Token,
+		Expiry: session.Expiry.Format(time.RFC3339),
+	}
+}
+
+func (s *Server) Register(ctx context.Context, req *pb.Credentials) (*pb.Session, error) {
+	session, err := s.gm.Register(&gophermart.Credentials{Login: req.Login, Password: req.Password}, clientFromContext(ctx))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return newSession(session), nil
+}
+
+func (s *Server) Login(ctx context.Context, req *pb.Credentials) (*pb.Session, error) {
+	// переданный токен, как и кука HTTP API, обнуляет прежнюю сессию
+	session, err := s.gm.Login(
+		&gophermart.Credentials{Login: req.Login, Password: req.Password}, tokenFromContext(ctx), clientFromContext(ctx),
+	)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return newSession(session), nil
+}
+
+func (s *Server) PostOrder(ctx context.Context, req *pb.PostOrderRequest) (*pb.PostOrderResponse, error) {
+	orderID, err := strconv.ParseUint(req.Number, 10, 64)
+	if err != nil {
+		return nil, statusError(gophermart.ErrOrderInvalidFormat)
+	}
+
+	err = s.gm.PostOrders(orderID, userID(ctx))
+	if err != nil {
+		if errors.Is(err, gophermart.ErrOrderAlreadyLoadedByUser) {
+			return &pb.PostOrderResponse{AlreadyUploaded: true}, nil
+		}
+		return nil, statusError(err)
+	}
+
+	return &pb.PostOrderResponse{}, nil
+}
+
+// ordersFilter собирает фильтр списка заказов так же, как одноимённая функция HTTP-обработчиков
+func ordersFilter(req *pb.GetOrdersRequest) (*gophermart.OrdersFilter, error) {
+	var err error
+	filter := &gophermart.OrdersFilter{
+		Sort:  strings.ToLower(req.Sort),
+		Limit: req.Limit,
+	}
+
+	for _, st := range req.Statuses {
+		filter.Statuses = append(filter.Statuses, strings.ToUpper(strings.TrimSpace(st)))
+	}
+	if req.From != "" {
+		if filter.From, err = time.Parse(time.RFC3339, req.From); err != nil {
+			return nil, fmt.Errorf("invalid `from` field: RFC3339 needed")
+		}
+	}
+	if req.To != "" {
+		if filter.To, err = time.Parse(time.RFC3339, req.To); err != nil {
+			return nil, fmt.Errorf("invalid `to` field: RFC3339 needed")
+		}
+	}
+	if req.Cursor != "" {
+		if filter.Cursor, err = gophermart.DecodeOrdersCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	return filter, nil
+}
+
+func (s *Server) GetOrders(ctx context.Context, req *pb.GetOrdersRequest) (*pb.GetOrdersResponse, error) {
+	filter, err := ordersFilter(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	orsPr, nextCursor, err := s.gm.GetOrders(userID(ctx), filter)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &pb.GetOrdersResponse{
+		Orders:     make([]*pb.Order, 0, len(orsPr)),
+		NextCursor: nextCursor,
+	}
+	for _, opr := range orsPr {
+		resp.Orders = append(resp.Orders, &pb.Order{
+			Number:     opr.Number,
+			Status:     opr.Status,
+			Accrual:    uint64(opr.Accrual),
+			UploadedAt: opr.UploadedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, _ *pb.GetBalanceRequest) (*pb.Balance, error) {
+	blPr, err := s.gm.GetBalance(userID(ctx))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &pb.Balance{
+		Current:   uint64(blPr.Current),
+		Withdrawn: uint64(blPr.Withdrawn),
+	}, nil
+}
+
+func (s *Server) Withdraw(ctx context.Context, req *pb.WithdrawRequest) (*pb.WithdrawResponse, error) {
+	err := s.gm.PostWithdraw(&gophermart.WithdrawProxy{
+		Order:  req.Order,
+		Sum:    gophermart.Amount(req.Sum),
+		UserID: userID(ctx),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &pb.WithdrawResponse{}, nil
+}
+
+func (s *Server) GetWithdrawals(ctx context.Context, _ *pb.GetWithdrawalsRequest) (*pb.GetWithdrawalsResponse, error) {
+	wdsPr, err := s.gm.GetWithdrawals(userID(ctx))
+	if err != nil {
+		// отсутствие списаний в gRPC не ошибка, а пустой список
+		if errors.Is(err, gophermart.ErrNoContent) {
+			return &pb.GetWithdrawalsResponse{}, nil
+		}
+		return nil, statusError(err)
+	}
+
+	resp := &pb.GetWithdrawalsResponse{
+		Withdrawals: make([]*pb.Withdrawal, 0, len(wdsPr)),
+	}
+	for _, wpr := range wdsPr {
+		resp.Withdrawals = append(resp.Withdrawals, &pb.Withdrawal{
+			Order:       wpr.Order,
+			Sum:         uint64(wpr.Sum),
+			ProcessedAt: wpr.ProcessedAt,
+		})
+	}
+
+	return resp, nil
+}
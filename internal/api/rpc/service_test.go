@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/sergeysynergy/hardtest/internal/api/rpc/pb"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{err: gophermart.ErrLoginAlreadyTaken, want: codes.AlreadyExists},
+		{err: fmt.Errorf("wrapped - %w", gophermart.ErrOrderAlreadyLoadedByAnotherUser), want: codes.AlreadyExists},
+		{err: gophermart.ErrUserNotFound, want: codes.Unauthenticated},
+		{err: gophermart.ErrOrderInvalidFormat, want: codes.InvalidArgument},
+		{err: gophermart.ErrNotEnoughFunds, want: codes.FailedPrecondition},
+		{err: fmt.Errorf("connection refused"), want: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.want, status.Code(statusError(tt.err)))
+		})
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	s := &Server{}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	// публичные методы вызываются без токена
+	info := &grpc.UnaryServerInfo{FullMethod: pb.GopherMart_Login_FullMethodName}
+	resp, err := s.authInterceptor(context.Background(), nil, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	// остальные требуют токен сессии
+	info = &grpc.UnaryServerInfo{FullMethod: pb.GopherMart_GetBalance_FullMethodName}
+	_, err = s.authInterceptor(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "))
+	_, err = s.authInterceptor(ctx, nil, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
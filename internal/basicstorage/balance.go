@@ -0,0 +1,61 @@
+package basicstorage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (s *Storage) GetBalance(userID uint64) (*gophermart.Balance, error) {
+	s.balancesMu.RLock()
+	defer s.balancesMu.RUnlock()
+
+	b, ok := s.balancesByUserID[userID]
+	if !ok {
+		return nil, fmt.Errorf("user balance not found")
+	}
+
+	// отдаём копию, чтобы кэш баланса не менялся вместе с хранилищем
+	balance := *b
+	return &balance, nil
+}
+
+func (s *Storage) AddWithdraw(withdraw *gophermart.Withdraw) error {
+	s.balancesMu.Lock()
+	defer s.balancesMu.Unlock()
+
+	b, ok := s.balancesByUserID[withdraw.UserID]
+	if !ok {
+		return fmt.Errorf("user balance not found")
+	}
+	if b.Current < withdraw.Sum {
+		return gophermart.ErrNotEnoughFunds
+	}
+	for _, w := range s.withdrawals {
+		if w.OrderID == withdraw.OrderID {
+			return gophermart.ErrWithdrawAlreadyRecorded
+		}
+	}
+
+	b.Current -= withdraw.Sum
+	b.Withdrawn += withdraw.Sum
+	withdraw.ProcessedAt = time.Now()
+	s.withdrawals = append(s.withdrawals, withdraw)
+
+	return nil
+}
+
+func (s *Storage) GetUserWithdrawals(userID uint64) ([]*gophermart.Withdraw, error) {
+	s.balancesMu.RLock()
+	defer s.balancesMu.RUnlock()
+
+	ws := make([]*gophermart.Withdraw, 0)
+	for _, w := range s.withdrawals {
+		if w.UserID == userID {
+			ws = append(ws, w)
+		}
+	}
+
+	return ws, nil
+}
@@ -6,25 +6,41 @@ import (
 	"github.com/sergeysynergy/hardtest/internal/gophermart"
 )
 
+// Storage хранилище в памяти для тестов и локального запуска без Postgres. Сценарии, которые проверяют
+// тесты обработчиков по спецификации OpenAPI, — заказы, баланс и списания — работают как в базе,
+// остальные методы gophermart.Storer не поддерживаются, см. unsupported.go
 type Storage struct {
 	counter uint64
 
 	usersByLoginMu sync.RWMutex
 	usersByLogin   map[string]*gophermart.User
 	usersByID      map[uint64]*gophermart.User
+	// токены сброса пароля защищены мьютексом пользователей
+	passwordResetsByTokenHash map[string]*gophermart.PasswordReset
 
-	sessionsBySessionTokenMu sync.RWMutex
-	sessionsBySessionToken   map[string]*gophermart.Session
+	sessionsByTokenHashMu sync.RWMutex
+	sessionsByTokenHash   map[string]*gophermart.Session
+
+	loginFailuresMu    sync.Mutex
+	loginFailuresByKey map[string]*gophermart.LoginFailures
 
 	ordersByIDMu sync.RWMutex
 	ordersByID   map[uint64]*gophermart.Order
+
+	// баланс и списания меняются вместе, поэтому защищены одним мьютексом
+	balancesMu       sync.RWMutex
+	balancesByUserID map[uint64]*gophermart.Balance
+	withdrawals      []*gophermart.Withdraw
 }
 
 func New() *Storage {
 	return &Storage{
-		usersByLogin:           make(map[string]*gophermart.User),
-		usersByID:              make(map[uint64]*gophermart.User),
-		sessionsBySessionToken: make(map[string]*gophermart.Session),
-		ordersByID:             make(map[uint64]*gophermart.Order),
+		usersByLogin:              make(map[string]*gophermart.User),
+		usersByID:                 make(map[uint64]*gophermart.User),
+		passwordResetsByTokenHash: make(map[string]*gophermart.PasswordReset),
+		sessionsByTokenHash:       make(map[string]*gophermart.Session),
+		loginFailuresByKey:        make(map[string]*gophermart.LoginFailures),
+		ordersByID:                make(map[uint64]*gophermart.Order),
+		balancesByUserID:          make(map[uint64]*gophermart.Balance),
 	}
 }
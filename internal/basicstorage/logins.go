@@ -0,0 +1,72 @@
+package basicstorage
+
+import (
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// Счётчики неудачных попыток входа в памяти действуют только в пределах одного процесса
+
+func (s *Storage) GetLoginFailures(key string) (*gophermart.LoginFailures, error) {
+	s.loginFailuresMu.Lock()
+	defer s.loginFailuresMu.Unlock()
+
+	failures, ok := s.loginFailuresByKey[key]
+	if !ok {
+		return &gophermart.LoginFailures{Key: key}, nil
+	}
+	copied := *failures
+
+	return &copied, nil
+}
+
+func (s *Storage) AddLoginFailure(key string, now time.Time, window time.Duration) (*gophermart.LoginFailures, error) {
+	s.loginFailuresMu.Lock()
+	defer s.loginFailuresMu.Unlock()
+
+	failures, ok := s.loginFailuresByKey[key]
+	if !ok || failures.LastFailure.Before(now.Add(-window)) {
+		failures = &gophermart.LoginFailures{Key: key}
+		s.loginFailuresByKey[key] = failures
+	}
+	failures.Failures++
+	failures.LastFailure = now
+	copied := *failures
+
+	return &copied, nil
+}
+
+func (s *Storage) ReleaseLoginFailure(key string) error {
+	s.loginFailuresMu.Lock()
+	defer s.loginFailuresMu.Unlock()
+
+	if failures, ok := s.loginFailuresByKey[key]; ok && failures.Failures > 0 {
+		failures.Failures--
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteLoginFailures(key string) error {
+	s.loginFailuresMu.Lock()
+	delete(s.loginFailuresByKey, key)
+	s.loginFailuresMu.Unlock()
+
+	return nil
+}
+
+func (s *Storage) DeleteLoginFailuresBefore(t time.Time) (int64, error) {
+	s.loginFailuresMu.Lock()
+	defer s.loginFailuresMu.Unlock()
+
+	var deleted int64
+	for key, failures := range s.loginFailuresByKey {
+		if failures.LastFailure.Before(t) {
+			delete(s.loginFailuresByKey, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
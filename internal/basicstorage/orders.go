@@ -1,10 +1,13 @@
 package basicstorage
 
 import (
-	"github.com/sergeysynergy/hardtest/internal/gophermart"
-	"github.com/sergeysynergy/hardtest/pkg/loon"
+	"fmt"
 	"log"
+	"sort"
 	"strconv"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+	"github.com/sergeysynergy/hardtest/pkg/loon"
 )
 
 func (s *Storage) AddOrder(o *gophermart.Order) error {
@@ -15,14 +18,46 @@ func (s *Storage) AddOrder(o *gophermart.Order) error {
 	}
 
 	s.ordersByIDMu.Lock()
+	defer s.ordersByIDMu.Unlock()
+
+	return s.addOrder(o)
+}
+
+// addOrder вызывается под блокировкой ordersByIDMu
+func (s *Storage) addOrder(o *gophermart.Order) error {
+	if bo, ok := s.ordersByID[o.ID]; ok {
+		if bo.UserID == o.UserID {
+			return gophermart.ErrOrderAlreadyLoadedByUser
+		}
+		return gophermart.ErrOrderAlreadyLoadedByAnotherUser
+	}
 	s.ordersByID[o.ID] = o
-	s.ordersByIDMu.Unlock()
 
 	return nil
 }
 
+func (s *Storage) AddOrders(orders []*gophermart.Order) ([]error, error) {
+	s.ordersByIDMu.Lock()
+	defer s.ordersByIDMu.Unlock()
+
+	errs := make([]error, len(orders))
+	for i, o := range orders {
+		errs[i] = s.addOrder(o)
+	}
+
+	return errs, nil
+}
+
 func (s *Storage) GetOrder(id uint64) (*gophermart.Order, error) {
-	return nil, nil
+	s.ordersByIDMu.RLock()
+	defer s.ordersByIDMu.RUnlock()
+
+	o, ok := s.ordersByID[id]
+	if !ok {
+		return nil, fmt.Errorf("order not found")
+	}
+
+	return o, nil
 }
 
 func (s *Storage) GetPullOrders(_ uint32) (map[uint64]*gophermart.Order, error) {
@@ -39,6 +74,63 @@ func (s *Storage) GetPullOrders(_ uint32) (map[uint64]*gophermart.Order, error)
 	return orders, nil
 }
 
+func (s *Storage) GetUserOrders(userID uint64, filter *gophermart.OrdersFilter) ([]*gophermart.Order, error) {
+	statuses := make(map[string]bool, len(filter.Statuses))
+	for _, st := range filter.Statuses {
+		statuses[st] = true
+	}
+
+	s.ordersByIDMu.RLock()
+	orders := make([]*gophermart.Order, 0)
+	for _, o := range s.ordersByID {
+		if o.UserID != userID {
+			continue
+		}
+		if len(statuses) > 0 && !statuses[o.Status] {
+			continue
+		}
+		if !filter.From.IsZero() && o.UploadedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !o.UploadedAt.Before(filter.To) {
+			continue
+		}
+		orders = append(orders, o)
+	}
+	s.ordersByIDMu.RUnlock()
+
+	// тот же порядок, что и в базе: по паре (uploaded_at, id)
+	less := func(a, b *gophermart.Order) bool {
+		if a.UploadedAt.Equal(b.UploadedAt) {
+			return a.ID < b.ID
+		}
+		return a.UploadedAt.Before(b.UploadedAt)
+	}
+	desc := filter.Sort == gophermart.SortDesc
+	sort.Slice(orders, func(i, j int) bool {
+		if desc {
+			return less(orders[j], orders[i])
+		}
+		return less(orders[i], orders[j])
+	})
+
+	if filter.Cursor != nil {
+		cursor := &gophermart.Order{ID: filter.Cursor.ID, UploadedAt: filter.Cursor.UploadedAt}
+		page := orders[:0]
+		for _, o := range orders {
+			if (!desc && less(cursor, o)) || (desc && less(o, cursor)) {
+				page = append(page, o)
+			}
+		}
+		orders = page
+	}
+	if filter.Limit > 0 && uint64(len(orders)) > filter.Limit {
+		orders = orders[:filter.Limit]
+	}
+
+	return orders, nil
+}
+
 func (s *Storage) UpdateOrder(o *gophermart.Order) error {
 	id := strconv.Itoa(int(o.ID))
 	if !loon.IsValid(id) {
@@ -49,5 +141,14 @@ func (s *Storage) UpdateOrder(o *gophermart.Order) error {
 	s.ordersByID[o.ID] = o
 	s.ordersByIDMu.Unlock()
 
+	// начисление по обработанному заказу пополняет баланс пользователя
+	if o.Status == gophermart.StatusProcessed {
+		s.balancesMu.Lock()
+		if b, ok := s.balancesByUserID[o.UserID]; ok {
+			b.Current += o.Accrual
+		}
+		s.balancesMu.Unlock()
+	}
+
 	return nil
 }
@@ -1,34 +1,156 @@
 package basicstorage
 
 import (
-	"fmt"
+	"sort"
+	"time"
+
 	"github.com/sergeysynergy/hardtest/internal/gophermart"
 )
 
 func (s *Storage) AddSession(userSession *gophermart.Session) error {
-	s.sessionsBySessionTokenMu.Lock()
-	s.sessionsBySessionToken[userSession.Token] = userSession
-	s.sessionsBySessionTokenMu.Unlock()
+	s.sessionsByTokenHashMu.Lock()
+	s.sessionsByTokenHash[userSession.TokenHash] = userSession
+	s.sessionsByTokenHashMu.Unlock()
 
 	return nil
 }
 
-func (s *Storage) GetSession(token string) (*gophermart.Session, error) {
-	s.sessionsBySessionTokenMu.RLock()
-	defer s.sessionsBySessionTokenMu.RUnlock()
+func (s *Storage) GetSession(tokenHash string) (*gophermart.Session, error) {
+	s.sessionsByTokenHashMu.RLock()
+	defer s.sessionsByTokenHashMu.RUnlock()
 
-	userSession, ok := s.sessionsBySessionToken[token]
+	userSession, ok := s.sessionsByTokenHash[tokenHash]
 	if !ok {
-		return nil, fmt.Errorf("session token not found")
+		return nil, gophermart.ErrSessionNotFound
 	}
 
 	return userSession, nil
 }
 
-func (s *Storage) DeleteSession(token string) error {
-	s.sessionsBySessionTokenMu.Lock()
-	delete(s.sessionsBySessionToken, token)
-	s.sessionsBySessionTokenMu.Unlock()
+func (s *Storage) TouchSession(tokenHash string, expiry, lastUsedAt time.Time) error {
+	s.sessionsByTokenHashMu.Lock()
+	defer s.sessionsByTokenHashMu.Unlock()
+
+	userSession, ok := s.sessionsByTokenHash[tokenHash]
+	if !ok {
+		return gophermart.ErrSessionNotFound
+	}
+	// сессию могли отдать наружу, поэтому храним копию, а не меняем прежнюю
+	renewed := *userSession
+	renewed.Expiry = expiry
+	renewed.LastUsedAt = lastUsedAt
+	s.sessionsByTokenHash[tokenHash] = &renewed
+
+	return nil
+}
+
+func (s *Storage) UseSession(tokenHash string) error {
+	s.sessionsByTokenHashMu.Lock()
+	defer s.sessionsByTokenHashMu.Unlock()
+
+	userSession, ok := s.sessionsByTokenHash[tokenHash]
+	if !ok || userSession.Used {
+		return gophermart.ErrSessionNotFound
+	}
+	used := *userSession
+	used.Used = true
+	s.sessionsByTokenHash[tokenHash] = &used
+
+	return nil
+}
+
+func (s *Storage) DeleteSession(tokenHash string) error {
+	s.sessionsByTokenHashMu.Lock()
+	delete(s.sessionsByTokenHash, tokenHash)
+	s.sessionsByTokenHashMu.Unlock()
+
+	return nil
+}
+
+func (s *Storage) DeleteSessionFamily(family string) error {
+	s.sessionsByTokenHashMu.Lock()
+	for token, userSession := range s.sessionsByTokenHash {
+		if userSession.Family == family {
+			delete(s.sessionsByTokenHash, token)
+		}
+	}
+	s.sessionsByTokenHashMu.Unlock()
+
+	return nil
+}
+
+func (s *Storage) DeleteExpiredSessions(now time.Time) (int64, error) {
+	s.sessionsByTokenHashMu.Lock()
+	defer s.sessionsByTokenHashMu.Unlock()
+
+	var deleted int64
+	for token, userSession := range s.sessionsByTokenHash {
+		if userSession.ExpiredAt(now) {
+			delete(s.sessionsByTokenHash, token)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) CountActiveSessions(now time.Time) (int64, error) {
+	s.sessionsByTokenHashMu.RLock()
+	defer s.sessionsByTokenHashMu.RUnlock()
+
+	var active int64
+	for _, userSession := range s.sessionsByTokenHash {
+		if !userSession.Used && !userSession.ExpiredAt(now) {
+			active++
+		}
+	}
+
+	return active, nil
+}
+
+func (s *Storage) GetUserSessions(userID uint64, now time.Time) ([]*gophermart.Session, error) {
+	s.sessionsByTokenHashMu.RLock()
+	defer s.sessionsByTokenHashMu.RUnlock()
+
+	sessions := make([]*gophermart.Session, 0)
+	for _, userSession := range s.sessionsByTokenHash {
+		if userSession.UserID == userID && !userSession.Used && !userSession.ExpiredAt(now) {
+			sessions = append(sessions, userSession)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+func (s *Storage) DeleteUserSession(userID uint64, id string) error {
+	s.sessionsByTokenHashMu.Lock()
+	defer s.sessionsByTokenHashMu.Unlock()
+
+	found := false
+	for tokenHash, userSession := range s.sessionsByTokenHash {
+		if userSession.UserID == userID && userSession.ID == id {
+			delete(s.sessionsByTokenHash, tokenHash)
+			found = true
+		}
+	}
+	if !found {
+		return gophermart.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteUserSessions(userID uint64, exceptID string) error {
+	s.sessionsByTokenHashMu.Lock()
+	for tokenHash, userSession := range s.sessionsByTokenHash {
+		if userSession.UserID == userID && userSession.ID != exceptID {
+			delete(s.sessionsByTokenHash, tokenHash)
+		}
+	}
+	s.sessionsByTokenHashMu.Unlock()
 
 	return nil
 }
@@ -0,0 +1,83 @@
+package basicstorage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// Хранилище в памяти поддерживает только базовый сценарий: пользователи, сессии, заказы, баланс и списания.
+// Остальные методы нужны для соответствия интерфейсу gophermart.Storer и всегда возвращают ошибку.
+
+var errNotImplemented = fmt.Errorf("method not implemented")
+
+func (s *Storage) AddTransfer(_ *gophermart.Transfer, _ time.Time, _ uint64) (uint64, error) {
+	return 0, errNotImplemented
+}
+
+func (s *Storage) GetUserTransfers(_ uint64) ([]*gophermart.Transfer, error) {
+	return nil, errNotImplemented
+}
+
+func (s *Storage) AddAdjustment(_ *gophermart.Adjustment) (uint64, error) {
+	return 0, errNotImplemented
+}
+
+func (s *Storage) GetUserAdjustments(_ uint64) ([]*gophermart.Adjustment, error) {
+	return nil, errNotImplemented
+}
+
+func (s *Storage) GetUserStatement(_ uint64, _ *gophermart.StatementFilter) ([]*gophermart.StatementEntry, error) {
+	return nil, errNotImplemented
+}
+
+func (s *Storage) ExportHistory(_ *gophermart.HistoryFilter, _ func(*gophermart.HistoryRecord) error) error {
+	return errNotImplemented
+}
+
+func (s *Storage) AddWebhook(_ *gophermart.Webhook) (uint64, error) {
+	return 0, errNotImplemented
+}
+
+func (s *Storage) GetWebhooks(_ uint64) ([]*gophermart.Webhook, error) {
+	return nil, errNotImplemented
+}
+
+func (s *Storage) DeleteWebhook(_, _ uint64) error {
+	return errNotImplemented
+}
+
+func (s *Storage) GetWebhookAttempts(_, _ uint64, _ uint64) ([]*gophermart.WebhookAttempt, error) {
+	return nil, errNotImplemented
+}
+
+// FanOutOutbox исходящей очереди в памяти нет: доставлять вебхукам нечего
+func (s *Storage) FanOutOutbox(_ uint64, _ time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *Storage) ClaimDeliveries(_, _ time.Time, _ uint64) ([]*gophermart.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (s *Storage) RecordDeliveryAttempt(_ *gophermart.WebhookAttempt, _ string, _ time.Time) error {
+	return errNotImplemented
+}
+
+func (s *Storage) AddHold(_ *gophermart.Hold) (uint64, error) {
+	return 0, errNotImplemented
+}
+
+func (s *Storage) CaptureHold(_, _ uint64) (*gophermart.Hold, error) {
+	return nil, errNotImplemented
+}
+
+func (s *Storage) ReleaseHold(_, _ uint64) (*gophermart.Hold, error) {
+	return nil, errNotImplemented
+}
+
+// ReleaseExpiredHolds резервов в памяти не бывает, освобождать нечего
+func (s *Storage) ReleaseExpiredHolds(_ time.Time) ([]uint64, error) {
+	return nil, nil
+}
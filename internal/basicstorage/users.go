@@ -22,6 +22,10 @@ func (s *Storage) AddUser(u *gophermart.User) (uint64, error) {
 	s.usersByLogin[user.Login] = user
 	s.usersByID[user.ID] = user
 
+	s.balancesMu.Lock()
+	s.balancesByUserID[user.ID] = &gophermart.Balance{UserID: user.ID}
+	s.balancesMu.Unlock()
+
 	return user.ID, nil
 }
 
@@ -36,12 +40,12 @@ func (s *Storage) GetUser(_key interface{}) (*gophermart.User, error) {
 	case string:
 		u, ok = s.usersByLogin[key]
 		if !ok {
-			return nil, fmt.Errorf("user with login `%s` not found", u.Login)
+			return nil, fmt.Errorf("user with login `%s` not found - %w", key, gophermart.ErrUserNotFound)
 		}
 	case uint64:
 		u, ok = s.usersByID[key]
 		if !ok {
-			return nil, fmt.Errorf("user with ID `%d` not found", u.ID)
+			return nil, fmt.Errorf("user with ID `%d` not found - %w", key, gophermart.ErrUserNotFound)
 		}
 	default:
 		return nil, fmt.Errorf("given type not implemented")
@@ -53,3 +57,50 @@ func (s *Storage) GetUser(_key interface{}) (*gophermart.User, error) {
 func (s *Storage) DeleteUser(login string) error {
 	return fmt.Errorf("method not impemented")
 }
+
+func (s *Storage) UpdateUserPassword(userID uint64, password []byte) error {
+	s.usersByLoginMu.Lock()
+	defer s.usersByLoginMu.Unlock()
+
+	u, ok := s.usersByID[userID]
+	if !ok {
+		return fmt.Errorf("user with ID `%d` not found - %w", userID, gophermart.ErrUserNotFound)
+	}
+
+	// прочитанного ранее пользователя не меняем: кладём копию
+	updated := *u
+	updated.Password = password
+	s.usersByLogin[updated.Login] = &updated
+	s.usersByID[updated.ID] = &updated
+
+	return nil
+}
+
+func (s *Storage) AddPasswordReset(reset *gophermart.PasswordReset) error {
+	s.usersByLoginMu.Lock()
+	defer s.usersByLoginMu.Unlock()
+
+	for tokenHash, r := range s.passwordResetsByTokenHash {
+		if r.UserID == reset.UserID {
+			delete(s.passwordResetsByTokenHash, tokenHash)
+		}
+	}
+	stored := *reset
+	stored.Token = ""
+	s.passwordResetsByTokenHash[stored.TokenHash] = &stored
+
+	return nil
+}
+
+func (s *Storage) TakePasswordReset(tokenHash string) (*gophermart.PasswordReset, error) {
+	s.usersByLoginMu.Lock()
+	defer s.usersByLoginMu.Unlock()
+
+	reset, ok := s.passwordResetsByTokenHash[tokenHash]
+	if !ok {
+		return nil, gophermart.ErrInvalidResetToken
+	}
+	delete(s.passwordResetsByTokenHash, tokenHash)
+
+	return reset, nil
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (s *Storage) initAdjustments(ctx context.Context) error {
+	tableName := "adjustments"
+	_, err := s.db.ExecContext(ctx, "select * from "+tableName+";")
+	if err != nil {
+		queryCreateTable := `
+			CREATE TABLE ` + tableName + ` (
+				id serial PRIMARY KEY,
+				user_id bigint NOT NULL,
+				type varchar NOT NULL,
+				sum bigint NOT NULL,
+				reason text NOT NULL,
+				operator_id varchar NOT NULL,
+				created_at timestamp NOT NULL
+			);
+		`
+
+		_, err = s.db.ExecContext(ctx, queryCreateTable)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] table `%s` created", tableName)
+	}
+
+	err = s.initAdjustmentsStatements()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) initAdjustmentsStatements() error {
+	tableName := "adjustments"
+	var err error
+	var stmt *sql.Stmt
+
+	// запись о корректировке баланса: таблица только пополняется и служит журналом аудита
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"INSERT INTO "+tableName+" (user_id, type, sum, reason, operator_id, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["adjustmentsInsert"] = stmt
+
+	// запрос списка корректировок баланса пользователя
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT id, user_id, type, sum, reason, operator_id, created_at FROM "+tableName+
+			" WHERE user_id=$1 ORDER BY created_at desc",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["adjustmentsGetForUser"] = stmt
+
+	return nil
+}
+
+func (s *Storage) AddAdjustment(a *gophermart.Adjustment) (uint64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	txGetBalance := tx.StmtContext(s.ctx, s.stmts["balanceGetForUpdate"])
+	txUpdateBalance := tx.StmtContext(s.ctx, s.stmts["balanceUpdate"])
+	txInsert := tx.StmtContext(s.ctx, s.stmts["adjustmentsInsert"])
+
+	var balance gophermart.Balance
+	row := txGetBalance.QueryRowContext(s.ctx, a.UserID)
+	err = row.Scan(&balance.UserID, &balance.Current, &balance.Withdrawn)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("user balance not found - %w", err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user balance - %w", err)
+	}
+
+	current := balance.Current + a.Sum
+	if a.Type == gophermart.AdjustmentDebit {
+		if balance.Current < a.Sum {
+			return 0, gophermart.ErrNotEnoughFunds
+		}
+		current = balance.Current - a.Sum
+	}
+
+	_, err = txUpdateBalance.ExecContext(s.ctx, a.UserID, current, balance.Withdrawn)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update user balance - %w", err)
+	}
+
+	var id uint64
+	row = txInsert.QueryRowContext(s.ctx, a.UserID, a.Type, a.Sum, a.Reason, a.OperatorID, a.CreatedAt)
+	if err = row.Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert adjustment - %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("add adjustment transaction failed - %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetUserAdjustments(userID uint64) ([]*gophermart.Adjustment, error) {
+	as := make([]*gophermart.Adjustment, 0)
+
+	rows, err := s.stmts["adjustmentsGetForUser"].QueryContext(s.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a gophermart.Adjustment
+		err = rows.Scan(&a.ID, &a.UserID, &a.Type, &a.Sum, &a.Reason, &a.OperatorID, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		as = append(as, &a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return as, nil
+}
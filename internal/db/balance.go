@@ -62,6 +62,16 @@ func (s *Storage) initBalanceStatements() error {
 	}
 	s.stmts["balanceGet"] = stmt
 
+	// запрос текущего баланса пользователя с блокировкой строки до конца транзакции
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT * FROM "+tableName+" WHERE user_id=$1 FOR UPDATE",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["balanceGetForUpdate"] = stmt
+
 	// обновление баланса
 	stmt, err = s.db.PrepareContext(
 		s.ctx,
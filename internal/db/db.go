@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -73,6 +74,16 @@ func (s *Storage) init(dsn string) error {
 		return fmt.Errorf(`failed to create 'sessions' table - %w`, err)
 	}
 
+	err = s.initPasswordResets(ctx)
+	if err != nil {
+		return fmt.Errorf(`failed to create 'password_resets' table - %w`, err)
+	}
+
+	err = s.initLoginFailures(ctx)
+	if err != nil {
+		return fmt.Errorf(`failed to create 'login_failures' table - %w`, err)
+	}
+
 	err = s.initOrders(ctx)
 	if err != nil {
 		return fmt.Errorf(`failed to create 'orders' table - %w`, err)
@@ -88,6 +99,37 @@ func (s *Storage) init(dsn string) error {
 		return fmt.Errorf(`failed to create 'withdrawals' table - %w`, err)
 	}
 
+	err = s.initTransfers(ctx)
+	if err != nil {
+		return fmt.Errorf(`failed to create 'transfers' table - %w`, err)
+	}
+
+	err = s.initAdjustments(ctx)
+	if err != nil {
+		return fmt.Errorf(`failed to create 'adjustments' table - %w`, err)
+	}
+
+	err = s.initHolds(ctx)
+	if err != nil {
+		return fmt.Errorf(`failed to create 'holds' table - %w`, err)
+	}
+
+	err = s.initOutbox(ctx)
+	if err != nil {
+		return fmt.Errorf(`failed to create 'outbox' table - %w`, err)
+	}
+
+	err = s.initWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf(`failed to create 'webhooks' tables - %w`, err)
+	}
+
+	// выписка собирается из всех таблиц операций, поэтому готовим её запрос последним
+	err = s.initStatementStatements()
+	if err != nil {
+		return fmt.Errorf(`failed to prepare statement query - %w`, err)
+	}
+
 	s.db.SetMaxOpenConns(40)
 	s.db.SetMaxIdleConns(20)
 	s.db.SetConnMaxIdleTime(time.Second * 60)
@@ -95,6 +137,25 @@ func (s *Storage) init(dsn string) error {
 	return nil
 }
 
+// columnType тип колонки таблицы, пустой, если колонки нет: нужен, чтобы доводить до текущего вида таблицы,
+// созданные прежними версиями
+func (s *Storage) columnType(ctx context.Context, table, column string) (string, error) {
+	var dataType string
+	row := s.db.QueryRowContext(ctx,
+		"SELECT data_type FROM information_schema.columns WHERE table_name = $1 AND column_name = $2",
+		table, column,
+	)
+	err := row.Scan(&dataType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get `%s.%s` column type - %w", table, column, err)
+	}
+
+	return dataType, nil
+}
+
 func (s *Storage) Ping() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
@@ -0,0 +1,344 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (s *Storage) initHolds(ctx context.Context) error {
+	tableName := "holds"
+	_, err := s.db.ExecContext(ctx, "select * from "+tableName+";")
+	if err != nil {
+		queryCreateTable := `
+			CREATE TABLE ` + tableName + ` (
+				id serial PRIMARY KEY,
+				user_id bigint NOT NULL,
+				order_id bigint NOT NULL,
+				sum bigint NOT NULL,
+				status varchar NOT NULL,
+				created_at timestamp NOT NULL,
+				expires_at timestamp NOT NULL
+			);
+			CREATE UNIQUE INDEX holds_active_order_id ON ` + tableName + ` (order_id) WHERE status = 'HELD';
+		`
+
+		_, err = s.db.ExecContext(ctx, queryCreateTable)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] table `%s` created", tableName)
+	}
+
+	err = s.initHoldsStatements()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) initHoldsStatements() error {
+	tableName := "holds"
+	var err error
+	var stmt *sql.Stmt
+
+	// добавление нового резерва
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"INSERT INTO "+tableName+" (user_id, order_id, sum, status, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["holdsInsert"] = stmt
+
+	// запрос активного резерва по номеру заказа
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT id FROM "+tableName+" WHERE order_id=$1 AND status='HELD'",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["holdsGetActiveByOrderID"] = stmt
+
+	// запрос резерва по ID с блокировкой строки до конца транзакции
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT id, user_id, order_id, sum, status, created_at, expires_at FROM "+tableName+" WHERE id=$1 FOR UPDATE",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["holdsGetForUpdate"] = stmt
+
+	// смена статуса резерва
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"UPDATE "+tableName+" SET status = $2 WHERE id = $1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["holdsUpdateStatus"] = stmt
+
+	// освобождение просроченных резервов одним запросом: баллы возвращаются на баланс пользователей
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		`WITH expired AS (
+			UPDATE `+tableName+` SET status = 'EXPIRED' WHERE status = 'HELD' AND expires_at <= $1 RETURNING user_id, sum
+		)
+		UPDATE balance b SET current = b.current + e.total
+		FROM (SELECT user_id, SUM(sum) AS total FROM expired GROUP BY user_id) e
+		WHERE b.user_id = e.user_id
+		RETURNING b.user_id`,
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["holdsReleaseExpired"] = stmt
+
+	return nil
+}
+
+func (s *Storage) AddHold(hold *gophermart.Hold) (uint64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	txGetBalance := tx.StmtContext(s.ctx, s.stmts["balanceGetForUpdate"])
+	txUpdateBalance := tx.StmtContext(s.ctx, s.stmts["balanceUpdate"])
+	txGetWithdrawal := tx.StmtContext(s.ctx, s.stmts["withdrawalsGetByID"])
+	txGetActiveHold := tx.StmtContext(s.ctx, s.stmts["holdsGetActiveByOrderID"])
+	txInsertHold := tx.StmtContext(s.ctx, s.stmts["holdsInsert"])
+
+	// проверим баланс
+	var balance gophermart.Balance
+	row := txGetBalance.QueryRowContext(s.ctx, hold.UserID)
+	err = row.Scan(&balance.UserID, &balance.Current, &balance.Withdrawn)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("user balance not found - %w", err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user balance - %w", err)
+	}
+	if balance.Current < hold.Sum {
+		return 0, gophermart.ErrNotEnoughFunds
+	}
+
+	// по заказу не должно быть ни списания, ни другого активного резерва
+	var id uint64
+	var bw gophermart.Withdraw
+	date := new(string)
+	err = txGetWithdrawal.QueryRowContext(s.ctx, hold.OrderID).Scan(&bw.OrderID, &bw.UserID, &bw.Sum, date)
+	if err == nil {
+		return 0, gophermart.ErrWithdrawAlreadyRecorded
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get withdrawal - %w", err)
+	}
+	err = txGetActiveHold.QueryRowContext(s.ctx, hold.OrderID).Scan(&id)
+	if err == nil {
+		return 0, gophermart.ErrHoldAlreadyExists
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get hold - %w", err)
+	}
+
+	// средств достаточно, уменьшим доступный баланс
+	current := balance.Current - hold.Sum
+	_, err = txUpdateBalance.ExecContext(s.ctx, hold.UserID, current, balance.Withdrawn)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update user balance - %w", err)
+	}
+
+	row = txInsertHold.QueryRowContext(s.ctx, hold.UserID, hold.OrderID, hold.Sum, hold.Status, hold.CreatedAt, hold.ExpiresAt)
+	if err = row.Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert hold - %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("add hold transaction failed - %w", err)
+	}
+
+	return id, nil
+}
+
+// getActiveHold получает резерв пользователя в рамках транзакции, блокируя его строку
+func (s *Storage) getActiveHold(tx *sql.Tx, holdID, userID uint64) (*gophermart.Hold, error) {
+	txGetHold := tx.StmtContext(s.ctx, s.stmts["holdsGetForUpdate"])
+
+	h := &gophermart.Hold{}
+	row := txGetHold.QueryRowContext(s.ctx, holdID)
+	err := row.Scan(&h.ID, &h.UserID, &h.OrderID, &h.Sum, &h.Status, &h.CreatedAt, &h.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, gophermart.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold - %w", err)
+	}
+
+	// чужой резерв для пользователя не существует
+	if h.UserID != userID {
+		return nil, gophermart.ErrHoldNotFound
+	}
+	if h.Status != gophermart.HoldStatusHeld {
+		return nil, gophermart.ErrHoldNotActive
+	}
+
+	return h, nil
+}
+
+func (s *Storage) CaptureHold(holdID, userID uint64) (*gophermart.Hold, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txUpdateHold := tx.StmtContext(s.ctx, s.stmts["holdsUpdateStatus"])
+	txGetBalance := tx.StmtContext(s.ctx, s.stmts["balanceGetForUpdate"])
+	txUpdateBalance := tx.StmtContext(s.ctx, s.stmts["balanceUpdate"])
+	txGetWithdrawal := tx.StmtContext(s.ctx, s.stmts["withdrawalsGetByID"])
+	txInsertWithdrawal := tx.StmtContext(s.ctx, s.stmts["withdrawalsInsert"])
+
+	h, err := s.getActiveHold(tx, holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance gophermart.Balance
+	row := txGetBalance.QueryRowContext(s.ctx, userID)
+	err = row.Scan(&balance.UserID, &balance.Current, &balance.Withdrawn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user balance - %w", err)
+	}
+
+	now := time.Now()
+	if !h.ExpiresAt.After(now) {
+		// резерв просрочен: вернём баллы на счёт вместо списания
+		h.Status = gophermart.HoldStatusExpired
+		if _, err = txUpdateHold.ExecContext(s.ctx, h.ID, h.Status); err != nil {
+			return nil, fmt.Errorf("failed to update hold - %w", err)
+		}
+		_, err = txUpdateBalance.ExecContext(s.ctx, userID, balance.Current+h.Sum, balance.Withdrawn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update user balance - %w", err)
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, fmt.Errorf("expire hold transaction failed - %w", err)
+		}
+		return nil, gophermart.ErrHoldExpired
+	}
+
+	// списание по заказу могли записать в обход резерва: резерв остаётся активным, его можно освободить
+	var bw gophermart.Withdraw
+	date := new(string)
+	err = txGetWithdrawal.QueryRowContext(s.ctx, h.OrderID).Scan(&bw.OrderID, &bw.UserID, &bw.Sum, date)
+	if err == nil {
+		return nil, gophermart.ErrWithdrawAlreadyRecorded
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get withdrawal - %w", err)
+	}
+
+	// баллы уже вычтены из текущего баланса при резервировании, осталось учесть списание
+	h.Status = gophermart.HoldStatusCaptured
+	if _, err = txUpdateHold.ExecContext(s.ctx, h.ID, h.Status); err != nil {
+		return nil, fmt.Errorf("failed to update hold - %w", err)
+	}
+	_, err = txUpdateBalance.ExecContext(s.ctx, userID, balance.Current, balance.Withdrawn+h.Sum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user balance - %w", err)
+	}
+	withdraw := &gophermart.Withdraw{OrderID: h.OrderID, UserID: userID, Sum: h.Sum, ProcessedAt: now}
+	_, err = txInsertWithdrawal.ExecContext(s.ctx, withdraw.OrderID, withdraw.UserID, withdraw.Sum, withdraw.ProcessedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert withdrawal - %w", err)
+	}
+
+	// для подписчиков списание по резерву не отличается от обычного
+	if err = s.addOutboxEvent(tx, gophermart.NewWithdrawalMade(withdraw)); err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("capture hold transaction failed - %w", err)
+	}
+
+	return h, nil
+}
+
+func (s *Storage) ReleaseHold(holdID, userID uint64) (*gophermart.Hold, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txUpdateHold := tx.StmtContext(s.ctx, s.stmts["holdsUpdateStatus"])
+	txGetBalance := tx.StmtContext(s.ctx, s.stmts["balanceGetForUpdate"])
+	txUpdateBalance := tx.StmtContext(s.ctx, s.stmts["balanceUpdate"])
+
+	h, err := s.getActiveHold(tx, holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance gophermart.Balance
+	row := txGetBalance.QueryRowContext(s.ctx, userID)
+	err = row.Scan(&balance.UserID, &balance.Current, &balance.Withdrawn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user balance - %w", err)
+	}
+
+	// вернём зарезервированные баллы на счёт
+	h.Status = gophermart.HoldStatusReleased
+	if _, err = txUpdateHold.ExecContext(s.ctx, h.ID, h.Status); err != nil {
+		return nil, fmt.Errorf("failed to update hold - %w", err)
+	}
+	_, err = txUpdateBalance.ExecContext(s.ctx, userID, balance.Current+h.Sum, balance.Withdrawn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user balance - %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("release hold transaction failed - %w", err)
+	}
+
+	return h, nil
+}
+
+func (s *Storage) ReleaseExpiredHolds(now time.Time) ([]uint64, error) {
+	userIDs := make([]uint64, 0)
+
+	rows, err := s.stmts["holdsReleaseExpired"].QueryContext(s.ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID uint64
+		if err = rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (s *Storage) initLoginFailures(ctx context.Context) error {
+	tableName := "login_failures"
+	_, err := s.db.ExecContext(ctx, "select * from "+tableName+";")
+	if err != nil {
+		queryCreateTable := `
+			CREATE TABLE ` + tableName + ` (
+				key varchar PRIMARY KEY,
+				failures integer NOT NULL,
+				last_failure timestamptz NOT NULL
+			);
+			CREATE INDEX login_failures_last_failure_idx ON ` + tableName + ` (last_failure);
+		`
+
+		_, err = s.db.ExecContext(ctx, queryCreateTable)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] table `%s` created", tableName)
+	}
+
+	err = s.initLoginFailuresStatements()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) initLoginFailuresStatements() error {
+	tableName := "login_failures"
+	var err error
+	var stmt *sql.Stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT failures, last_failure FROM "+tableName+" WHERE key=$1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["loginFailuresGet"] = stmt
+
+	// неудача учитывается одним запросом, чтобы одновременные попытки на разных репликах не потеряли счёт;
+	// счётчик, в котором неудач не было дольше окна, начинается заново
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"INSERT INTO "+tableName+" AS lf (key, failures, last_failure) VALUES ($1, 1, $2) "+
+			"ON CONFLICT (key) DO UPDATE SET "+
+			"failures = CASE WHEN lf.last_failure < $3 THEN 1 ELSE lf.failures + 1 END, last_failure = $2 "+
+			"RETURNING failures, last_failure",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["loginFailuresAdd"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"UPDATE "+tableName+" SET failures = failures - 1 WHERE key=$1 AND failures > 0",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["loginFailuresRelease"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM "+tableName+" WHERE key=$1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["loginFailuresDelete"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM "+tableName+" WHERE last_failure < $1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["loginFailuresDeleteBefore"] = stmt
+
+	return nil
+}
+
+func (s *Storage) GetLoginFailures(key string) (*gophermart.LoginFailures, error) {
+	failures := &gophermart.LoginFailures{Key: key}
+	row := s.stmts["loginFailuresGet"].QueryRowContext(s.ctx, key)
+	err := row.Scan(&failures.Failures, &failures.LastFailure)
+	if errors.Is(err, sql.ErrNoRows) {
+		return failures, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login failures - %w", err)
+	}
+
+	return failures, nil
+}
+
+func (s *Storage) AddLoginFailure(key string, now time.Time, window time.Duration) (*gophermart.LoginFailures, error) {
+	failures := &gophermart.LoginFailures{Key: key}
+	row := s.stmts["loginFailuresAdd"].QueryRowContext(s.ctx, key, now, now.Add(-window))
+	if err := row.Scan(&failures.Failures, &failures.LastFailure); err != nil {
+		return nil, fmt.Errorf("failed to add login failure - %w", err)
+	}
+
+	return failures, nil
+}
+
+// ReleaseLoginFailure снимает одну учтённую попытку, если она оказалась удачной
+func (s *Storage) ReleaseLoginFailure(key string) error {
+	_, err := s.stmts["loginFailuresRelease"].ExecContext(s.ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to release login failure - %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteLoginFailures(key string) error {
+	_, err := s.stmts["loginFailuresDelete"].ExecContext(s.ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete login failures - %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteLoginFailuresBefore(t time.Time) (int64, error) {
+	res, err := s.stmts["loginFailuresDeleteBefore"].ExecContext(s.ctx, t)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale login failures - %w", err)
+	}
+
+	return res.RowsAffected()
+}
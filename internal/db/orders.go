@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"github.com/sergeysynergy/hardtest/internal/gophermart"
 	"log"
+	"strings"
 	"time"
 )
 
+// ordersColumns столбцы заказа в порядке полей, которые читают запросы заказов
+const ordersColumns = "id, user_id, status, accrual, uploaded_at"
+
 func (s *Storage) initOrders(ctx context.Context) error {
 	tableName := "orders"
 	_, err := s.db.ExecContext(ctx, "select * from "+tableName+";")
@@ -32,6 +36,12 @@ func (s *Storage) initOrders(ctx context.Context) error {
 		log.Printf("[DEBUG] table `%s` created", tableName)
 	}
 
+	// время начисления: таблица могла быть создана до того, как его стали записывать
+	_, err = s.db.ExecContext(ctx, "ALTER TABLE "+tableName+" ADD COLUMN IF NOT EXISTS processed_at timestamp;")
+	if err != nil {
+		return err
+	}
+
 	err = s.initOrdersStatements()
 	if err != nil {
 		return err
@@ -55,50 +65,60 @@ func (s *Storage) initOrdersStatements() error {
 	}
 	s.stmts["ordersInsert"] = stmt
 
-	// запрос заказа по ID
+	// добавление заказа, если заказа с таким номером ещё нет
 	stmt, err = s.db.PrepareContext(
 		s.ctx,
-		"SELECT * FROM "+tableName+" WHERE id=$1",
+		"INSERT INTO "+tableName+" (id, user_id, status, uploaded_at) VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING",
 	)
 	if err != nil {
 		return err
 	}
-	s.stmts["orderGetByID"] = stmt
+	s.stmts["ordersInsertIfAbsent"] = stmt
 
-	// обновление заказа
+	// запрос владельца заказа
 	stmt, err = s.db.PrepareContext(
 		s.ctx,
-		"UPDATE "+tableName+" SET status = $2, accrual = $3 WHERE id = $1",
+		"SELECT user_id FROM "+tableName+" WHERE id=$1",
 	)
 	if err != nil {
 		return err
 	}
-	s.stmts["ordersUpdate"] = stmt
+	s.stmts["ordersGetOwner"] = stmt
 
 	// запрос заказа по ID
 	stmt, err = s.db.PrepareContext(
 		s.ctx,
-		"SELECT * FROM "+tableName+" WHERE id=$1",
+		"SELECT "+ordersColumns+" FROM "+tableName+" WHERE id=$1",
 	)
 	if err != nil {
 		return err
 	}
-	s.stmts["ordersGetByID"] = stmt
+	s.stmts["orderGetByID"] = stmt
 
-	// запрос списка заказов пользователя по user_id
+	// обновление заказа: время начисления записываем только вместе с окончательным статусом
 	stmt, err = s.db.PrepareContext(
 		s.ctx,
-		"SELECT * FROM "+tableName+" WHERE user_id=$1 order by uploaded_at",
+		"UPDATE "+tableName+" SET status = $2, accrual = $3, processed_at = COALESCE($4, processed_at) WHERE id = $1",
 	)
 	if err != nil {
 		return err
 	}
-	s.stmts["ordersGetForUser"] = stmt
+	s.stmts["ordersUpdate"] = stmt
+
+	// запрос заказа по ID
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT "+ordersColumns+" FROM "+tableName+" WHERE id=$1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["ordersGetByID"] = stmt
 
 	// запрос заказов для очереди обработки: только со статусом NEW и PROCESSING
 	stmt, err = s.db.PrepareContext(
 		s.ctx,
-		"SELECT * FROM "+tableName+" WHERE status='NEW' or status='PROCESSING' order by uploaded_at LIMIT $1",
+		"SELECT "+ordersColumns+" FROM "+tableName+" WHERE status='NEW' or status='PROCESSING' order by uploaded_at LIMIT $1",
 	)
 	if err != nil {
 		return err
@@ -130,6 +150,9 @@ func (s *Storage) AddOrder(o *gophermart.Order) error {
 			if err != nil {
 				return err
 			}
+			if err = s.addOutboxEvent(tx, gophermart.NewOrderAccepted(o)); err != nil {
+				return err
+			}
 
 			// всё хорошо, выполним транзакцию
 			err = tx.Commit()
@@ -149,6 +172,54 @@ func (s *Storage) AddOrder(o *gophermart.Order) error {
 	return gophermart.ErrOrderAlreadyLoadedByAnotherUser
 }
 
+// AddOrders добавляет пакет заказов одной транзакцией: для каждого заказа возвращается
+// nil либо ошибка о том, что номер уже загружен этим или другим пользователем
+func (s *Storage) AddOrders(orders []*gophermart.Order) ([]error, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txInsert := tx.StmtContext(s.ctx, s.stmts["ordersInsertIfAbsent"])
+	txGetOwner := tx.StmtContext(s.ctx, s.stmts["ordersGetOwner"])
+
+	errs := make([]error, len(orders))
+	for i, o := range orders {
+		res, err := txInsert.ExecContext(s.ctx, o.ID, o.UserID, o.Status, o.UploadedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert order - %w", err)
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert order - %w", err)
+		}
+		if inserted == 1 {
+			if err = s.addOutboxEvent(tx, gophermart.NewOrderAccepted(o)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// заказ уже существует, выясним, кем он был загружен
+		var ownerID uint64
+		if err = txGetOwner.QueryRowContext(s.ctx, o.ID).Scan(&ownerID); err != nil {
+			return nil, fmt.Errorf("failed to get order owner - %w", err)
+		}
+		errs[i] = gophermart.ErrOrderAlreadyLoadedByAnotherUser
+		if ownerID == o.UserID {
+			errs[i] = gophermart.ErrOrderAlreadyLoadedByUser
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("add orders transaction failed - %w", err)
+	}
+
+	return errs, nil
+}
+
 func (s *Storage) GetOrder(orderID uint64) (*gophermart.Order, error) {
 	o := &gophermart.Order{}
 	accrual := new(sql.NullInt64)
@@ -174,14 +245,47 @@ func (s *Storage) GetOrder(orderID uint64) (*gophermart.Order, error) {
 	return o, nil
 }
 
-func (s *Storage) GetUserOrders(id uint64) ([]*gophermart.Order, error) {
+// GetUserOrders выборка заказов пользователя собирается динамически из условий фильтра,
+// поэтому вместо подготовленного запроса используем обычный с параметрами
+func (s *Storage) GetUserOrders(id uint64, filter *gophermart.OrdersFilter) ([]*gophermart.Order, error) {
 	orders := make([]*gophermart.Order, 0)
 
-	rows, err := s.stmts["ordersGetForUser"].QueryContext(s.ctx, id)
-	if err != nil {
-		return nil, err
+	conds := []string{"user_id=$1"}
+	args := []interface{}{id}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
 	}
-	if rows.Err() != nil {
+
+	if len(filter.Statuses) > 0 {
+		// status хранится как char(256): приведение к text отбрасывает хвостовые пробелы
+		conds = append(conds, "status::text = ANY("+arg(filter.Statuses)+"::text[])")
+	}
+	if !filter.From.IsZero() {
+		conds = append(conds, "uploaded_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conds = append(conds, "uploaded_at < "+arg(filter.To))
+	}
+
+	// сортируем по паре (uploaded_at, id), чтобы курсор однозначно указывал на позицию
+	direction, cmp := "ASC", ">"
+	if filter.Sort == gophermart.SortDesc {
+		direction, cmp = "DESC", "<"
+	}
+	if filter.Cursor != nil {
+		cond := fmt.Sprintf("(uploaded_at, id) %s (%s, %s)", cmp, arg(filter.Cursor.UploadedAt), arg(filter.Cursor.ID))
+		conds = append(conds, cond)
+	}
+
+	query := "SELECT " + ordersColumns + " FROM orders WHERE " + strings.Join(conds, " AND ") +
+		fmt.Sprintf(" ORDER BY uploaded_at %s, id %s", direction, direction)
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(s.ctx, query, args...)
+	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
@@ -206,6 +310,9 @@ func (s *Storage) GetUserOrders(id uint64) ([]*gophermart.Order, error) {
 
 		orders = append(orders, &bo)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return orders, nil
 }
@@ -255,12 +362,13 @@ func (s *Storage) UpdateOrder(o *gophermart.Order) error {
 
 	txUpdateOrder := tx.StmtContext(s.ctx, s.stmts["ordersUpdate"])
 	txUpdateBalance := tx.StmtContext(s.ctx, s.stmts["balanceUpdate"])
-	txGetBalance := tx.StmtContext(s.ctx, s.stmts["balanceGet"])
+	txGetBalance := tx.StmtContext(s.ctx, s.stmts["balanceGetForUpdate"])
 
 	// обновим заказ
 	if o.Status == gophermart.StatusProcessed {
 		// записываем начисление только для заказов со статусом выполнено
-		_, err = txUpdateOrder.ExecContext(s.ctx, o.ID, o.Status, o.Accrual)
+		processedAt := sql.NullTime{Time: time.Now(), Valid: true}
+		_, err = txUpdateOrder.ExecContext(s.ctx, o.ID, o.Status, o.Accrual, processedAt)
 		if err != nil {
 			return fmt.Errorf("failed to update order - %w", err)
 		}
@@ -280,12 +388,17 @@ func (s *Storage) UpdateOrder(o *gophermart.Order) error {
 		}
 	} else {
 		// для всех остальных статусов - начисления не записываем, баланс не обновляем
-		_, err = txUpdateOrder.ExecContext(s.ctx, o.ID, o.Status, o.Accrual)
+		_, err = txUpdateOrder.ExecContext(s.ctx, o.ID, o.Status, o.Accrual, sql.NullTime{})
 		if err != nil {
 			return fmt.Errorf("failed to update order - %w", err)
 		}
 	}
 
+	// окончательный статус заказа публикуем подписчикам вебхуков через исходящую очередь
+	if err = s.addOutboxEvent(tx, gophermart.NewOrderStatusEvent(o)); err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("update order transaction failed - %w", err)
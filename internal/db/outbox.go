@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (s *Storage) initOutbox(ctx context.Context) error {
+	tableName := "outbox"
+	_, err := s.db.ExecContext(ctx, "select * from "+tableName+";")
+	if err != nil {
+		queryCreateTable := `
+			CREATE TABLE ` + tableName + ` (
+				id bigserial PRIMARY KEY,
+				event varchar NOT NULL,
+				user_id bigint NOT NULL,
+				payload jsonb NOT NULL,
+				created_at timestamp NOT NULL,
+				dispatched_at timestamp
+			);
+			CREATE INDEX outbox_not_dispatched ON ` + tableName + ` (id) WHERE dispatched_at IS NULL;
+		`
+
+		_, err = s.db.ExecContext(ctx, queryCreateTable)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] table `%s` created", tableName)
+	}
+
+	err = s.initOutboxStatements()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) initOutboxStatements() error {
+	tableName := "outbox"
+	var err error
+	var stmt *sql.Stmt
+
+	// запись события в исходящую очередь: выполняется в транзакции изменения данных
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"INSERT INTO "+tableName+" (event, user_id, payload, created_at) VALUES ($1, $2, $3::jsonb, $4)",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["outboxInsert"] = stmt
+
+	return nil
+}
+
+// addOutboxEvent записывает доменное событие в исходящую очередь в рамках переданной транзакции,
+// так что событие появится тогда и только тогда, когда будут зафиксированы сами изменения;
+// внутренние события сервиса в очередь не попадают
+func (s *Storage) addOutboxEvent(tx *sql.Tx, e gophermart.Event) error {
+	if e == nil || !gophermart.IsOutboxEvent(e.EventName()) {
+		return nil
+	}
+
+	oe, err := gophermart.NewOutboxEvent(e)
+	if err != nil {
+		return fmt.Errorf("failed to build outbox event - %w", err)
+	}
+
+	txInsert := tx.StmtContext(s.ctx, s.stmts["outboxInsert"])
+	_, err = txInsert.ExecContext(s.ctx, oe.Event, oe.UserID, string(oe.Payload), oe.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert outbox event - %w", err)
+	}
+
+	return nil
+}
+
+// FanOutOutbox превращает новые события очереди в доставки для подходящих подписок одним запросом;
+// SKIP LOCKED позволяет нескольким экземплярам сервиса разбирать очередь, не мешая друг другу
+func (s *Storage) FanOutOutbox(limit uint64, now time.Time) (int64, error) {
+	res, err := s.stmts["outboxFanOut"].ExecContext(s.ctx, limit, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
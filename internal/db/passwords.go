@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (s *Storage) initPasswordResets(ctx context.Context) error {
+	tableName := "password_resets"
+	_, err := s.db.ExecContext(ctx, "select * from "+tableName+";")
+	if err != nil {
+		// у пользователя не больше одного токена сброса: новый запрос заменяет прежний
+		queryCreateTable := `
+			CREATE TABLE ` + tableName + ` (
+				token_hash varchar PRIMARY KEY,
+				user_id bigint NOT NULL UNIQUE,
+				expires_at timestamptz NOT NULL
+			);
+		`
+
+		_, err = s.db.ExecContext(ctx, queryCreateTable)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] table `%s` created", tableName)
+	}
+
+	err = s.initPasswordResetsStatements()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) initPasswordResetsStatements() error {
+	tableName := "password_resets"
+	var err error
+	var stmt *sql.Stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"INSERT INTO "+tableName+" (token_hash, user_id, expires_at) VALUES ($1, $2, $3) "+
+			"ON CONFLICT (user_id) DO UPDATE SET token_hash=EXCLUDED.token_hash, expires_at=EXCLUDED.expires_at",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["passwordResetsUpsert"] = stmt
+
+	// токен одноразовый: читаем и удаляем одним запросом, из двух одновременных сбросов пройдёт только один
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM "+tableName+" WHERE token_hash=$1 RETURNING user_id, expires_at",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["passwordResetsTake"] = stmt
+
+	return nil
+}
+
+func (s *Storage) AddPasswordReset(reset *gophermart.PasswordReset) error {
+	_, err := s.stmts["passwordResetsUpsert"].ExecContext(s.ctx, reset.TokenHash, reset.UserID, reset.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to add password reset - %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) TakePasswordReset(tokenHash string) (*gophermart.PasswordReset, error) {
+	reset := &gophermart.PasswordReset{TokenHash: tokenHash}
+	row := s.stmts["passwordResetsTake"].QueryRowContext(s.ctx, tokenHash)
+	err := row.Scan(&reset.UserID, &reset.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gophermart.ErrInvalidResetToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take password reset - %w", err)
+	}
+
+	return reset, nil
+}
@@ -5,20 +5,36 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/sergeysynergy/hardtest/internal/gophermart"
 )
 
+// sessionColumns колонки сессии в порядке, в котором их читает scanSession
+const sessionColumns = "id, user_id, token_hash, expiry, absolute_expiry, remember, family, created_at, last_used_at, ip, user_agent, used"
+
 func (s *Storage) initSessions(ctx context.Context) error {
 	dbName := "sessions"
 	_, err := s.db.ExecContext(ctx, "select * from "+dbName+";")
 	if err != nil {
 		queryCreateTable := `
 			CREATE TABLE ` + dbName + ` (
+				id varchar NOT NULL,
 				user_id bigint NOT NULL,
-				token varchar NOT NULL, 
-				expiry time NOT NULL
+				token_hash varchar NOT NULL,
+				expiry timestamptz NOT NULL,
+				absolute_expiry timestamptz,
+				remember boolean NOT NULL DEFAULT false,
+				family varchar NOT NULL DEFAULT '',
+				created_at timestamptz NOT NULL DEFAULT now(),
+				last_used_at timestamptz NOT NULL DEFAULT now(),
+				ip varchar NOT NULL DEFAULT '',
+				user_agent varchar NOT NULL DEFAULT '',
+				used boolean NOT NULL DEFAULT false
 			);
+			CREATE UNIQUE INDEX sessions_token_hash_idx ON ` + dbName + ` (token_hash);
+			CREATE INDEX sessions_user_id_idx ON ` + dbName + ` (user_id);
+			CREATE INDEX sessions_family_idx ON ` + dbName + ` (family) WHERE family <> '';
 		`
 
 		_, err = s.db.ExecContext(ctx, queryCreateTable)
@@ -29,6 +45,66 @@ func (s *Storage) initSessions(ctx context.Context) error {
 		log.Printf("[DEBUG] table `%s` created", dbName)
 	}
 
+	// таблица могла быть создана до появления refresh-токенов и сроков сессии
+	_, err = s.db.ExecContext(ctx, `
+		ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS family varchar NOT NULL DEFAULT '';
+		ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS absolute_expiry timestamptz;
+		ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS remember boolean NOT NULL DEFAULT false;
+		CREATE INDEX IF NOT EXISTS sessions_family_idx ON `+dbName+` (family) WHERE family <> '';
+		CREATE INDEX IF NOT EXISTS sessions_expiry_idx ON `+dbName+` (expiry);
+		ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS id varchar NOT NULL DEFAULT '';
+		ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+		ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS last_used_at timestamptz NOT NULL DEFAULT now();
+		ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS ip varchar NOT NULL DEFAULT '';
+		ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS user_agent varchar NOT NULL DEFAULT '';
+		CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON `+dbName+` (user_id);
+		ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS used boolean NOT NULL DEFAULT false;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// раньше срок хранился как время суток без даты: такие сессии продлить нельзя, они просто истекают
+	expiryType, err := s.columnType(ctx, dbName, "expiry")
+	if err != nil {
+		return err
+	}
+	if expiryType == "time without time zone" {
+		_, err = s.db.ExecContext(ctx, "ALTER TABLE "+dbName+" ALTER COLUMN expiry TYPE timestamptz USING now()")
+		if err != nil {
+			return err
+		}
+		log.Printf("[DEBUG] column `%s.expiry` converted to timestamptz", dbName)
+	}
+
+	// раньше токены хранились открытыми: заменим их хэшами, действующие сессии при этом сохранятся
+	tokenType, err := s.columnType(ctx, dbName, "token")
+	if err != nil {
+		return err
+	}
+	if tokenType != "" {
+		_, err = s.db.ExecContext(ctx, `
+			ALTER TABLE `+dbName+` ADD COLUMN IF NOT EXISTS token_hash varchar;
+			UPDATE `+dbName+` SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex');
+			ALTER TABLE `+dbName+` DROP COLUMN token;
+			ALTER TABLE `+dbName+` ALTER COLUMN token_hash SET NOT NULL;
+			CREATE UNIQUE INDEX IF NOT EXISTS sessions_token_hash_idx ON `+dbName+` (token_hash);
+		`)
+		if err != nil {
+			return err
+		}
+		log.Printf("[DEBUG] tokens in `%s` replaced with hashes", dbName)
+	}
+
+	// сессиям, открытым до появления списка сессий, нужен идентификатор; у refresh-токенов это семейство
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE `+dbName+` SET id = CASE WHEN family <> '' THEN family ELSE md5(random()::text || token_hash) END
+		WHERE id = ''
+	`)
+	if err != nil {
+		return err
+	}
+
 	err = s.initSessionsStatements()
 	if err != nil {
 		return err
@@ -44,7 +120,7 @@ func (s *Storage) initSessionsStatements() error {
 
 	stmt, err = s.db.PrepareContext(
 		s.ctx,
-		"INSERT INTO "+dbName+" (user_id, token, expiry) VALUES ($1, $2, $3)",
+		"INSERT INTO "+dbName+" ("+sessionColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
 	)
 	if err != nil {
 		return err
@@ -53,7 +129,7 @@ func (s *Storage) initSessionsStatements() error {
 
 	stmt, err = s.db.PrepareContext(
 		s.ctx,
-		"SELECT * FROM "+dbName+" WHERE token=$1",
+		"SELECT "+sessionColumns+" FROM "+dbName+" WHERE token_hash=$1",
 	)
 	if err != nil {
 		return err
@@ -62,18 +138,101 @@ func (s *Storage) initSessionsStatements() error {
 
 	stmt, err = s.db.PrepareContext(
 		s.ctx,
-		"DELETE FROM "+dbName+" WHERE token=$1",
+		"UPDATE "+dbName+" SET expiry=$2, last_used_at=$3 WHERE token_hash=$1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["sessionsTouch"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"UPDATE "+dbName+" SET used=true WHERE token_hash=$1 AND NOT used",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["sessionsUse"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT "+sessionColumns+" FROM "+dbName+
+			" WHERE user_id=$1 AND NOT used AND expiry >= $2 AND (absolute_expiry IS NULL OR absolute_expiry >= $2)"+
+			" ORDER BY last_used_at DESC",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["sessionsGetUser"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM "+dbName+" WHERE user_id=$1 AND id=$2",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["sessionsDeleteUserSession"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM "+dbName+" WHERE user_id=$1 AND id<>$2",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["sessionsDeleteUser"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM "+dbName+" WHERE token_hash=$1",
 	)
 	if err != nil {
 		return err
 	}
 	s.stmts["sessionsDelete"] = stmt
 
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM "+dbName+" WHERE family=$1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["sessionsDeleteFamily"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM "+dbName+" WHERE expiry < $1 OR absolute_expiry < $1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["sessionsDeleteExpired"] = stmt
+
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT count(*) FROM "+dbName+" WHERE NOT used AND expiry >= $1 AND (absolute_expiry IS NULL OR absolute_expiry >= $1)",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["sessionsCountActive"] = stmt
+
 	return nil
 }
 
 func (s *Storage) AddSession(session *gophermart.Session) error {
-	_, err := s.stmts["sessionsInsert"].ExecContext(s.ctx, session.UserID, session.Token, session.Expiry)
+	// у refresh-токенов абсолютного срока нет
+	var absoluteExpiry sql.NullTime
+	if !session.AbsoluteExpiry.IsZero() {
+		absoluteExpiry = sql.NullTime{Time: session.AbsoluteExpiry, Valid: true}
+	}
+
+	_, err := s.stmts["sessionsInsert"].ExecContext(s.ctx,
+		session.ID, session.UserID, session.TokenHash, session.Expiry, absoluteExpiry, session.Remember, session.Family,
+		session.CreatedAt, session.LastUsedAt, session.IP, session.UserAgent, session.Used,
+	)
 	if err != nil {
 		return err
 	}
@@ -81,10 +240,8 @@ func (s *Storage) AddSession(session *gophermart.Session) error {
 	return nil
 }
 
-func (s *Storage) GetSession(token string) (*gophermart.Session, error) {
-	session := &gophermart.Session{}
-	row := s.stmts["sessionsGet"].QueryRowContext(s.ctx, token)
-	err := row.Scan(&session.UserID, &session.Token, &session.Expiry)
+func (s *Storage) GetSession(tokenHash string) (*gophermart.Session, error) {
+	session, err := scanSession(s.stmts["sessionsGet"].QueryRowContext(s.ctx, tokenHash))
 	if err == sql.ErrNoRows {
 		return nil, gophermart.ErrSessionNotFound
 	}
@@ -95,8 +252,60 @@ func (s *Storage) GetSession(token string) (*gophermart.Session, error) {
 	return session, nil
 }
 
-func (s *Storage) DeleteSession(token string) error {
-	res, err := s.stmts["sessionsDelete"].ExecContext(s.ctx, token)
+// scanSession читает сессию из строки с колонками sessionColumns
+func scanSession(row interface{ Scan(...interface{}) error }) (*gophermart.Session, error) {
+	session := &gophermart.Session{}
+	var absoluteExpiry sql.NullTime
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.TokenHash, &session.Expiry, &absoluteExpiry, &session.Remember,
+		&session.Family, &session.CreatedAt, &session.LastUsedAt, &session.IP, &session.UserAgent, &session.Used,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// у refresh-токенов абсолютного срока нет
+	session.AbsoluteExpiry = absoluteExpiry.Time
+
+	return session, nil
+}
+
+func (s *Storage) TouchSession(tokenHash string, expiry, lastUsedAt time.Time) error {
+	res, err := s.stmts["sessionsTouch"].ExecContext(s.ctx, tokenHash, expiry, lastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to touch session - %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return gophermart.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// UseSession отмечает refresh-токен использованным; ErrSessionNotFound, если токена нет или он уже использован
+func (s *Storage) UseSession(tokenHash string) error {
+	res, err := s.stmts["sessionsUse"].ExecContext(s.ctx, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to use session - %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return gophermart.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteSession(tokenHash string) error {
+	res, err := s.stmts["sessionsDelete"].ExecContext(s.ctx, tokenHash)
 	if err != nil {
 		return err
 	}
@@ -111,3 +320,86 @@ func (s *Storage) DeleteSession(token string) error {
 
 	return nil
 }
+
+// DeleteSessionFamily отзывает все refresh-токены семейства; пустое семейство у сессий по куке не удаляется
+func (s *Storage) DeleteSessionFamily(family string) error {
+	if family == "" {
+		return nil
+	}
+
+	_, err := s.stmts["sessionsDeleteFamily"].ExecContext(s.ctx, family)
+	if err != nil {
+		return fmt.Errorf("failed to delete session family - %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteExpiredSessions(now time.Time) (int64, error) {
+	res, err := s.stmts["sessionsDeleteExpired"].ExecContext(s.ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions - %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// CountActiveSessions число действующих сессий, включая неиспользованные refresh-токены
+func (s *Storage) CountActiveSessions(now time.Time) (int64, error) {
+	var active int64
+	err := s.stmts["sessionsCountActive"].QueryRowContext(s.ctx, now).Scan(&active)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active sessions - %w", err)
+	}
+
+	return active, nil
+}
+
+func (s *Storage) GetUserSessions(userID uint64, now time.Time) ([]*gophermart.Session, error) {
+	sessions := make([]*gophermart.Session, 0)
+
+	rows, err := s.stmts["sessionsGetUser"].QueryContext(s.ctx, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sessions - %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *Storage) DeleteUserSession(userID uint64, id string) error {
+	res, err := s.stmts["sessionsDeleteUserSession"].ExecContext(s.ctx, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user session - %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return gophermart.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteUserSessions(userID uint64, exceptID string) error {
+	_, err := s.stmts["sessionsDeleteUser"].ExecContext(s.ctx, userID, exceptID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user sessions - %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (s *Storage) initStatementStatements() error {
+	var err error
+	var stmt *sql.Stmt
+
+	// выписка по счёту: баланс после каждой операции считаем по всей истории,
+	// и только потом применяем фильтр по датам и пагинацию. Начисление датируется обработкой заказа,
+	// а не загрузкой: до обработки баллов на счету ещё нет. У заказов, обработанных до появления
+	// столбца processed_at, время обработки неизвестно, для них остаётся время загрузки
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		`WITH entries AS (
+			SELECT 'accrual' AS type, id::text AS reference, COALESCE(accrual, 0) AS amount,
+					COALESCE(processed_at, uploaded_at) AS processed_at
+				FROM orders WHERE user_id=$1 AND status='PROCESSED' AND accrual > 0
+			UNION ALL
+			SELECT 'withdrawal', order_id::text, -sum, processed_at
+				FROM withdrawals WHERE user_id=$1
+			UNION ALL
+			SELECT 'adjustment', id::text, CASE WHEN type='debit' THEN -sum ELSE sum END, created_at
+				FROM adjustments WHERE user_id=$1
+			UNION ALL
+			SELECT 'transfer', id::text, CASE WHEN from_user_id=$1 THEN -sum ELSE sum END, created_at
+				FROM transfers WHERE from_user_id=$1 OR to_user_id=$1
+			UNION ALL
+			SELECT 'hold', order_id::text, -sum, created_at
+				FROM holds WHERE user_id=$1 AND status='HELD'
+		), running AS (
+			SELECT type, reference, amount, processed_at,
+				(SUM(amount) OVER (ORDER BY processed_at, type, reference ROWS UNBOUNDED PRECEDING))::bigint AS balance
+			FROM entries
+		)
+		SELECT type, reference, amount, balance, processed_at FROM running
+		WHERE ($2::timestamp IS NULL OR processed_at >= $2) AND ($3::timestamp IS NULL OR processed_at < $3)
+		ORDER BY processed_at, type, reference
+		LIMIT $4 OFFSET $5`,
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["statementGetForUser"] = stmt
+
+	// выгрузка истории заказов и списаний одного или всех пользователей за период
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		`SELECT 'order' AS type, o.user_id, u.login, o.id, o.status::text, COALESCE(o.accrual, 0), o.uploaded_at AS processed_at
+			FROM orders o JOIN users u ON u.id = o.user_id
+			WHERE ($1::bigint = 0 OR o.user_id = $1)
+				AND ($2::timestamp IS NULL OR o.uploaded_at >= $2) AND ($3::timestamp IS NULL OR o.uploaded_at < $3)
+		UNION ALL
+		SELECT 'withdrawal', w.user_id, u.login, w.order_id, '', w.sum, w.processed_at
+			FROM withdrawals w JOIN users u ON u.id = w.user_id
+			WHERE ($1::bigint = 0 OR w.user_id = $1)
+				AND ($2::timestamp IS NULL OR w.processed_at >= $2) AND ($3::timestamp IS NULL OR w.processed_at < $3)
+		ORDER BY processed_at`,
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["historyExport"] = stmt
+
+	return nil
+}
+
+func (s *Storage) GetUserStatement(userID uint64, filter *gophermart.StatementFilter) ([]*gophermart.StatementEntry, error) {
+	entries := make([]*gophermart.StatementEntry, 0)
+
+	from := sql.NullTime{Time: filter.From, Valid: !filter.From.IsZero()}
+	to := sql.NullTime{Time: filter.To, Valid: !filter.To.IsZero()}
+	// LIMIT NULL в Postgres означает отсутствие ограничения
+	limit := sql.NullInt64{Int64: int64(filter.Limit), Valid: filter.Limit > 0}
+
+	rows, err := s.stmts["statementGetForUser"].QueryContext(s.ctx, userID, from, to, limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e gophermart.StatementEntry
+		err = rows.Scan(&e.Type, &e.Reference, &e.Amount, &e.Balance, &e.ProcessedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ExportHistory читает историю построчно: строки передаются в fn по мере получения из базы
+func (s *Storage) ExportHistory(filter *gophermart.HistoryFilter, fn func(*gophermart.HistoryRecord) error) error {
+	from := sql.NullTime{Time: filter.From, Valid: !filter.From.IsZero()}
+	to := sql.NullTime{Time: filter.To, Valid: !filter.To.IsZero()}
+
+	rows, err := s.stmts["historyExport"].QueryContext(s.ctx, filter.UserID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hr gophermart.HistoryRecord
+		err = rows.Scan(&hr.Type, &hr.UserID, &hr.Login, &hr.Number, &hr.Status, &hr.Sum, &hr.ProcessedAt)
+		if err != nil {
+			return err
+		}
+
+		if err = fn(&hr); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (s *Storage) initTransfers(ctx context.Context) error {
+	tableName := "transfers"
+	_, err := s.db.ExecContext(ctx, "select * from "+tableName+";")
+	if err != nil {
+		queryCreateTable := `
+			CREATE TABLE ` + tableName + ` (
+				id serial PRIMARY KEY,
+				from_user_id bigint NOT NULL,
+				to_user_id bigint NOT NULL,
+				sum bigint NOT NULL,
+				created_at timestamp NOT NULL
+			);
+		`
+
+		_, err = s.db.ExecContext(ctx, queryCreateTable)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] table `%s` created", tableName)
+	}
+
+	err = s.initTransfersStatements()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) initTransfersStatements() error {
+	tableName := "transfers"
+	var err error
+	var stmt *sql.Stmt
+
+	// запись о переводе баллов
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"INSERT INTO "+tableName+" (from_user_id, to_user_id, sum, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["transfersInsert"] = stmt
+
+	// сумма исходящих переводов пользователя начиная с заданного момента
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT COALESCE(SUM(sum), 0) FROM "+tableName+" WHERE from_user_id=$1 AND created_at >= $2",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["transfersSumOutgoing"] = stmt
+
+	// запрос входящих и исходящих переводов пользователя вместе с логинами участников
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT t.id, t.from_user_id, t.to_user_id, t.sum, t.created_at, f.login, r.login FROM "+tableName+" t"+
+			" JOIN users f ON f.id = t.from_user_id JOIN users r ON r.id = t.to_user_id"+
+			" WHERE t.from_user_id=$1 OR t.to_user_id=$1 ORDER BY t.created_at desc",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["transfersGetForUser"] = stmt
+
+	return nil
+}
+
+// lockOrder порядок блокировки балансов участников перевода: всегда по возрастанию ID,
+// чтобы встречные переводы не приводили к взаимоблокировке
+func lockOrder(fromUserID, toUserID uint64) []uint64 {
+	if fromUserID > toUserID {
+		return []uint64{toUserID, fromUserID}
+	}
+	return []uint64{fromUserID, toUserID}
+}
+
+func (s *Storage) AddTransfer(t *gophermart.Transfer, dayStart time.Time, dailyLimit uint64) (uint64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	txGetBalance := tx.StmtContext(s.ctx, s.stmts["balanceGetForUpdate"])
+	txUpdateBalance := tx.StmtContext(s.ctx, s.stmts["balanceUpdate"])
+	txSumOutgoing := tx.StmtContext(s.ctx, s.stmts["transfersSumOutgoing"])
+	txInsert := tx.StmtContext(s.ctx, s.stmts["transfersInsert"])
+
+	balances := make(map[uint64]*gophermart.Balance, 2)
+	for _, userID := range lockOrder(t.FromUserID, t.ToUserID) {
+		b := &gophermart.Balance{}
+		row := txGetBalance.QueryRowContext(s.ctx, userID)
+		err = row.Scan(&b.UserID, &b.Current, &b.Withdrawn)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("user balance not found - %w", err)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to get user balance - %w", err)
+		}
+		balances[userID] = b
+	}
+
+	from, to := balances[t.FromUserID], balances[t.ToUserID]
+	if from.Current < t.Sum {
+		return 0, gophermart.ErrNotEnoughFunds
+	}
+
+	// проверим суточный лимит: строка баланса отправителя заблокирована, параллельный перевод его не обойдёт
+	if dailyLimit > 0 {
+		var sent uint64
+		if err = txSumOutgoing.QueryRowContext(s.ctx, t.FromUserID, dayStart).Scan(&sent); err != nil {
+			return 0, fmt.Errorf("failed to get outgoing transfers sum - %w", err)
+		}
+		if sent+t.Sum > dailyLimit {
+			return 0, gophermart.ErrTransferLimitExceeded
+		}
+	}
+
+	_, err = txUpdateBalance.ExecContext(s.ctx, from.UserID, from.Current-t.Sum, from.Withdrawn)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update sender balance - %w", err)
+	}
+	_, err = txUpdateBalance.ExecContext(s.ctx, to.UserID, to.Current+t.Sum, to.Withdrawn)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update recipient balance - %w", err)
+	}
+
+	var id uint64
+	row := txInsert.QueryRowContext(s.ctx, t.FromUserID, t.ToUserID, t.Sum, t.CreatedAt)
+	if err = row.Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert transfer - %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("add transfer transaction failed - %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetUserTransfers(userID uint64) ([]*gophermart.Transfer, error) {
+	ts := make([]*gophermart.Transfer, 0)
+
+	rows, err := s.stmts["transfersGetForUser"].QueryContext(s.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t gophermart.Transfer
+		err = rows.Scan(&t.ID, &t.FromUserID, &t.ToUserID, &t.Sum, &t.CreatedAt, &t.FromLogin, &t.ToLogin)
+		if err != nil {
+			return nil, err
+		}
+
+		ts = append(ts, &t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ts, nil
+}
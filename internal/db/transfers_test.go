@@ -0,0 +1,13 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockOrder(t *testing.T) {
+	// встречные переводы блокируют балансы в одном и том же порядке
+	assert.Equal(t, []uint64{3, 7}, lockOrder(3, 7))
+	assert.Equal(t, []uint64{3, 7}, lockOrder(7, 3))
+}
@@ -81,6 +81,15 @@ func (s *Storage) initUsersStatements() error {
 	}
 	s.stmts["usersDelete"] = stmt
 
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"UPDATE "+tableName+" SET password=$2 WHERE id=$1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["usersUpdatePassword"] = stmt
+
 	return nil
 }
 
@@ -118,6 +127,10 @@ func (s *Storage) AddUser(u *gophermart.User) (uint64, error) {
 			return 0, err
 		}
 
+		if err = s.addOutboxEvent(tx, gophermart.NewUserRegistered(u)); err != nil {
+			return 0, err
+		}
+
 	} else if err != nil {
 		return 0, err
 	} else {
@@ -186,3 +199,20 @@ func (s *Storage) DeleteUser(login string) error {
 
 	return nil
 }
+
+func (s *Storage) UpdateUserPassword(userID uint64, password []byte) error {
+	res, err := s.stmts["usersUpdatePassword"].ExecContext(s.ctx, userID, password)
+	if err != nil {
+		return fmt.Errorf("failed to update user password - %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return gophermart.ErrUserNotFound
+	}
+
+	return nil
+}
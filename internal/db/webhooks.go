@@ -0,0 +1,356 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func (s *Storage) initWebhooks(ctx context.Context) error {
+	tableName := "webhooks"
+	_, err := s.db.ExecContext(ctx, "select * from "+tableName+";")
+	if err != nil {
+		// user_id пустой у глобальных подписок, events пустой у подписок на все события
+		queryCreateTable := `
+			CREATE TABLE ` + tableName + ` (
+				id serial PRIMARY KEY,
+				user_id bigint,
+				url varchar NOT NULL,
+				secret varchar NOT NULL,
+				events varchar NOT NULL,
+				created_at timestamp NOT NULL
+			);
+			CREATE TABLE webhook_deliveries (
+				id bigserial PRIMARY KEY,
+				outbox_id bigint NOT NULL,
+				webhook_id bigint NOT NULL,
+				status varchar NOT NULL,
+				attempts integer NOT NULL,
+				next_attempt_at timestamp NOT NULL
+			);
+			CREATE INDEX webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'PENDING';
+			CREATE TABLE webhook_log (
+				id bigserial PRIMARY KEY,
+				delivery_id bigint NOT NULL,
+				attempt integer NOT NULL,
+				status_code integer NOT NULL,
+				error text NOT NULL,
+				attempted_at timestamp NOT NULL
+			);
+		`
+
+		_, err = s.db.ExecContext(ctx, queryCreateTable)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] table `%s` created", tableName)
+	}
+
+	err = s.initWebhooksStatements()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) initWebhooksStatements() error {
+	tableName := "webhooks"
+	var err error
+	var stmt *sql.Stmt
+
+	// добавление подписки
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"INSERT INTO "+tableName+" (user_id, url, secret, events, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["webhooksInsert"] = stmt
+
+	// запрос подписок пользователя или глобальных подписок
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT id, url, events, created_at FROM "+tableName+" WHERE user_id IS NOT DISTINCT FROM $1 ORDER BY id",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["webhooksGetForUser"] = stmt
+
+	// проверка принадлежности подписки
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"SELECT id FROM "+tableName+" WHERE id=$1 AND user_id IS NOT DISTINCT FROM $2",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["webhooksGetOwned"] = stmt
+
+	// удаление подписки
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM "+tableName+" WHERE id=$1 AND user_id IS NOT DISTINCT FROM $2",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["webhooksDelete"] = stmt
+
+	// отмена недоставленных событий удалённой подписки
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"DELETE FROM webhook_deliveries WHERE webhook_id=$1 AND status='PENDING'",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["webhookDeliveriesDeletePending"] = stmt
+
+	// разбор новых событий очереди на доставки подходящим подпискам
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		`WITH batch AS (
+			SELECT id, event, user_id FROM outbox WHERE dispatched_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+		), deliveries AS (
+			INSERT INTO webhook_deliveries (outbox_id, webhook_id, status, attempts, next_attempt_at)
+			SELECT b.id, w.id, 'PENDING', 0, $2 FROM batch b JOIN `+tableName+` w
+				ON (w.user_id IS NULL OR w.user_id = b.user_id)
+				AND (w.events = '' OR b.event = ANY(string_to_array(w.events, ',')))
+		)
+		UPDATE outbox SET dispatched_at = $2 WHERE id IN (SELECT id FROM batch)`,
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["outboxFanOut"] = stmt
+
+	// захват готовых к отправке доставок: сдвигаем время следующей попытки на срок аренды,
+	// чтобы их не взял другой экземпляр диспетчера
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		`UPDATE webhook_deliveries d SET next_attempt_at = $2
+		FROM outbox o, `+tableName+` w
+		WHERE d.id IN (
+			SELECT id FROM webhook_deliveries WHERE status = 'PENDING' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED
+		) AND o.id = d.outbox_id AND w.id = d.webhook_id
+		RETURNING d.id, d.outbox_id, d.webhook_id, w.url, w.secret, o.event, o.payload::text, d.attempts`,
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["webhookDeliveriesClaim"] = stmt
+
+	// итог попытки доставки
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt_at = $4 WHERE id = $1",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["webhookDeliveriesUpdate"] = stmt
+
+	// запись журнала доставки
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		"INSERT INTO webhook_log (delivery_id, attempt, status_code, error, attempted_at) VALUES ($1, $2, $3, $4, $5)",
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["webhookLogInsert"] = stmt
+
+	// журнал доставок подписки, последние попытки первыми
+	stmt, err = s.db.PrepareContext(
+		s.ctx,
+		`SELECT l.delivery_id, o.event, l.attempt, l.status_code, l.error, l.attempted_at
+		FROM webhook_log l
+			JOIN webhook_deliveries d ON d.id = l.delivery_id
+			JOIN outbox o ON o.id = d.outbox_id
+		WHERE d.webhook_id = $1
+		ORDER BY l.attempted_at DESC, l.id DESC
+		LIMIT $2`,
+	)
+	if err != nil {
+		return err
+	}
+	s.stmts["webhookLogGet"] = stmt
+
+	return nil
+}
+
+// webhookOwner глобальные подписки хранятся с пустым user_id
+func webhookOwner(userID uint64) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(userID), Valid: userID != 0}
+}
+
+func (s *Storage) AddWebhook(wh *gophermart.Webhook) (uint64, error) {
+	var id uint64
+	row := s.stmts["webhooksInsert"].QueryRowContext(
+		s.ctx, webhookOwner(wh.UserID), wh.URL, wh.Secret, strings.Join(wh.Events, ","), wh.CreatedAt,
+	)
+	if err := row.Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert webhook - %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetWebhooks(userID uint64) ([]*gophermart.Webhook, error) {
+	hooks := make([]*gophermart.Webhook, 0)
+
+	rows, err := s.stmts["webhooksGetForUser"].QueryContext(s.ctx, webhookOwner(userID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		wh := gophermart.Webhook{UserID: userID}
+		var events string
+		if err = rows.Scan(&wh.ID, &wh.URL, &events, &wh.CreatedAt); err != nil {
+			return nil, err
+		}
+		if events != "" {
+			wh.Events = strings.Split(events, ",")
+		}
+
+		hooks = append(hooks, &wh)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func (s *Storage) DeleteWebhook(webhookID, userID uint64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txDelete := tx.StmtContext(s.ctx, s.stmts["webhooksDelete"])
+	txDeletePending := tx.StmtContext(s.ctx, s.stmts["webhookDeliveriesDeletePending"])
+
+	res, err := txDelete.ExecContext(s.ctx, webhookID, webhookOwner(userID))
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return gophermart.ErrWebhookNotFound
+	}
+
+	if _, err = txDeletePending.ExecContext(s.ctx, webhookID); err != nil {
+		return fmt.Errorf("failed to delete pending deliveries - %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("delete webhook transaction failed - %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetWebhookAttempts(webhookID, userID uint64, limit uint64) ([]*gophermart.WebhookAttempt, error) {
+	var id uint64
+	err := s.stmts["webhooksGetOwned"].QueryRowContext(s.ctx, webhookID, webhookOwner(userID)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, gophermart.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook - %w", err)
+	}
+
+	attempts := make([]*gophermart.WebhookAttempt, 0)
+	rows, err := s.stmts["webhookLogGet"].QueryContext(s.ctx, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a gophermart.WebhookAttempt
+		err = rows.Scan(&a.DeliveryID, &a.Event, &a.Attempt, &a.StatusCode, &a.Error, &a.AttemptedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		attempts = append(attempts, &a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+func (s *Storage) ClaimDeliveries(now, leaseUntil time.Time, limit uint64) ([]*gophermart.WebhookDelivery, error) {
+	deliveries := make([]*gophermart.WebhookDelivery, 0)
+
+	rows, err := s.stmts["webhookDeliveriesClaim"].QueryContext(s.ctx, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d gophermart.WebhookDelivery
+		var payload string
+		err = rows.Scan(&d.ID, &d.OutboxID, &d.WebhookID, &d.URL, &d.Secret, &d.Event, &payload, &d.Attempts)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+
+		deliveries = append(deliveries, &d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) RecordDeliveryAttempt(a *gophermart.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txUpdate := tx.StmtContext(s.ctx, s.stmts["webhookDeliveriesUpdate"])
+	txLog := tx.StmtContext(s.ctx, s.stmts["webhookLogInsert"])
+
+	_, err = txUpdate.ExecContext(s.ctx, a.DeliveryID, status, a.Attempt, nextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery - %w", err)
+	}
+	_, err = txLog.ExecContext(s.ctx, a.DeliveryID, a.Attempt, a.StatusCode, a.Error, a.AttemptedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery log - %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("record delivery attempt transaction failed - %w", err)
+	}
+
+	return nil
+}
@@ -85,8 +85,9 @@ func (s *Storage) AddWithdraw(withdraw *gophermart.Withdraw) error {
 	defer tx.Rollback()
 
 	txGetByID := tx.StmtContext(s.ctx, s.stmts["withdrawalsGetByID"])
+	txGetActiveHold := tx.StmtContext(s.ctx, s.stmts["holdsGetActiveByOrderID"])
 	txInsertWithdrawal := tx.StmtContext(s.ctx, s.stmts["withdrawalsInsert"])
-	txGetBalance := tx.StmtContext(s.ctx, s.stmts["balanceGet"])
+	txGetBalance := tx.StmtContext(s.ctx, s.stmts["balanceGetForUpdate"])
 	txUpdateBalance := tx.StmtContext(s.ctx, s.stmts["balanceUpdate"])
 
 	// проверим баланс
@@ -103,6 +104,16 @@ func (s *Storage) AddWithdraw(withdraw *gophermart.Withdraw) error {
 		return gophermart.ErrNotEnoughFunds
 	}
 
+	// по заказу с активным резервом списывать нельзя: иначе подтверждение резерва упрётся в уже записанное списание
+	var holdID uint64
+	err = txGetActiveHold.QueryRowContext(s.ctx, withdraw.OrderID).Scan(&holdID)
+	if err == nil {
+		return gophermart.ErrHoldAlreadyExists
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get hold - %w", err)
+	}
+
 	// средств достаточно, обновим баланс
 	current := balance.Current - withdraw.Sum
 	withdrawn := balance.Withdrawn + withdraw.Sum
@@ -119,11 +130,17 @@ func (s *Storage) AddWithdraw(withdraw *gophermart.Withdraw) error {
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// добавим новую запись в случае отсутствия результата
-			_, err = txInsertWithdrawal.ExecContext(s.ctx, withdraw.OrderID, withdraw.UserID, withdraw.Sum, time.Now())
+			withdraw.ProcessedAt = time.Now()
+			_, err = txInsertWithdrawal.ExecContext(s.ctx, withdraw.OrderID, withdraw.UserID, withdraw.Sum, withdraw.ProcessedAt)
 			if err != nil {
 				return err
 			}
 
+			// опубликуем списание подписчикам вебхуков через исходящую очередь
+			if err = s.addOutboxEvent(tx, gophermart.NewWithdrawalMade(withdraw)); err != nil {
+				return err
+			}
+
 			// всё хорошо, выполним транзакцию
 			err = tx.Commit()
 			if err != nil {
@@ -0,0 +1,83 @@
+package gophermart
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	AdjustmentCredit = "credit"
+	AdjustmentDebit  = "debit"
+)
+
+// Adjustment ручная корректировка баланса сотрудником поддержки: неизменяемая запись для аудита
+type Adjustment struct {
+	ID         uint64
+	UserID     uint64
+	Type       string
+	Sum        uint64
+	Reason     string
+	OperatorID string
+	CreatedAt  time.Time
+}
+
+type AdjustmentProxy struct {
+	ID          uint64 `json:"id,omitempty"`
+	Login       string `json:"login,omitempty"`
+	Type        string `json:"type"`
+	Sum         Amount `json:"sum"`
+	Reason      string `json:"reason"`
+	OperatorID  string `json:"operator_id,omitempty"`
+	ProcessedAt string `json:"processed_at,omitempty"`
+}
+
+type adjustments struct {
+	linker *GopherMart
+}
+
+func newAdjustments(linker *GopherMart) *adjustments {
+	return &adjustments{
+		linker: linker,
+	}
+}
+
+func (as *adjustments) Add(adjustment *Adjustment) error {
+	if adjustment.Type != AdjustmentCredit && adjustment.Type != AdjustmentDebit {
+		return ErrInvalidAdjustmentType
+	}
+	if adjustment.Sum == 0 {
+		return ErrInvalidAmount
+	}
+	adjustment.Reason = strings.TrimSpace(adjustment.Reason)
+	if adjustment.Reason == "" {
+		return ErrAdjustmentReasonRequired
+	}
+	adjustment.OperatorID = strings.TrimSpace(adjustment.OperatorID)
+	if adjustment.OperatorID == "" {
+		return ErrAdjustmentOperatorRequired
+	}
+	adjustment.CreatedAt = time.Now()
+
+	id, err := as.linker.storage.AddAdjustment(adjustment)
+	if err != nil {
+		return err
+	}
+	adjustment.ID = id
+
+	as.linker.Events.Publish(&BalanceChanged{UserID: adjustment.UserID, Reason: "adjustment"})
+
+	return nil
+}
+
+func (as *adjustments) GetAdjustments(userID uint64) ([]*Adjustment, error) {
+	ads, err := as.linker.storage.GetUserAdjustments(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ads) == 0 {
+		return nil, ErrNoContent
+	}
+
+	return ads, nil
+}
@@ -0,0 +1,103 @@
+package gophermart
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount денежная сумма в копейках: в API передаётся как десятичное число рублей
+// с не более чем двумя знаками после точки, хранится без потери точности
+type Amount uint64
+
+// ParseAmount разбирает десятичную запись суммы в рублях, например `729.98`;
+// отрицательные, нечисловые и более точные, чем до копеек, значения отвергаются
+func ParseAmount(s string) (Amount, error) {
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+		if fracPart == "" {
+			return 0, ErrInvalidAmount
+		}
+	}
+	if intPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, ErrInvalidAmount
+	}
+
+	// лишние нули в дробной части точность не увеличивают: 1.500 == 1.5
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(fracPart) > 2 {
+		return 0, ErrInvalidAmount
+	}
+	fracPart += strings.Repeat("0", 2-len(fracPart))
+
+	// суммы хранятся в колонках bigint: больше math.MaxInt64 копеек записать не получится
+	kopecks, _ := strconv.ParseUint(fracPart, 10, 64)
+	rubles, err := strconv.ParseUint(intPart, 10, 64)
+	if err != nil || rubles > (math.MaxInt64-kopecks)/100 {
+		return 0, ErrInvalidAmount
+	}
+
+	return Amount(rubles*100 + kopecks), nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a Amount) String() string {
+	rubles := strconv.FormatUint(uint64(a)/100, 10)
+	kopecks := uint64(a) % 100
+	if kopecks == 0 {
+		return rubles
+	}
+
+	return strings.TrimRight(rubles+"."+strconv.FormatUint(100+kopecks, 10)[1:], "0")
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON принимает сумму как JSON-число, так и строкой: `500.5` или `"500.5"`
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+
+	amount, err := ParseAmount(string(data))
+	if err != nil {
+		return err
+	}
+	*a = amount
+
+	return nil
+}
+
+// Set позволяет задавать сумму флагом командной строки
+func (a *Amount) Set(s string) error {
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = amount
+
+	return nil
+}
+
+// UnmarshalText позволяет задавать сумму переменной окружения
+func (a *Amount) UnmarshalText(text []byte) error {
+	return a.Set(string(text))
+}
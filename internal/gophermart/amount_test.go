@@ -0,0 +1,81 @@
+package gophermart
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Amount
+		wantErr bool
+	}{
+		{name: "integer", input: "500", want: 50000},
+		{name: "two decimals", input: "729.98", want: 72998},
+		{name: "no float truncation", input: "0.29", want: 29},
+		{name: "one decimal", input: "0.5", want: 50},
+		{name: "trailing zeros", input: "1.500", want: 150},
+		{name: "zero", input: "0", want: 0},
+		{name: "negative", input: "-1", wantErr: true},
+		{name: "NaN", input: "NaN", wantErr: true},
+		{name: "over-precise", input: "0.001", wantErr: true},
+		{name: "exponent", input: "1e2", wantErr: true},
+		{name: "empty fraction", input: "1.", wantErr: true},
+		{name: "empty integer", input: ".5", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "max bigint", input: "92233720368547758.07", want: math.MaxInt64},
+		{name: "bigint overflow", input: "92233720368547758.08", wantErr: true},
+		{name: "overflow", input: "184467440737095516.16", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAmount(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAmount)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "number", input: `{"sum":751.29}`, want: `{"sum":751.29}`},
+		{name: "string", input: `{"sum":"0.29"}`, want: `{"sum":0.29}`},
+		{name: "integer", input: `{"sum":500}`, want: `{"sum":500}`},
+		{name: "kopecks", input: `{"sum":0.05}`, want: `{"sum":0.05}`},
+		{name: "negative", input: `{"sum":-0.01}`, wantErr: true},
+		{name: "NaN", input: `{"sum":"NaN"}`, wantErr: true},
+		{name: "over-precise", input: `{"sum":10.125}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v struct {
+				Sum Amount `json:"sum"`
+			}
+			err := json.Unmarshal([]byte(tt.input), &v)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := json.Marshal(v)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
@@ -1,8 +1,6 @@
 package gophermart
 
-import (
-	"sync"
-)
+import "sync"
 
 type Balance struct {
 	UserID    uint64
@@ -11,8 +9,8 @@ type Balance struct {
 }
 
 type BalanceProxy struct {
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Current   Amount `json:"current"`
+	Withdrawn Amount `json:"withdrawn"`
 }
 
 type balances struct {
@@ -48,3 +46,10 @@ func (bs *balances) Get(userID uint64) (*Balance, error) {
 
 	return b, nil
 }
+
+// invalidate подписчик событий, изменяющих баланс: удаляет баланс пользователя из кэша
+func (bs *balances) invalidate(e Event) {
+	bs.mu.Lock()
+	delete(bs.byUserID, e.EventUserID())
+	bs.mu.Unlock()
+}
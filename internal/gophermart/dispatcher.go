@@ -0,0 +1,147 @@
+package gophermart
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	dispatcherInterval    = 5 * time.Second
+	dispatcherBatch       = 100
+	dispatcherLease       = time.Minute // на это время доставка закрепляется за одним экземпляром диспетчера
+	dispatcherTimeout     = 10 * time.Second
+	dispatcherMaxAttempts = 10
+	dispatcherBackoffBase = 10 * time.Second
+	dispatcherBackoffMax  = time.Hour
+)
+
+// Dispatcher разбирает исходящую очередь событий на доставки подписчикам
+// и отправляет их с повторными попытками, записывая каждую попытку в журнал
+type Dispatcher struct {
+	storage     Storer
+	client      *resty.Client
+	interval    time.Duration
+	maxAttempts uint32
+}
+
+func NewDispatcher(gm *GopherMart) *Dispatcher {
+	client := resty.New().SetTimeout(dispatcherTimeout)
+	if !gm.webhooksAllowPrivate {
+		client.SetTransport(webhookTransport())
+	}
+
+	return &Dispatcher{
+		storage:     gm.storage,
+		client:      client,
+		interval:    dispatcherInterval,
+		maxAttempts: dispatcherMaxAttempts,
+	}
+}
+
+// Start обрабатывает очередь, пока не будет отменён контекст
+func (d *Dispatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.dispatch(ctx); err != nil {
+			log.Println("[ERROR] Webhooks dispatch failed -", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context) error {
+	now := time.Now()
+
+	// новые события превратим в доставки для каждой подходящей подписки
+	n, err := d.storage.FanOutOutbox(dispatcherBatch, now)
+	if err != nil {
+		return fmt.Errorf("failed to fan out outbox - %w", err)
+	}
+	if n > 0 {
+		log.Printf("[DEBUG] Outbox events dispatched: %d\n", n)
+	}
+
+	deliveries, err := d.storage.ClaimDeliveries(now, now.Add(dispatcherLease), dispatcherBatch)
+	if err != nil {
+		return fmt.Errorf("failed to claim deliveries - %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return nil
+		}
+		d.deliver(ctx, delivery)
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *WebhookDelivery) {
+	attempt := &WebhookAttempt{
+		DeliveryID:  delivery.ID,
+		Event:       delivery.Event,
+		Attempt:     delivery.Attempts + 1,
+		AttemptedAt: time.Now(),
+	}
+
+	timestamp := attempt.AttemptedAt.Unix()
+	resp, err := d.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Gophermart-Event", delivery.Event).
+		SetHeader("X-Gophermart-Delivery", strconv.FormatUint(delivery.ID, 10)).
+		SetHeader("X-Gophermart-Timestamp", strconv.FormatInt(timestamp, 10)).
+		SetHeader("X-Gophermart-Signature", SignWebhook(delivery.Secret, timestamp, delivery.Payload)).
+		SetBody(delivery.Payload).
+		Post(delivery.URL)
+
+	status := DeliveryDelivered
+	if err != nil {
+		attempt.Error = err.Error()
+	} else {
+		attempt.StatusCode = resp.StatusCode()
+		if resp.StatusCode() < http.StatusOK || resp.StatusCode() >= http.StatusMultipleChoices {
+			attempt.Error = fmt.Sprintf("unexpected status code %d", resp.StatusCode())
+		}
+	}
+
+	// при неудаче откладываем следующую попытку по экспоненте, пока не исчерпаем лимит
+	nextAttemptAt := attempt.AttemptedAt
+	if attempt.Error != "" {
+		status = DeliveryPending
+		if attempt.Attempt >= d.maxAttempts {
+			status = DeliveryFailed
+		}
+		nextAttemptAt = nextAttemptAt.Add(backoff(attempt.Attempt))
+		log.Printf("[WARNING] Webhook delivery %d attempt %d failed - %s\n", delivery.ID, attempt.Attempt, attempt.Error)
+	}
+
+	if err = d.storage.RecordDeliveryAttempt(attempt, status, nextAttemptAt); err != nil {
+		log.Printf("[ERROR] Failed to record webhook delivery %d attempt - %s\n", delivery.ID, err)
+	}
+}
+
+func backoff(attempt uint32) time.Duration {
+	delay := dispatcherBackoffBase
+	for i := uint32(1); i < attempt && delay < dispatcherBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > dispatcherBackoffMax {
+		delay = dispatcherBackoffMax
+	}
+
+	return delay
+}
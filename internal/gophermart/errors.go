@@ -8,13 +8,48 @@ var (
 	ErrInvalidPair        = errors.New("invalid pair: login/password")
 	ErrUnauthorizedAccess = errors.New("unauthorized access detected: incident will be reported")
 	ErrSessionNotFound    = errors.New("session not found")
+	ErrSessionExpired     = errors.New("session has expired")
+
+	ErrJWTDisabled         = errors.New("JWT mode is disabled")
+	ErrInvalidSigningKey   = errors.New("invalid token signing key")
+	ErrInvalidToken        = errors.New("invalid access token")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	ErrTooManyLoginAttempts  = errors.New("too many failed login attempts")
+	ErrWrongPassword         = errors.New("current password is wrong")
+	ErrInvalidLogin          = errors.New("invalid login")
+	ErrInvalidPassword       = errors.New("invalid password")
+	ErrPasswordResetDisabled = errors.New("password reset is disabled")
+	ErrInvalidResetToken     = errors.New("invalid or expired password reset token")
 
 	ErrOrderAlreadyLoadedByUser        = errors.New("the order number has already been uploaded by this user")
 	ErrOrderAlreadyLoadedByAnotherUser = errors.New("the order number has already been uploaded by another user")
 	ErrOrderInvalidFormat              = errors.New("invalid order number format")
+	ErrInvalidBatchSize                = errors.New("invalid batch size: from 1 to 1000 order numbers needed")
+	ErrInvalidOrdersFilter             = errors.New("invalid orders filter: unknown status or sort order")
+	ErrInvalidCursor                   = errors.New("invalid pagination cursor")
 
 	ErrTooManyRequests = errors.New("too many requests")
 	ErrNoContent       = errors.New("no content")
+	ErrInvalidPeriod   = errors.New("invalid period: end is before start")
+
+	ErrInvalidAmount           = errors.New("invalid amount: non-negative number with at most two decimal places needed")
+	ErrNotEnoughFunds          = errors.New("not enough funds on account")
+	ErrWithdrawAlreadyRecorded = errors.New("withdraw for this order has already been recorded")
+
+	ErrTransferToSelf        = errors.New("transfer to yourself is not allowed")
+	ErrTransferLimitExceeded = errors.New("daily transfer limit exceeded")
+
+	ErrInvalidAdjustmentType      = errors.New("invalid adjustment type: credit or debit needed")
+	ErrAdjustmentReasonRequired   = errors.New("adjustment reason required")
+	ErrAdjustmentOperatorRequired = errors.New("adjustment operator ID required")
+
+	ErrInvalidWebhookURL   = errors.New("invalid webhook URL: absolute http or https URL needed")
+	ErrInvalidWebhookEvent = errors.New("unknown webhook event")
+	ErrWebhookNotFound     = errors.New("webhook not found")
 
-	ErrNotEnoughFunds = errors.New("not enough funds on account")
+	ErrHoldNotFound      = errors.New("hold not found")
+	ErrHoldAlreadyExists = errors.New("active hold for this order already exists")
+	ErrHoldNotActive     = errors.New("hold is not active")
+	ErrHoldExpired       = errors.New("hold has expired")
 )
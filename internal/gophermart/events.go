@@ -0,0 +1,229 @@
+package gophermart
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	EventUserRegistered = "user.registered"
+	EventOrderAccepted  = "order.accepted"
+	EventOrderProcessed = "order.processed"
+	EventOrderInvalid   = "order.invalid"
+	EventWithdrawalMade = "withdrawal.made"
+	// внутренние события: в исходящую очередь не пишутся и подписчикам вебхуков недоступны
+	EventOrderUpdated   = "order.updated"
+	EventBalanceChanged = "balance.changed"
+)
+
+// IsOutboxEvent события, которые хранилище пишет в исходящую очередь и на которые можно подписать вебхук
+func IsOutboxEvent(name string) bool {
+	switch name {
+	case EventUserRegistered:
+	case EventOrderAccepted:
+	case EventOrderProcessed:
+	case EventOrderInvalid:
+	case EventWithdrawalMade:
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Event доменное событие; при записи в исходящую очередь сама структура события становится полем data
+type Event interface {
+	EventName() string
+	EventUserID() uint64
+}
+
+type UserRegistered struct {
+	UserID uint64 `json:"-"`
+	Login  string `json:"login"`
+}
+
+func NewUserRegistered(u *User) *UserRegistered {
+	return &UserRegistered{UserID: u.ID, Login: u.Login}
+}
+
+func (e *UserRegistered) EventName() string   { return EventUserRegistered }
+func (e *UserRegistered) EventUserID() uint64 { return e.UserID }
+
+type OrderAccepted struct {
+	UserID uint64 `json:"-"`
+	*OrderProxy
+}
+
+func NewOrderAccepted(o *Order) *OrderAccepted {
+	return &OrderAccepted{UserID: o.UserID, OrderProxy: newOrderProxy(o)}
+}
+
+func (e *OrderAccepted) EventName() string   { return EventOrderAccepted }
+func (e *OrderAccepted) EventUserID() uint64 { return e.UserID }
+
+type OrderProcessed struct {
+	UserID uint64 `json:"-"`
+	*OrderProxy
+}
+
+func (e *OrderProcessed) EventName() string   { return EventOrderProcessed }
+func (e *OrderProcessed) EventUserID() uint64 { return e.UserID }
+
+type OrderInvalid struct {
+	UserID uint64 `json:"-"`
+	*OrderProxy
+}
+
+func (e *OrderInvalid) EventName() string   { return EventOrderInvalid }
+func (e *OrderInvalid) EventUserID() uint64 { return e.UserID }
+
+// OrderUpdated заказ перешёл в промежуточный статус
+type OrderUpdated struct {
+	UserID uint64 `json:"-"`
+	*OrderProxy
+}
+
+func (e *OrderUpdated) EventName() string   { return EventOrderUpdated }
+func (e *OrderUpdated) EventUserID() uint64 { return e.UserID }
+
+// NewOrderStatusEvent событие об изменении статуса заказа обработчиком очереди
+func NewOrderStatusEvent(o *Order) Event {
+	switch o.Status {
+	case StatusProcessed:
+		return &OrderProcessed{UserID: o.UserID, OrderProxy: newOrderProxy(o)}
+	case StatusInvalid:
+		return &OrderInvalid{UserID: o.UserID, OrderProxy: newOrderProxy(o)}
+	}
+
+	return &OrderUpdated{UserID: o.UserID, OrderProxy: newOrderProxy(o)}
+}
+
+type WithdrawalMade struct {
+	*WithdrawProxy
+}
+
+func NewWithdrawalMade(w *Withdraw) *WithdrawalMade {
+	return &WithdrawalMade{&WithdrawProxy{
+		Order:       fmt.Sprint(w.OrderID),
+		Sum:         Amount(w.Sum),
+		UserID:      w.UserID,
+		ProcessedAt: w.ProcessedAt.Format(time.RFC3339),
+	}}
+}
+
+func (e *WithdrawalMade) EventName() string   { return EventWithdrawalMade }
+func (e *WithdrawalMade) EventUserID() uint64 { return e.UserID }
+
+// BalanceChanged баланс пользователя изменился не из-за начисления или списания:
+// перевод, корректировка, резерв баллов
+type BalanceChanged struct {
+	UserID uint64 `json:"-"`
+	Reason string `json:"reason"`
+}
+
+func (e *BalanceChanged) EventName() string   { return EventBalanceChanged }
+func (e *BalanceChanged) EventUserID() uint64 { return e.UserID }
+
+// OutboxEvent событие, записанное в исходящую очередь в одной транзакции с изменением данных;
+// из очереди его забирает диспетчер вебхуков
+type OutboxEvent struct {
+	ID        uint64
+	Event     string
+	UserID    uint64
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// NewOutboxEvent собирает из доменного события запись исходящей очереди с телом,
+// которое получат подписчики вебхуков
+func NewOutboxEvent(e Event) (*OutboxEvent, error) {
+	now := time.Now()
+	payload, err := json.Marshal(struct {
+		Event     string `json:"event"`
+		UserID    uint64 `json:"user_id"`
+		CreatedAt string `json:"created_at"`
+		Data      Event  `json:"data"`
+	}{
+		Event:     e.EventName(),
+		UserID:    e.EventUserID(),
+		CreatedAt: now.Format(time.RFC3339),
+		Data:      e,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEvent{
+		Event:     e.EventName(),
+		UserID:    e.EventUserID(),
+		Payload:   payload,
+		CreatedAt: now,
+	}, nil
+}
+
+// EventHandler подписчик шины событий; вызывается синхронно после фиксации изменений в хранилище
+type EventHandler func(Event)
+
+// events шина доменных событий внутри процесса: подписчики вызываются в порядке подписки,
+// паника одного подписчика не мешает остальным
+type events struct {
+	mu       sync.RWMutex
+	handlers []eventSubscription
+}
+
+type eventSubscription struct {
+	names   map[string]struct{} // пустой набор означает подписку на все события
+	handler EventHandler
+}
+
+func newEvents() *events {
+	return &events{}
+}
+
+// Subscribe подписывает обработчик на перечисленные события, без перечисления — на все
+func (es *events) Subscribe(handler EventHandler, names ...string) {
+	sub := eventSubscription{
+		names:   make(map[string]struct{}, len(names)),
+		handler: handler,
+	}
+	for _, name := range names {
+		sub.names[name] = struct{}{}
+	}
+
+	es.mu.Lock()
+	es.handlers = append(es.handlers, sub)
+	es.mu.Unlock()
+}
+
+func (es *events) Publish(e Event) {
+	es.mu.RLock()
+	handlers := es.handlers
+	es.mu.RUnlock()
+
+	for _, sub := range handlers {
+		if len(sub.names) > 0 {
+			if _, ok := sub.names[e.EventName()]; !ok {
+				continue
+			}
+		}
+		es.call(sub.handler, e)
+	}
+}
+
+func (es *events) call(handler EventHandler, e Event) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("[ERROR] Event %s handler panicked - %v\n", e.EventName(), p)
+		}
+	}()
+
+	handler(e)
+}
+
+// logEvent подписчик, записывающий все события в журнал
+func logEvent(e Event) {
+	log.Printf("[DEBUG] Event %s for user %d\n", e.EventName(), e.EventUserID())
+}
@@ -0,0 +1,47 @@
+package gophermart
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsPublish(t *testing.T) {
+	evs := newEvents()
+
+	var all, withdrawals []string
+	evs.Subscribe(func(e Event) { all = append(all, e.EventName()) })
+	evs.Subscribe(func(e Event) { panic("broken subscriber") })
+	evs.Subscribe(func(e Event) { withdrawals = append(withdrawals, e.EventName()) }, EventWithdrawalMade)
+
+	evs.Publish(&UserRegistered{UserID: 1, Login: "gopher"})
+	evs.Publish(NewWithdrawalMade(&Withdraw{OrderID: 2377225624, UserID: 1, Sum: 500_00}))
+
+	assert.Equal(t, []string{EventUserRegistered, EventWithdrawalMade}, all)
+	assert.Equal(t, []string{EventWithdrawalMade}, withdrawals)
+}
+
+func TestNewOutboxEvent(t *testing.T) {
+	w := &Withdraw{
+		OrderID:     2377225624,
+		UserID:      7,
+		Sum:         751_50,
+		ProcessedAt: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	oe, err := NewOutboxEvent(NewWithdrawalMade(w))
+	require.NoError(t, err)
+	assert.Equal(t, EventWithdrawalMade, oe.Event)
+	assert.Equal(t, uint64(7), oe.UserID)
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(oe.Payload, &payload))
+	assert.Equal(t, EventWithdrawalMade, payload["event"])
+	assert.Equal(t, map[string]interface{}{
+		"order":        "2377225624",
+		"sum":          751.5,
+		"processed_at": "2022-01-02T03:04:05Z",
+	}, payload["data"])
+}
@@ -0,0 +1,58 @@
+package gophermart
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	HistoryOrder      = "order"
+	HistoryWithdrawal = "withdrawal"
+)
+
+// HistoryRecord запись истории пользователя для выгрузки: загруженный заказ или списание
+type HistoryRecord struct {
+	Type        string
+	UserID      uint64
+	Login       string
+	Number      uint64
+	Status      string
+	Sum         uint64
+	ProcessedAt time.Time
+}
+
+// HistoryFilter условия выгрузки истории; нулевой UserID означает всех пользователей
+type HistoryFilter struct {
+	UserID uint64
+	From   time.Time
+	To     time.Time
+}
+
+type HistoryRecordProxy struct {
+	Type        string `json:"type"`
+	Login       string `json:"login"`
+	Number      string `json:"number"`
+	Status      string `json:"status,omitempty"`
+	Sum         Amount `json:"sum"`
+	ProcessedAt string `json:"processed_at"`
+}
+
+// ExportHistory построчно передаёт историю заказов и списаний в функцию fn,
+// не загружая её в память целиком
+func (g *GopherMart) ExportHistory(filter *HistoryFilter, fn func(*HistoryRecordProxy) error) error {
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return ErrInvalidPeriod
+	}
+
+	return g.storage.ExportHistory(filter, func(hr *HistoryRecord) error {
+		return fn(&HistoryRecordProxy{
+			Type:        hr.Type,
+			Login:       hr.Login,
+			Number:      fmt.Sprint(hr.Number),
+			Status:      strings.TrimSpace(hr.Status),
+			Sum:         Amount(hr.Sum),
+			ProcessedAt: hr.ProcessedAt.Format(time.RFC3339),
+		})
+	})
+}
@@ -0,0 +1,62 @@
+package gophermart_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestExportHistory(t *testing.T) {
+	st := newStubStorage()
+	gm := gophermart.New(st)
+	start := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	collect := func(filter *gophermart.HistoryFilter) ([]*gophermart.HistoryRecordProxy, error) {
+		hrs := make([]*gophermart.HistoryRecordProxy, 0)
+		err := gm.ExportHistory(filter, func(hr *gophermart.HistoryRecordProxy) error {
+			hrs = append(hrs, hr)
+			return nil
+		})
+		return hrs, err
+	}
+
+	// конец периода раньше начала: до хранилища запрос не доходит
+	_, err := collect(&gophermart.HistoryFilter{UserID: 1, From: start, To: start.Add(-time.Hour)})
+	assert.ErrorIs(t, err, gophermart.ErrInvalidPeriod)
+	assert.Nil(t, st.historyFilter)
+
+	st.history = []*gophermart.HistoryRecord{
+		// статус хранится в колонке фиксированной длины и приходит с хвостовыми пробелами
+		{Type: gophermart.HistoryOrder, UserID: 1, Login: "gopher", Number: 12345678903,
+			Status: gophermart.StatusProcessed + "   ", Sum: 500_50, ProcessedAt: start},
+		{Type: gophermart.HistoryWithdrawal, UserID: 2, Login: "mole", Number: 2377225624,
+			Sum: 100_00, ProcessedAt: start},
+	}
+	filter := &gophermart.HistoryFilter{From: start, To: start.Add(time.Hour)}
+	hrs, err := collect(filter)
+	require.NoError(t, err)
+	assert.Equal(t, filter, st.historyFilter)
+
+	processedAt := start.Format(time.RFC3339)
+	assert.Equal(t, []*gophermart.HistoryRecordProxy{
+		{Type: gophermart.HistoryOrder, Login: "gopher", Number: "12345678903",
+			Status: gophermart.StatusProcessed, Sum: 500_50, ProcessedAt: processedAt},
+		{Type: gophermart.HistoryWithdrawal, Login: "mole", Number: "2377225624",
+			Sum: 100_00, ProcessedAt: processedAt},
+	}, hrs)
+
+	// ошибка записи прерывает выгрузку
+	errWrite := errors.New("client has gone")
+	calls := 0
+	err = gm.ExportHistory(&gophermart.HistoryFilter{}, func(*gophermart.HistoryRecordProxy) error {
+		calls++
+		return errWrite
+	})
+	assert.ErrorIs(t, err, errWrite)
+	assert.Equal(t, 1, calls)
+}
@@ -2,29 +2,197 @@ package gophermart
 
 import "time"
 
+const (
+	defaultHoldTTL = 15 * time.Minute
+	// DefaultTransferDailyLimit суточный лимит переводов по умолчанию: 10 000 рублей в копейках
+	DefaultTransferDailyLimit Amount = 10000_00
+)
+
 type GopherMart struct {
 	storage Storer
+	holdTTL time.Duration // время жизни резерва баллов до его автоматического освобождения
+	// сколько копеек пользователь может перевести другим пользователям за сутки, 0 — без ограничений
+	transferDailyLimit uint64
+	// ключи подписи токенов доступа, без ключей режим JWT выключен
+	signingKeys     []*SigningKey
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	// сроки сессий по куке: обычной и открытой с «запомнить меня»
+	sessionTTL    sessionTTL
+	rememberMeTTL sessionTTL
+	// хэширование паролей и требования к логину и паролю
+	passwordHasher    *PasswordHasher
+	credentialsPolicy CredentialsPolicy
+	// ограничения неудачных попыток входа по логину и по адресу клиента
+	loginLimits LoginLimits
+	ipLimits    LoginLimits
+	// доставка токенов сброса пароля, без неё сброс выключен
+	resetNotifier    ResetNotifier
+	passwordResetTTL time.Duration
+	// вебхуки на адреса внутренних сетей, по умолчанию запрещены
+	webhooksAllowPrivate bool
 
 	Users       *Users
 	Sessions    *sessions
+	Tokens      *tokens
+	Passwords   *passwords
+	Logins      *logins
 	Orders      *orders
 	Balances    *balances
 	Withdrawals *withdrawals
+	Holds       *holds
+	Transfers   *transfers
+	Adjustments *adjustments
+	Webhooks    *webhooks
+
+	Events        *events
+	Notifications *notifications
 }
 
-func New(st Storer) *GopherMart {
+type Option func(*GopherMart)
+
+func New(st Storer, opts ...Option) *GopherMart {
+	evs := newEvents()
 	gm := &GopherMart{
-		storage:  st,
-		Users:    newUsers(st),
-		Sessions: newSessions(st),
+		storage:            st,
+		holdTTL:            defaultHoldTTL,
+		transferDailyLimit: uint64(DefaultTransferDailyLimit),
+		accessTokenTTL:     defaultAccessTokenTTL,
+		refreshTokenTTL:    defaultRefreshTokenTTL,
+		sessionTTL:         sessionTTL{idle: defaultSessionIdleTTL, absolute: defaultSessionAbsoluteTTL},
+		rememberMeTTL:      sessionTTL{idle: defaultRememberMeIdleTTL, absolute: defaultRememberMeAbsoluteTTL},
+		passwordResetTTL:   defaultPasswordResetTTL,
+		passwordHasher:     defaultPasswordHasher(),
+		credentialsPolicy:  DefaultCredentialsPolicy(),
+		loginLimits:        DefaultLoginLimits(),
+		ipLimits:           DefaultIPLimits(),
+
+		Events: evs,
+	}
+	// применяем в цикле каждую опцию
+	for _, opt := range opts {
+		opt(gm) // *GopherMart как аргумент
 	}
+
+	gm.Users = newUsers(gm)
+	gm.Sessions = newSessions(gm)
+	gm.Tokens = newTokens(gm)
+	gm.Passwords = newPasswords(gm)
+	gm.Logins = newLogins(gm)
 	gm.Orders = newOrders(gm)
 	gm.Balances = newBalance(gm)
 	gm.Withdrawals = newWithdrawals(gm)
+	gm.Holds = newHolds(gm)
+	gm.Transfers = newTransfers(gm)
+	gm.Adjustments = newAdjustments(gm)
+	gm.Webhooks = newWebhooks(gm)
+	gm.Notifications = newNotifications(gm)
+
+	// подписчики вызываются по порядку: уведомление должно читать баланс уже после сброса кэша
+	gm.Events.Subscribe(logEvent)
+	gm.Events.Subscribe(gm.Balances.invalidate, EventOrderProcessed, EventWithdrawalMade, EventBalanceChanged)
+	gm.Events.Subscribe(gm.Notifications.notify)
 
 	return gm
 }
 
+func WithHoldTTL(ttl time.Duration) Option {
+	return func(gm *GopherMart) {
+		if ttl > 0 {
+			gm.holdTTL = ttl
+		}
+	}
+}
+
+// WithWebhooksAllowPrivate разрешает вебхуки на адреса внутренних сетей и localhost: только для разработки и тестов
+func WithWebhooksAllowPrivate(allow bool) Option {
+	return func(gm *GopherMart) {
+		gm.webhooksAllowPrivate = allow
+	}
+}
+
+func WithTransferDailyLimit(limit Amount) Option {
+	return func(gm *GopherMart) {
+		gm.transferDailyLimit = uint64(limit)
+	}
+}
+
+// WithJWT включает выдачу токенов доступа: первый ключ подписывает токены, остальные нужны для ротации;
+// нулевые сроки оставляют значения по умолчанию
+func WithJWT(keys []*SigningKey, accessTTL, refreshTTL time.Duration) Option {
+	return func(gm *GopherMart) {
+		gm.signingKeys = keys
+		if accessTTL > 0 {
+			gm.accessTokenTTL = accessTTL
+		}
+		if refreshTTL > 0 {
+			gm.refreshTokenTTL = refreshTTL
+		}
+	}
+}
+
+// WithSessionTTL задаёт сроки сессии: бездействия, который продлевается при активности, и абсолютный от момента входа;
+// нулевые сроки оставляют значения по умолчанию
+func WithSessionTTL(idle, absolute time.Duration) Option {
+	return func(gm *GopherMart) {
+		gm.sessionTTL = withTTL(gm.sessionTTL, idle, absolute)
+	}
+}
+
+// WithRememberMeTTL задаёт сроки сессий, открытых с «запомнить меня»
+func WithRememberMeTTL(idle, absolute time.Duration) Option {
+	return func(gm *GopherMart) {
+		gm.rememberMeTTL = withTTL(gm.rememberMeTTL, idle, absolute)
+	}
+}
+
+// WithPasswordHasher задаёт алгоритм и стоимость хэширования паролей; хэши, полученные с прежними
+// настройками, пересчитываются при входе пользователя
+func WithPasswordHasher(hasher *PasswordHasher) Option {
+	return func(gm *GopherMart) {
+		if hasher != nil {
+			gm.passwordHasher = hasher
+		}
+	}
+}
+
+func WithCredentialsPolicy(policy CredentialsPolicy) Option {
+	return func(gm *GopherMart) {
+		gm.credentialsPolicy = policy
+	}
+}
+
+// WithLoginLimits задаёт ограничения неудачных попыток входа по логину и по адресу клиента;
+// нулевые ограничения выключают соответствующую защиту
+func WithLoginLimits(login, ip LoginLimits) Option {
+	return func(gm *GopherMart) {
+		gm.loginLimits = login
+		gm.ipLimits = ip
+	}
+}
+
+// WithPasswordReset включает сброс пароля: токен доставляется notifier и действует ttl,
+// нулевой срок оставляет значение по умолчанию
+func WithPasswordReset(notifier ResetNotifier, ttl time.Duration) Option {
+	return func(gm *GopherMart) {
+		gm.resetNotifier = notifier
+		if ttl > 0 {
+			gm.passwordResetTTL = ttl
+		}
+	}
+}
+
+func withTTL(ttl sessionTTL, idle, absolute time.Duration) sessionTTL {
+	if idle > 0 {
+		ttl.idle = idle
+	}
+	if absolute > 0 {
+		ttl.absolute = absolute
+	}
+
+	return ttl
+}
+
 func WithTestOrders(st Storer) {
 	orders := map[uint64]*Order{
 		2486622125: {
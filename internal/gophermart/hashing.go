@@ -0,0 +1,129 @@
+package gophermart
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+var argon2idPrefix = []byte("$argon2id$")
+
+// PasswordHasher хэширует пароли выбранным алгоритмом с заданной стоимостью. Проверка пароля
+// не зависит от настроек: алгоритм и параметры записаны в самом хэше, поэтому после смены настроек
+// прежние хэши продолжают проверяться и пересчитываются при входе
+type PasswordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     argon2Params
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32 // КиБ
+	threads uint8
+}
+
+func NewBcryptHasher(cost int) (*PasswordHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost should be from %d to %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return &PasswordHasher{algorithm: HashBcrypt, bcryptCost: cost}, nil
+}
+
+// NewArgon2idHasher time — число проходов, memory — память в КиБ, threads — число потоков
+func NewArgon2idHasher(time, memory uint32, threads uint8) (*PasswordHasher, error) {
+	if time == 0 || threads == 0 || memory < 8*uint32(threads) {
+		return nil, fmt.Errorf("argon2id needs positive time and threads and at least 8 KiB of memory per thread")
+	}
+
+	return &PasswordHasher{algorithm: HashArgon2id, argon2: argon2Params{time: time, memory: memory, threads: threads}}, nil
+}
+
+func defaultPasswordHasher() *PasswordHasher {
+	return &PasswordHasher{algorithm: HashBcrypt, bcryptCost: bcrypt.DefaultCost}
+}
+
+func (h *PasswordHasher) Hash(password string) ([]byte, error) {
+	if h.algorithm == HashArgon2id {
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		return encodeArgon2id(h.argon2, salt, argon2.IDKey([]byte(password), salt, h.argon2.time, h.argon2.memory, h.argon2.threads, argon2KeyLen)), nil
+	}
+
+	return bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+}
+
+// NeedsRehash хэш получен другим алгоритмом или с другими параметрами, чем заданы сейчас
+func (h *PasswordHasher) NeedsRehash(hash []byte) bool {
+	if bytes.HasPrefix(hash, argon2idPrefix) {
+		params, _, _, err := decodeArgon2id(hash)
+		return err != nil || h.algorithm != HashArgon2id || params != h.argon2
+	}
+
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || h.algorithm != HashBcrypt || cost != h.bcryptCost
+}
+
+// verifyPassword проверяет пароль по хэшу любого поддерживаемого алгоритма
+func verifyPassword(hash []byte, password string) bool {
+	if bytes.HasPrefix(hash, argon2idPrefix) {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1
+	}
+
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// encodeArgon2id хэш в общепринятом формате `$argon2id$v=19$m=65536,t=1,p=4$<соль>$<ключ>`
+func encodeArgon2id(p argon2Params, salt, key []byte) []byte {
+	return []byte(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)))
+}
+
+func decodeArgon2id(hash []byte) (p argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads)
+	if err != nil || p.time == 0 || p.threads == 0 {
+		return p, nil, nil, fmt.Errorf("invalid argon2id parameters")
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, err
+	}
+	if len(salt) == 0 || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("argon2id hash without salt or key")
+	}
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,146 @@
+package gophermart
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/pkg/loon"
+)
+
+const (
+	HoldStatusHeld     = "HELD"
+	HoldStatusCaptured = "CAPTURED"
+	HoldStatusReleased = "RELEASED"
+	HoldStatusExpired  = "EXPIRED"
+)
+
+// Hold резервирование баллов под оплату заказа: баллы уже недоступны для списания,
+// но окончательное списание ещё не записано
+type Hold struct {
+	ID        uint64
+	UserID    uint64
+	OrderID   uint64
+	Sum       uint64
+	Status    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type HoldProxy struct {
+	ID        uint64 `json:"id"`
+	Order     string `json:"order"`
+	Sum       Amount `json:"sum"`
+	UserID    uint64 `json:"-"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type holds struct {
+	linker *GopherMart
+}
+
+func newHolds(linker *GopherMart) *holds {
+	return &holds{
+		linker: linker,
+	}
+}
+
+func (hs *holds) Add(hold *Hold) error {
+	// пустой резерв ничего не блокирует, но занимает заказ
+	if hold.Sum == 0 {
+		return ErrInvalidAmount
+	}
+
+	// Проверим номер заказа на соответствие алгоритму Луна
+	strOrderID := strconv.Itoa(int(hold.OrderID))
+	if !loon.IsValid(strOrderID) {
+		return ErrOrderInvalidFormat
+	}
+
+	now := time.Now()
+	hold.Status = HoldStatusHeld
+	hold.CreatedAt = now
+	hold.ExpiresAt = now.Add(hs.linker.holdTTL)
+
+	id, err := hs.linker.storage.AddHold(hold)
+	if err != nil {
+		return err
+	}
+	hold.ID = id
+
+	// доступный баланс уменьшился
+	hs.linker.Events.Publish(&BalanceChanged{UserID: hold.UserID, Reason: "hold"})
+
+	return nil
+}
+
+func (hs *holds) Capture(holdID, userID uint64) (*Hold, error) {
+	hold, err := hs.linker.storage.CaptureHold(holdID, userID)
+	if err != nil {
+		// просроченный резерв хранилище освободило вместо списания: баллы вернулись на счёт
+		if errors.Is(err, ErrHoldExpired) {
+			hs.linker.Events.Publish(&BalanceChanged{UserID: userID, Reason: "hold expired"})
+		}
+		return nil, err
+	}
+
+	// резерв превратился в списание
+	hs.linker.Events.Publish(NewWithdrawalMade(&Withdraw{
+		OrderID:     hold.OrderID,
+		UserID:      userID,
+		Sum:         hold.Sum,
+		ProcessedAt: time.Now(),
+	}))
+
+	return hold, nil
+}
+
+func (hs *holds) Release(holdID, userID uint64) (*Hold, error) {
+	hold, err := hs.linker.storage.ReleaseHold(holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// баллы вернулись на счёт
+	hs.linker.Events.Publish(&BalanceChanged{UserID: userID, Reason: "hold released"})
+
+	return hold, nil
+}
+
+// ReleaseExpired возвращает на счёт баллы всех просроченных резервов
+func (hs *holds) ReleaseExpired() error {
+	userIDs, err := hs.linker.storage.ReleaseExpiredHolds(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		hs.linker.Events.Publish(&BalanceChanged{UserID: userID, Reason: "hold expired"})
+	}
+	if len(userIDs) > 0 {
+		log.Printf("[DEBUG] Expired holds released for %d users\n", len(userIDs))
+	}
+
+	return nil
+}
+
+// Sweep периодически освобождает просроченные резервы, пока не будет отменён контекст
+func (hs *holds) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := hs.ReleaseExpired(); err != nil {
+				log.Println("[ERROR] Failed to release expired holds -", err)
+			}
+		}
+	}
+}
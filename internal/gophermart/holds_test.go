@@ -0,0 +1,138 @@
+package gophermart_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// recordEvents подписывается на все события и возвращает указатель на список полученных
+func recordEvents(gm *gophermart.GopherMart) *[]gophermart.Event {
+	evs := make([]gophermart.Event, 0)
+	gm.Events.Subscribe(func(e gophermart.Event) { evs = append(evs, e) })
+	return &evs
+}
+
+func TestHolds(t *testing.T) {
+	const userID = 1
+
+	st := newStubStorage()
+	st.fund(userID, 1000_00)
+	gm := gophermart.New(st)
+	evs := recordEvents(gm)
+
+	// прогреем кэш баланса: после резерва он должен сброситься
+	bl, err := gm.GetBalance(userID)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.Amount(1000_00), bl.Current)
+
+	_, err = gm.PostHold(&gophermart.HoldProxy{Order: "12345678903", Sum: 0, UserID: userID})
+	assert.ErrorIs(t, err, gophermart.ErrInvalidAmount)
+	_, err = gm.PostHold(&gophermart.HoldProxy{Order: "12345678900", Sum: 100_00, UserID: userID})
+	assert.ErrorIs(t, err, gophermart.ErrOrderInvalidFormat)
+	_, err = gm.PostHold(&gophermart.HoldProxy{Order: "12345678903", Sum: 2000_00, UserID: userID})
+	assert.ErrorIs(t, err, gophermart.ErrNotEnoughFunds)
+	require.Empty(t, *evs)
+
+	captured, err := gm.PostHold(&gophermart.HoldProxy{Order: "12345678903", Sum: 300_00, UserID: userID})
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.HoldStatusHeld, captured.Status)
+	released, err := gm.PostHold(&gophermart.HoldProxy{Order: "2377225624", Sum: 200_00, UserID: userID})
+	require.NoError(t, err)
+
+	// зарезервированные баллы недоступны для списания
+	bl, err = gm.GetBalance(userID)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.Amount(500_00), bl.Current)
+	assert.Equal(t, gophermart.Amount(0), bl.Withdrawn)
+
+	_, err = gm.CaptureHold(captured.ID, userID+1)
+	assert.ErrorIs(t, err, gophermart.ErrHoldNotFound)
+
+	hpr, err := gm.CaptureHold(captured.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.HoldStatusCaptured, hpr.Status)
+	hpr, err = gm.ReleaseHold(released.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.HoldStatusReleased, hpr.Status)
+
+	bl, err = gm.GetBalance(userID)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.Amount(700_00), bl.Current)
+	assert.Equal(t, gophermart.Amount(300_00), bl.Withdrawn)
+
+	// завершённый резерв нельзя ни списать, ни освободить повторно
+	for _, id := range []uint64{captured.ID, released.ID} {
+		_, err = gm.CaptureHold(id, userID)
+		assert.ErrorIs(t, err, gophermart.ErrHoldNotActive)
+		_, err = gm.ReleaseHold(id, userID)
+		assert.ErrorIs(t, err, gophermart.ErrHoldNotActive)
+	}
+
+	require.Len(t, *evs, 4)
+	assert.Equal(t, &gophermart.BalanceChanged{UserID: userID, Reason: "hold"}, (*evs)[0])
+	assert.Equal(t, &gophermart.BalanceChanged{UserID: userID, Reason: "hold"}, (*evs)[1])
+	withdrawal, ok := (*evs)[2].(*gophermart.WithdrawalMade)
+	require.True(t, ok)
+	assert.Equal(t, uint64(userID), withdrawal.EventUserID())
+	assert.Equal(t, &gophermart.BalanceChanged{UserID: userID, Reason: "hold released"}, (*evs)[3])
+}
+
+func TestHoldsExpired(t *testing.T) {
+	st := newStubStorage()
+	st.fund(1, 1000_00)
+	st.fund(2, 1000_00)
+	gm := gophermart.New(st)
+
+	expired, err := gm.PostHold(&gophermart.HoldProxy{Order: "12345678903", Sum: 300_00, UserID: 1})
+	require.NoError(t, err)
+	_, err = gm.PostHold(&gophermart.HoldProxy{Order: "2377225624", Sum: 100_00, UserID: 1})
+	require.NoError(t, err)
+	_, err = gm.PostHold(&gophermart.HoldProxy{Order: "79927398713", Sum: 100_00, UserID: 1})
+	require.NoError(t, err)
+	_, err = gm.PostHold(&gophermart.HoldProxy{Order: "4561261212345467", Sum: 100_00, UserID: 2})
+	require.NoError(t, err)
+	for id := uint64(1); id <= 4; id++ {
+		st.expire(id)
+	}
+
+	bl, err := gm.GetBalance(1)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.Amount(500_00), bl.Current)
+
+	// списание просроченного резерва отклоняется, но баллы возвращаются и кэш баланса сбрасывается
+	evs := recordEvents(gm)
+	_, err = gm.CaptureHold(expired.ID, 1)
+	assert.ErrorIs(t, err, gophermart.ErrHoldExpired)
+	assert.Equal(t, []gophermart.Event{&gophermart.BalanceChanged{UserID: 1, Reason: "hold expired"}}, *evs)
+
+	bl, err = gm.GetBalance(1)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.Amount(800_00), bl.Current)
+	assert.Equal(t, gophermart.Amount(0), bl.Withdrawn)
+
+	_, err = gm.CaptureHold(expired.ID, 1)
+	assert.ErrorIs(t, err, gophermart.ErrHoldNotActive)
+
+	// остальные просроченные резервы освобождает фоновая очистка: по одному событию на пользователя
+	*evs = (*evs)[:0]
+	require.NoError(t, gm.Holds.ReleaseExpired())
+	assert.ElementsMatch(t, []gophermart.Event{
+		&gophermart.BalanceChanged{UserID: 1, Reason: "hold expired"},
+		&gophermart.BalanceChanged{UserID: 2, Reason: "hold expired"},
+	}, *evs)
+
+	bl, err = gm.GetBalance(1)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.Amount(1000_00), bl.Current)
+	bl, err = gm.GetBalance(2)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.Amount(1000_00), bl.Current)
+
+	*evs = (*evs)[:0]
+	require.NoError(t, gm.Holds.ReleaseExpired())
+	assert.Empty(t, *evs)
+}
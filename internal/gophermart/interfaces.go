@@ -1,8 +1,11 @@
 package gophermart
 
+import "time"
+
 type Credentials struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	Remember bool   `json:"remember,omitempty"` // «запомнить меня»: длинные сроки сессии
 }
 
 type UseCases interface {
@@ -15,18 +18,63 @@ type Storer interface {
 	AddUser(*User) (uint64, error)
 	GetUser(interface{}) (*User, error)
 	DeleteUser(string) error
+	UpdateUserPassword(userID uint64, password []byte) error
+
+	// у пользователя действует только последний запрошенный токен сброса пароля
+	AddPasswordReset(*PasswordReset) error
+	TakePasswordReset(tokenHash string) (*PasswordReset, error)
 
+	// неудачные попытки входа: для неизвестного ключа возвращается пустой счётчик, а при добавлении
+	// неудачи счётчик обнуляется, если последняя неудача была раньше окна window
+	GetLoginFailures(key string) (*LoginFailures, error)
+	AddLoginFailure(key string, now time.Time, window time.Duration) (*LoginFailures, error)
+	ReleaseLoginFailure(key string) error
+	DeleteLoginFailures(key string) error
+	DeleteLoginFailuresBefore(t time.Time) (int64, error)
+
+	// сессии хранятся и ищутся по хэшу токена
 	AddSession(*Session) error
-	GetSession(string) (*Session, error)
-	DeleteSession(string) error
+	GetSession(tokenHash string) (*Session, error)
+	TouchSession(tokenHash string, expiry, lastUsedAt time.Time) error
+	UseSession(tokenHash string) error
+	DeleteSession(tokenHash string) error
+	DeleteSessionFamily(family string) error
+	GetUserSessions(userID uint64, now time.Time) ([]*Session, error)
+	DeleteUserSession(userID uint64, id string) error
+	DeleteUserSessions(userID uint64, exceptID string) error
+	DeleteExpiredSessions(now time.Time) (int64, error)
+	CountActiveSessions(now time.Time) (int64, error)
 
 	AddOrder(*Order) error
+	AddOrders([]*Order) ([]error, error)
 	GetOrder(orderID uint64) (*Order, error)
 	GetPullOrders(uint32) (map[uint64]*Order, error)
-	GetUserOrders(userID uint64) ([]*Order, error)
+	GetUserOrders(userID uint64, filter *OrdersFilter) ([]*Order, error)
 	UpdateOrder(*Order) error
 
 	GetBalance(userID uint64) (*Balance, error)
 	AddWithdraw(*Withdraw) error
 	GetUserWithdrawals(userID uint64) ([]*Withdraw, error)
+
+	AddTransfer(transfer *Transfer, dayStart time.Time, dailyLimit uint64) (uint64, error)
+	GetUserTransfers(userID uint64) ([]*Transfer, error)
+
+	AddAdjustment(*Adjustment) (uint64, error)
+	GetUserAdjustments(userID uint64) ([]*Adjustment, error)
+
+	GetUserStatement(userID uint64, filter *StatementFilter) ([]*StatementEntry, error)
+	ExportHistory(filter *HistoryFilter, fn func(*HistoryRecord) error) error
+
+	AddWebhook(*Webhook) (uint64, error)
+	GetWebhooks(userID uint64) ([]*Webhook, error)
+	DeleteWebhook(webhookID, userID uint64) error
+	GetWebhookAttempts(webhookID, userID uint64, limit uint64) ([]*WebhookAttempt, error)
+	FanOutOutbox(limit uint64, now time.Time) (int64, error)
+	ClaimDeliveries(now, leaseUntil time.Time, limit uint64) ([]*WebhookDelivery, error)
+	RecordDeliveryAttempt(attempt *WebhookAttempt, status string, nextAttemptAt time.Time) error
+
+	AddHold(*Hold) (uint64, error)
+	CaptureHold(holdID, userID uint64) (*Hold, error)
+	ReleaseHold(holdID, userID uint64) (*Hold, error)
+	ReleaseExpiredHolds(time.Time) ([]uint64, error)
 }
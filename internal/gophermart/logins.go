@@ -0,0 +1,262 @@
+package gophermart
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	loginKeyPrefix = "login:"
+	ipKeyPrefix    = "ip:"
+	resetKeyPrefix = "reset:"
+)
+
+// LoginFailures неудачные попытки входа по логину или адресу клиента. Счётчик обнуляется, если неудач
+// не было дольше окна, поэтому в хранилище достаточно числа неудач и времени последней
+type LoginFailures struct {
+	Key         string
+	Failures    int
+	LastFailure time.Time
+}
+
+// LoginLimits ограничения неудачных попыток входа: первые FreeAttempts неудач проходят без задержки,
+// после каждой следующей вход закрыт на BaseDelay, удваивающуюся с каждой неудачей до MaxDelay,
+// а после LockoutAttempts неудач — на LockoutDuration. Неудачи забываются через Window после последней
+type LoginLimits struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAttempts int
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// DefaultLoginLimits ограничения по логину: перебор пароля одной учётной записи
+func DefaultLoginLimits() LoginLimits {
+	return LoginLimits{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+		LockoutAttempts: 10,
+		LockoutDuration: 15 * time.Minute,
+		Window:          15 * time.Minute,
+	}
+}
+
+// DefaultIPLimits ограничения по адресу: за одним адресом может быть много пользователей, поэтому лимиты
+// мягче, но перебор логинов с одного адреса всё равно упирается в блокировку
+func DefaultIPLimits() LoginLimits {
+	return LoginLimits{
+		FreeAttempts:    10,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+		LockoutAttempts: 50,
+		LockoutDuration: 15 * time.Minute,
+		Window:          15 * time.Minute,
+	}
+}
+
+// enabled нулевые ограничения выключают защиту
+func (l LoginLimits) enabled() bool {
+	return l.LockoutAttempts > 0 || l.BaseDelay > 0
+}
+
+// blockedUntil до какого момента закрыт вход после неудач f
+func (l LoginLimits) blockedUntil(f *LoginFailures) time.Time {
+	if f == nil || f.Failures <= l.FreeAttempts {
+		return time.Time{}
+	}
+	if l.LockoutAttempts > 0 && f.Failures >= l.LockoutAttempts {
+		return f.LastFailure.Add(l.LockoutDuration)
+	}
+
+	delay := l.BaseDelay
+	for i := l.FreeAttempts + 1; i < f.Failures && delay < l.MaxDelay; i++ {
+		delay *= 2
+	}
+	if l.MaxDelay > 0 && delay > l.MaxDelay {
+		delay = l.MaxDelay
+	}
+
+	return f.LastFailure.Add(delay)
+}
+
+// ThrottledError вход временно закрыт после неудачных попыток; RetryAfter — через сколько можно повторить
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyLoginAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// logins защита входа от перебора паролей. Неудачи считаются в хранилище, а не в памяти процесса,
+// чтобы ограничения действовали на все реплики сервиса
+type logins struct {
+	storage    Storer
+	loginLimit LoginLimits
+	ipLimit    LoginLimits
+}
+
+func newLogins(gm *GopherMart) *logins {
+	return &logins{
+		storage:    gm.storage,
+		loginLimit: gm.loginLimits,
+		ipLimit:    gm.ipLimits,
+	}
+}
+
+// keys ключи счётчиков неудач попытки входа: по логину без учёта регистра и по адресу клиента
+func (ls *logins) keys(login string, client *SessionClient) map[string]LoginLimits {
+	keys := make(map[string]LoginLimits, 2)
+	if ls.loginLimit.enabled() {
+		keys[loginKeyPrefix+strings.ToLower(login)] = ls.loginLimit
+	}
+	if ls.ipLimit.enabled() && client != nil && client.IP != "" {
+		keys[ipKeyPrefix+client.IP] = ls.ipLimit
+	}
+
+	return keys
+}
+
+// Attempt резервирует попытку входа до проверки пароля: попытка сразу учитывается как неудачная, а Succeed
+// или Cancel снимают её. Возвращает ThrottledError, если вход по логину или с адреса клиента сейчас закрыт.
+// Резерв атомарен в хранилище, поэтому одновременные попытки не проходят проверку все разом: сверх
+// бесплатных попыток проходит только та, что зарезервирована сразу после прочитанного состояния
+func (ls *logins) Attempt(login string, client *SessionClient) error {
+	return ls.reserve(ls.keys(login, client))
+}
+
+// AttemptReset учитывает запрос сброса пароля для логина: запросы не снимаются, поэтому после бесплатных
+// попыток следующие запросы сброса по логину получают ThrottledError, пока не пройдёт задержка.
+// Счётчик ведётся и для несуществующих логинов, чтобы по ответу нельзя было их отличить
+func (ls *logins) AttemptReset(login string) error {
+	if !ls.loginLimit.enabled() {
+		return nil
+	}
+
+	return ls.reserve(map[string]LoginLimits{resetKeyPrefix + strings.ToLower(login): ls.loginLimit})
+}
+
+// reserve резервирует попытку по каждому ключу
+func (ls *logins) reserve(keys map[string]LoginLimits) error {
+	now := time.Now()
+
+	var retryAfter time.Duration
+	previous := make(map[string]int, len(keys))
+	for key, limits := range keys {
+		failures, err := ls.storage.GetLoginFailures(key)
+		if err != nil {
+			return err
+		}
+		if wait := limits.blockedUntil(failures).Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+		previous[key] = failures.Failures
+	}
+	if retryAfter > 0 {
+		loginsThrottled.Add(1)
+		return &ThrottledError{RetryAfter: retryAfter}
+	}
+
+	reserved := make([]string, 0, len(keys))
+	for key, limits := range keys {
+		failures, err := ls.storage.AddLoginFailure(key, now, limits.Window)
+		if err != nil {
+			ls.release(reserved)
+			return err
+		}
+		reserved = append(reserved, key)
+		if limits.LockoutAttempts > 0 && failures.Failures == limits.LockoutAttempts {
+			log.Printf("[WARNING] Login locked out for %s after %d failed attempts: %s\n",
+				limits.LockoutDuration, failures.Failures, key)
+		}
+		// между чтением и резервом попытку зарезервировал параллельный запрос: резерв остаётся неудачей,
+		// а попытка отклоняется, как если бы она пришла следом
+		if failures.Failures > limits.FreeAttempts && failures.Failures != previous[key]+1 {
+			if wait := limits.blockedUntil(failures).Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+	if retryAfter > 0 {
+		loginsThrottled.Add(1)
+		return &ThrottledError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// Succeed попытка удалась: неудачи по логину обнуляются, а по адресу снимается только эта попытка. Неудачи
+// по адресу не обнуляются: иначе перебор чужих паролей можно было бы перемежать входом в свою учётную запись
+func (ls *logins) Succeed(login string, client *SessionClient) {
+	for key := range ls.keys(login, client) {
+		if strings.HasPrefix(key, ipKeyPrefix) {
+			ls.release([]string{key})
+			continue
+		}
+		if err := ls.storage.DeleteLoginFailures(key); err != nil {
+			log.Println("[ERROR] Failed to reset login failures -", err)
+		}
+	}
+}
+
+// Cancel снимает попытку, которая не дошла до проверки пароля из-за внутренней ошибки
+func (ls *logins) Cancel(login string, client *SessionClient) {
+	reserved := make([]string, 0, 2)
+	for key := range ls.keys(login, client) {
+		reserved = append(reserved, key)
+	}
+	ls.release(reserved)
+}
+
+func (ls *logins) release(keys []string) {
+	for _, key := range keys {
+		if err := ls.storage.ReleaseLoginFailure(key); err != nil {
+			log.Println("[ERROR] Failed to release login attempt -", err)
+		}
+	}
+}
+
+// DeleteExpired удаляет счётчики, неудачи в которых уже забыты
+func (ls *logins) DeleteExpired() error {
+	window := ls.loginLimit.Window
+	if ls.ipLimit.Window > window {
+		window = ls.ipLimit.Window
+	}
+	lockout := ls.loginLimit.LockoutDuration
+	if ls.ipLimit.LockoutDuration > lockout {
+		lockout = ls.ipLimit.LockoutDuration
+	}
+	// блокировка может длиться дольше окна: удаляем счётчик, только когда истекли оба срока
+	if lockout > window {
+		window = lockout
+	}
+
+	_, err := ls.storage.DeleteLoginFailuresBefore(time.Now().Add(-window))
+	return err
+}
+
+// Sweep периодически удаляет устаревшие счётчики неудач, пока не будет отменён контекст
+func (ls *logins) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ls.DeleteExpired(); err != nil {
+				log.Println("[ERROR] Failed to delete login failures -", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package gophermart_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestLoginThrottling(t *testing.T) {
+	const delay, lockout = 50 * time.Millisecond, 300 * time.Millisecond
+	limits := gophermart.LoginLimits{
+		FreeAttempts:    2,
+		BaseDelay:       delay,
+		MaxDelay:        4 * delay,
+		LockoutAttempts: 4,
+		LockoutDuration: lockout,
+		Window:          lockout,
+	}
+	// задержка отсчитывается от начала попытки, поэтому хэш пароля должен считаться быстрее задержки
+	hasher, err := gophermart.NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+	gm := gophermart.New(basicstorage.New(),
+		gophermart.WithLoginLimits(limits, gophermart.LoginLimits{}),
+		gophermart.WithPasswordHasher(hasher),
+	)
+	creds := &gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}
+	_, err = gm.Register(creds, nil)
+	require.NoError(t, err)
+	wrong := &gophermart.Credentials{Login: "gopher", Password: "wrong"}
+
+	// первые неудачи без задержки
+	for i := 0; i < limits.FreeAttempts; i++ {
+		_, err = gm.Login(wrong, "", nil)
+		assert.ErrorIs(t, err, gophermart.ErrInvalidPair)
+	}
+	_, err = gm.Login(creds, "", nil)
+	require.NoError(t, err, "success resets failures")
+
+	for i := 0; i < limits.FreeAttempts+1; i++ {
+		_, err = gm.Login(wrong, "", nil)
+		assert.ErrorIs(t, err, gophermart.ErrInvalidPair)
+	}
+	// после лишней неудачи вход закрыт даже с верным паролем
+	_, err = gm.Login(creds, "", nil)
+	var throttled *gophermart.ThrottledError
+	require.True(t, errors.As(err, &throttled))
+	assert.ErrorIs(t, err, gophermart.ErrTooManyLoginAttempts)
+	assert.LessOrEqual(t, throttled.RetryAfter, delay)
+
+	time.Sleep(delay)
+	_, err = gm.Login(wrong, "", nil)
+	assert.ErrorIs(t, err, gophermart.ErrInvalidPair)
+
+	// блокировка
+	_, err = gm.Login(creds, "", nil)
+	require.True(t, errors.As(err, &throttled))
+	assert.Greater(t, throttled.RetryAfter, 2*delay)
+
+	time.Sleep(lockout)
+	_, err = gm.Login(creds, "", nil)
+	assert.NoError(t, err)
+}
+
+func TestLoginThrottlingByIP(t *testing.T) {
+	limits := gophermart.LoginLimits{FreeAttempts: 1, BaseDelay: time.Minute, Window: time.Minute}
+	gm := gophermart.New(basicstorage.New(), gophermart.WithLoginLimits(gophermart.LoginLimits{}, limits))
+	attacker := &gophermart.SessionClient{IP: "192.0.2.1"}
+
+	// перебор разных логинов с одного адреса
+	_, err := gm.Login(&gophermart.Credentials{Login: "gopher", Password: "wrong"}, "", attacker)
+	assert.ErrorIs(t, err, gophermart.ErrUserNotFound)
+	_, err = gm.Login(&gophermart.Credentials{Login: "rabbit", Password: "wrong"}, "", attacker)
+	assert.ErrorIs(t, err, gophermart.ErrUserNotFound)
+	_, err = gm.Login(&gophermart.Credentials{Login: "beaver", Password: "wrong"}, "", attacker)
+	assert.ErrorIs(t, err, gophermart.ErrTooManyLoginAttempts)
+
+	_, err = gm.Login(&gophermart.Credentials{Login: "beaver", Password: "wrong"}, "", &gophermart.SessionClient{IP: "192.0.2.2"})
+	assert.ErrorIs(t, err, gophermart.ErrUserNotFound, "other address is not throttled")
+}
+
+func TestLoginThrottlingConcurrent(t *testing.T) {
+	limits := gophermart.LoginLimits{FreeAttempts: 2, BaseDelay: time.Minute, Window: time.Minute}
+	gm := gophermart.New(basicstorage.New(), gophermart.WithLoginLimits(limits, gophermart.LoginLimits{}))
+	_, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+
+	// одновременные попытки не проверяют пароль все разом: сверх бесплатных проходит не больше одной
+	const attempts = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	checked := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := gm.Login(&gophermart.Credentials{Login: "gopher", Password: "wrong"}, "", nil)
+			if errors.Is(err, gophermart.ErrInvalidPair) {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+			} else {
+				assert.ErrorIs(t, err, gophermart.ErrTooManyLoginAttempts)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, checked, limits.FreeAttempts+1)
+
+	_, err = gm.Login(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, "", nil)
+	assert.ErrorIs(t, err, gophermart.ErrTooManyLoginAttempts)
+}
+
+func TestLoginUnknownUserTiming(t *testing.T) {
+	gm := gophermart.New(basicstorage.New())
+	_, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+
+	timeLogin := func(login string) time.Duration {
+		start := time.Now()
+		_, err := gm.Login(&gophermart.Credentials{Login: login, Password: "wrong"}, "", nil)
+		assert.Error(t, err)
+		return time.Since(start)
+	}
+	// хэш-пустышка считается при первом входе под несуществующим логином
+	timeLogin("ghost")
+
+	// неудачных попыток меньше бесплатных, задержки входа не мешают замеру
+	wrongPassword := timeLogin("gopher")
+	if d := timeLogin("gopher"); d < wrongPassword {
+		wrongPassword = d
+	}
+	unknownLogin := timeLogin("mole")
+	if d := timeLogin("rabbit"); d < unknownLogin {
+		unknownLogin = d
+	}
+
+	// по времени ответа несуществующий логин не отличить от неверного пароля: оба проверяют хэш
+	assert.Greater(t, unknownLogin, wrongPassword/2, "unknown login %s, wrong password %s", unknownLogin, wrongPassword)
+}
@@ -0,0 +1,18 @@
+package gophermart
+
+import "expvar"
+
+// Metrics счётчики сервиса, отдаются административным API
+var Metrics = expvar.NewMap("gophermart")
+
+var (
+	sessionsActive  = new(expvar.Int) // действующие сессии на момент последней очистки
+	sessionsExpired = new(expvar.Int) // удалённые очисткой истёкшие сессии
+	loginsThrottled = new(expvar.Int) // попытки входа, отклонённые защитой от перебора
+)
+
+func init() {
+	Metrics.Set("sessions_active", sessionsActive)
+	Metrics.Set("sessions_expired_total", sessionsExpired)
+	Metrics.Set("logins_throttled_total", loginsThrottled)
+}
@@ -0,0 +1,113 @@
+package gophermart
+
+import (
+	"log"
+	"sync"
+)
+
+const (
+	NotificationOrder   = "order"
+	NotificationBalance = "balance"
+
+	notificationsBuffer = 16 // сколько уведомлений подписчик может не забрать, прежде чем они начнут теряться
+)
+
+// Notification уведомление пользователю об изменении заказа или баланса
+type Notification struct {
+	Type    string        `json:"type"`
+	Order   *OrderProxy   `json:"order,omitempty"`
+	Balance *BalanceProxy `json:"balance,omitempty"`
+}
+
+// notifications рассылает уведомления подписчикам: у одного пользователя может быть несколько подписок,
+// например, открытых вкладок браузера
+type notifications struct {
+	linker   *GopherMart
+	mu       sync.RWMutex
+	byUserID map[uint64]map[chan *Notification]struct{}
+}
+
+func newNotifications(linker *GopherMart) *notifications {
+	return &notifications{
+		linker:   linker,
+		byUserID: make(map[uint64]map[chan *Notification]struct{}),
+	}
+}
+
+// Subscribe подписывает на уведомления пользователя; возвращённую функцию отписки нужно обязательно вызвать
+func (ns *notifications) Subscribe(userID uint64) (<-chan *Notification, func()) {
+	ch := make(chan *Notification, notificationsBuffer)
+
+	ns.mu.Lock()
+	if ns.byUserID[userID] == nil {
+		ns.byUserID[userID] = make(map[chan *Notification]struct{})
+	}
+	ns.byUserID[userID][ch] = struct{}{}
+	ns.mu.Unlock()
+
+	unsubscribe := func() {
+		ns.mu.Lock()
+		delete(ns.byUserID[userID], ch)
+		if len(ns.byUserID[userID]) == 0 {
+			delete(ns.byUserID, userID)
+		}
+		ns.mu.Unlock()
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish отправляет уведомление всем подпискам пользователя, не блокируясь на медленных подписчиках
+func (ns *notifications) Publish(userID uint64, n *Notification) {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	for ch := range ns.byUserID[userID] {
+		select {
+		case ch <- n:
+		default:
+			log.Printf("[WARNING] Notification for user %d dropped: subscriber is too slow\n", userID)
+		}
+	}
+}
+
+// hasSubscribers позволяет не готовить уведомления, которые некому отправить
+func (ns *notifications) hasSubscribers(userID uint64) bool {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	return len(ns.byUserID[userID]) > 0
+}
+
+// notify подписчик шины событий: пересылает пользователю изменения заказов и баланса
+func (ns *notifications) notify(e Event) {
+	userID := e.EventUserID()
+	if !ns.hasSubscribers(userID) {
+		return
+	}
+
+	switch e := e.(type) {
+	case *OrderAccepted:
+		ns.Publish(userID, &Notification{Type: NotificationOrder, Order: e.OrderProxy})
+		return
+	case *OrderUpdated:
+		ns.Publish(userID, &Notification{Type: NotificationOrder, Order: e.OrderProxy})
+		return
+	case *OrderInvalid:
+		ns.Publish(userID, &Notification{Type: NotificationOrder, Order: e.OrderProxy})
+		return
+	case *OrderProcessed:
+		// начисление меняет ещё и баланс
+		ns.Publish(userID, &Notification{Type: NotificationOrder, Order: e.OrderProxy})
+	case *WithdrawalMade, *BalanceChanged:
+	default:
+		return
+	}
+
+	blPr, err := ns.linker.GetBalance(userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get balance for notification of user %d - %s\n", userID, err)
+		return
+	}
+	ns.Publish(userID, &Notification{Type: NotificationBalance, Balance: blPr})
+}
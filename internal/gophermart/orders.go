@@ -1,8 +1,11 @@
 package gophermart
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -38,16 +41,95 @@ type Order struct {
 }
 
 type OrderProxy struct {
-	Number     string  `json:"number"`
-	Status     string  `json:"status"`
-	Accrual    float64 `json:"accrual,omitempty"`
-	UploadedAt string  `json:"uploaded_at"`
+	Number     string `json:"number"`
+	Status     string `json:"status"`
+	Accrual    Amount `json:"accrual,omitempty"`
+	UploadedAt string `json:"uploaded_at"`
 }
 
 func (op *OrderProxy) String() string {
 	return fmt.Sprintf("%#v\n", op)
 }
 
+func newOrderProxy(o *Order) *OrderProxy {
+	//{ need
+	//	"number": "346436439",
+	//	"status": "INVALID",
+	//	"uploaded_at": "2020-12-09T16:09:53+03:00"
+	//}
+	layout := "2006-01-02T15:04:05-07:00"
+
+	return &OrderProxy{
+		Number:     fmt.Sprint(o.ID),
+		Status:     strings.TrimSpace(o.Status),
+		Accrual:    Amount(o.Accrual),
+		UploadedAt: o.UploadedAt.Format(layout),
+	}
+}
+
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+
+	maxOrdersLimit = 1000
+	maxBatchSize   = 1000
+
+	BatchAccepted              = "accepted"
+	BatchAlreadyUploaded       = "already_uploaded"
+	BatchUploadedByAnotherUser = "uploaded_by_another_user"
+	BatchInvalidFormat         = "invalid_format"
+)
+
+// OrderBatchResult итог загрузки одного номера из пакета
+type OrderBatchResult struct {
+	Number string `json:"number"`
+	Status string `json:"status"`
+}
+
+// OrdersCursor позиция в списке заказов: последний выданный заказ страницы
+type OrdersCursor struct {
+	UploadedAt time.Time
+	ID         uint64
+}
+
+// Encode упаковывает курсор в непрозрачную для клиента строку
+func (c *OrdersCursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.UploadedAt.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeOrdersCursor(s string) (*OrdersCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	nsec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &OrdersCursor{UploadedAt: time.Unix(0, nsec).UTC(), ID: id}, nil
+}
+
+// OrdersFilter условия выборки заказов пользователя; нулевые значения означают отсутствие ограничения
+type OrdersFilter struct {
+	Statuses []string
+	From     time.Time // начало периода загрузки, включительно
+	To       time.Time // конец периода загрузки, не включительно
+	Sort     string
+	Limit    uint64
+	Cursor   *OrdersCursor
+}
+
 type orders struct {
 	linker *GopherMart
 	mu     sync.RWMutex
@@ -93,9 +175,81 @@ func (os *orders) Add(orderID, userID uint64) error {
 	os.byID[orderID] = order
 	os.mu.Unlock()
 
+	os.linker.Events.Publish(NewOrderAccepted(order))
+
 	return nil
 }
 
+// AddBatch загружает пакет номеров заказов: неверные номера отсеиваются сразу,
+// остальные записываются в хранилище одной транзакцией
+func (os *orders) AddBatch(numbers []string, userID uint64) ([]*OrderBatchResult, error) {
+	if len(numbers) == 0 || len(numbers) > maxBatchSize {
+		return nil, ErrInvalidBatchSize
+	}
+
+	results := make([]*OrderBatchResult, len(numbers))
+	valid := make([]*Order, 0, len(numbers))
+	validIdx := make([]int, 0, len(numbers))
+	now := time.Now()
+
+	for i, number := range numbers {
+		results[i] = &OrderBatchResult{Number: number, Status: BatchInvalidFormat}
+
+		// Проверим номер заказа на соответствие алгоритму Луна
+		orderID, err := strconv.ParseUint(number, 10, 64)
+		if err != nil || !loon.IsValid(number) {
+			continue
+		}
+
+		valid = append(valid, &Order{
+			ID:         orderID,
+			UserID:     userID,
+			Status:     StatusNew,
+			UploadedAt: now,
+		})
+		validIdx = append(validIdx, i)
+	}
+
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	errs, err := os.linker.storage.AddOrders(valid)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, order := range valid {
+		result := results[validIdx[k]]
+		switch {
+		case errs[k] == nil:
+			result.Status = BatchAccepted
+			// закэшируем принятый заказ
+			os.mu.Lock()
+			os.byID[order.ID] = order
+			os.mu.Unlock()
+			os.linker.Events.Publish(NewOrderAccepted(order))
+		case errors.Is(errs[k], ErrOrderAlreadyLoadedByUser):
+			result.Status = BatchAlreadyUploaded
+		case errors.Is(errs[k], ErrOrderAlreadyLoadedByAnotherUser):
+			result.Status = BatchUploadedByAnotherUser
+		default:
+			return nil, errs[k]
+		}
+	}
+
+	return results, nil
+}
+
+// updated обновляет кэш после изменения заказа обработчиком очереди и публикует событие о новом статусе
+func (os *orders) updated(order *Order) {
+	os.mu.Lock()
+	os.byID[order.ID] = order
+	os.mu.Unlock()
+
+	os.linker.Events.Publish(NewOrderStatusEvent(order))
+}
+
 func (os *orders) Get(orderID uint64) (*Order, error) {
 	var err error
 
@@ -117,11 +271,45 @@ func (os *orders) Get(orderID uint64) (*Order, error) {
 	return o, nil
 }
 
-func (os *orders) GetUserOrders(userID uint64) ([]*Order, error) {
-	ors, err := os.linker.storage.GetUserOrders(userID)
+// GetUserOrders возвращает страницу заказов пользователя и курсор следующей страницы,
+// пустой курсор означает, что страница последняя
+func (os *orders) GetUserOrders(userID uint64, filter *OrdersFilter) ([]*Order, *OrdersCursor, error) {
+	for _, status := range filter.Statuses {
+		if !isValidStatus(status) {
+			return nil, nil, ErrInvalidOrdersFilter
+		}
+	}
+	switch filter.Sort {
+	case "":
+		filter.Sort = SortAsc
+	case SortAsc, SortDesc:
+	default:
+		return nil, nil, ErrInvalidOrdersFilter
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, nil, ErrInvalidPeriod
+	}
+	if filter.Limit > maxOrdersLimit {
+		filter.Limit = maxOrdersLimit
+	}
+
+	// запросим на один заказ больше, чтобы узнать, есть ли следующая страница
+	limit := filter.Limit
+	if limit > 0 {
+		filter.Limit++
+	}
+	ors, err := os.linker.storage.GetUserOrders(userID, filter)
+	filter.Limit = limit
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if limit == 0 || uint64(len(ors)) <= limit {
+		return ors, nil, nil
 	}
 
-	return ors, nil
+	ors = ors[:limit]
+	last := ors[len(ors)-1]
+
+	return ors, &OrdersCursor{UploadedAt: last.UploadedAt, ID: last.ID}, nil
 }
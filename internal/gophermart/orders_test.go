@@ -0,0 +1,145 @@
+package gophermart_test
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestOrdersCursor(t *testing.T) {
+	cursor := &gophermart.OrdersCursor{UploadedAt: time.Date(2022, 1, 2, 3, 4, 5, 6, time.UTC), ID: 12345678903}
+	decoded, err := gophermart.DecodeOrdersCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+
+	for _, s := range []string{
+		"",
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("1641092645")),
+		base64.RawURLEncoding.EncodeToString([]byte("1641092645:1:2")),
+		base64.RawURLEncoding.EncodeToString([]byte("time:12345678903")),
+		base64.RawURLEncoding.EncodeToString([]byte("1641092645:-1")),
+	} {
+		_, err = gophermart.DecodeOrdersCursor(s)
+		assert.ErrorIs(t, err, gophermart.ErrInvalidCursor, s)
+	}
+}
+
+func TestGetOrders(t *testing.T) {
+	const userID = 1
+
+	st := basicstorage.New()
+	gm := gophermart.New(st)
+	start := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i, id := range []uint64{12345678903, 2377225624, 79927398713, 4561261212345467} {
+		status := gophermart.StatusNew
+		if i%2 == 1 {
+			status = gophermart.StatusProcessed
+		}
+		require.NoError(t, st.UpdateOrder(&gophermart.Order{
+			ID: id, UserID: userID, Status: status, UploadedAt: start.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	numbers := func(ors []*gophermart.OrderProxy) []string {
+		ns := make([]string, 0, len(ors))
+		for _, o := range ors {
+			ns = append(ns, o.Number)
+		}
+		return ns
+	}
+
+	tests := []struct {
+		name    string
+		filter  *gophermart.OrdersFilter
+		want    []string
+		wantErr error
+	}{
+		{name: "all", filter: &gophermart.OrdersFilter{},
+			want: []string{"12345678903", "2377225624", "79927398713", "4561261212345467"}},
+		{name: "desc", filter: &gophermart.OrdersFilter{Sort: gophermart.SortDesc},
+			want: []string{"4561261212345467", "79927398713", "2377225624", "12345678903"}},
+		{name: "status", filter: &gophermart.OrdersFilter{Statuses: []string{gophermart.StatusProcessed}},
+			want: []string{"2377225624", "4561261212345467"}},
+		{name: "period", filter: &gophermart.OrdersFilter{From: start.Add(time.Hour), To: start.Add(3 * time.Hour)},
+			want: []string{"2377225624", "79927398713"}},
+		{name: "unknown status", filter: &gophermart.OrdersFilter{Statuses: []string{"DONE"}},
+			wantErr: gophermart.ErrInvalidOrdersFilter},
+		{name: "unknown sort", filter: &gophermart.OrdersFilter{Sort: "random"},
+			wantErr: gophermart.ErrInvalidOrdersFilter},
+		{name: "reversed period", filter: &gophermart.OrdersFilter{From: start.Add(time.Hour), To: start},
+			wantErr: gophermart.ErrInvalidPeriod},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ors, next, err := gm.GetOrders(userID, tt.filter)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, numbers(ors))
+			assert.Empty(t, next)
+		})
+	}
+
+	// постранично в обратном порядке: курсор последней страницы пустой
+	var got []string
+	filter := &gophermart.OrdersFilter{Sort: gophermart.SortDesc, Limit: 3}
+	for page := 0; ; page++ {
+		require.Less(t, page, 3, "pagination should stop")
+		ors, next, err := gm.GetOrders(userID, filter)
+		require.NoError(t, err)
+		got = append(got, numbers(ors)...)
+		if next == "" {
+			break
+		}
+		filter.Cursor, err = gophermart.DecodeOrdersCursor(next)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"4561261212345467", "79927398713", "2377225624", "12345678903"}, got)
+}
+
+func TestPostOrdersBatch(t *testing.T) {
+	st := basicstorage.New()
+	gm := gophermart.New(st)
+	evs := recordEvents(gm)
+	require.NoError(t, st.UpdateOrder(&gophermart.Order{ID: 2377225624, UserID: 1, Status: gophermart.StatusNew}))
+	require.NoError(t, st.UpdateOrder(&gophermart.Order{ID: 79927398713, UserID: 2, Status: gophermart.StatusNew}))
+
+	_, err := gm.PostOrdersBatch(nil, 1)
+	assert.ErrorIs(t, err, gophermart.ErrInvalidBatchSize)
+	_, err = gm.PostOrdersBatch(make([]string, 1001), 1)
+	assert.ErrorIs(t, err, gophermart.ErrInvalidBatchSize)
+
+	// результат по каждому номеру в порядке пакета, неверные номера не мешают остальным
+	results, err := gm.PostOrdersBatch([]string{
+		"12345678903", "12345678900", "2377225624", "79927398713", "", "-1", "12345678903",
+	}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []*gophermart.OrderBatchResult{
+		{Number: "12345678903", Status: gophermart.BatchAccepted},
+		{Number: "12345678900", Status: gophermart.BatchInvalidFormat},
+		{Number: "2377225624", Status: gophermart.BatchAlreadyUploaded},
+		{Number: "79927398713", Status: gophermart.BatchUploadedByAnotherUser},
+		{Number: "", Status: gophermart.BatchInvalidFormat},
+		{Number: "-1", Status: gophermart.BatchInvalidFormat},
+		{Number: "12345678903", Status: gophermart.BatchAlreadyUploaded},
+	}, results)
+
+	// событие публикуется только по принятому заказу
+	require.Len(t, *evs, 1)
+	assert.Equal(t, gophermart.EventOrderAccepted, (*evs)[0].EventName())
+	assert.Equal(t, uint64(1), (*evs)[0].EventUserID())
+
+	// пакет из одних неверных номеров хранилище не затрагивает
+	results, err = gm.PostOrdersBatch([]string{"abc"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []*gophermart.OrderBatchResult{{Number: "abc", Status: gophermart.BatchInvalidFormat}}, results)
+}
@@ -0,0 +1,182 @@
+package gophermart
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultPasswordResetTTL = time.Hour
+
+// PasswordReset запрос на сброс пароля; токен знает только получатель уведомления, хранится его хэш
+type PasswordReset struct {
+	UserID    uint64    `json:"user_id"`
+	Login     string    `json:"login"`
+	Token     string    `json:"token"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ResetNotifier доставляет пользователю токен сброса пароля: письмом, сообщением и т.п.
+type ResetNotifier interface {
+	NotifyPasswordReset(*PasswordReset) error
+}
+
+// LogResetNotifier пишет токен сброса пароля в лог, годится только для локальной разработки
+type LogResetNotifier struct{}
+
+func (LogResetNotifier) NotifyPasswordReset(reset *PasswordReset) error {
+	log.Printf("[INFO] Password reset token for user `%s`: %s, expires at %s\n",
+		reset.Login, reset.Token, reset.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// FileResetNotifier дописывает запросы на сброс пароля в файл по одному JSON на строку
+type FileResetNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileResetNotifier(path string) *FileResetNotifier {
+	return &FileResetNotifier{path: path}
+}
+
+func (n *FileResetNotifier) NotifyPasswordReset(reset *PasswordReset) error {
+	line, err := json.Marshal(reset)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open password reset file - %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// passwords смена и сброс пароля. Сброс выключен, пока не задан способ доставки токена
+type passwords struct {
+	linker   *GopherMart
+	notifier ResetNotifier
+	resetTTL time.Duration
+}
+
+func newPasswords(gm *GopherMart) *passwords {
+	return &passwords{
+		linker:   gm,
+		notifier: gm.resetNotifier,
+		resetTTL: gm.passwordResetTTL,
+	}
+}
+
+// Change меняет пароль, проверив текущий, и завершает остальные сессии пользователя. Проверка текущего пароля —
+// такая же попытка входа, поэтому укравший сессию не может перебирать пароль в обход ограничений входа
+func (p *passwords) Change(userID uint64, oldPassword, newPassword, currentSessionID string) error {
+	user, err := p.linker.Users.Get(userID)
+	if err != nil {
+		return err
+	}
+	if err = p.linker.Logins.Attempt(user.Login, nil); err != nil {
+		return err
+	}
+	if !user.CheckPassword(oldPassword) {
+		return ErrWrongPassword
+	}
+	p.linker.Logins.Succeed(user.Login, nil)
+	// пароль меняют, когда он мог утечь: прежний пароль в качестве нового ничего не защитит
+	if newPassword == oldPassword {
+		return fmt.Errorf("%w: new password should differ from the current one", ErrInvalidPassword)
+	}
+	if err = p.linker.Users.ValidatePassword(user.Login, newPassword); err != nil {
+		return err
+	}
+
+	if err = p.linker.Users.UpdatePassword(userID, newPassword); err != nil {
+		return err
+	}
+
+	return p.linker.Sessions.DeleteUserSessions(userID, currentSessionID)
+}
+
+// RequestReset выдаёт токен сброса пароля и отправляет его пользователю; прежний токен пользователя
+// перестаёт действовать. О неизвестном логине не сообщаем, чтобы по ответу нельзя было перебирать логины.
+// Число запросов по логину ограничено, чтобы ими нельзя было завалить пользователя уведомлениями
+func (p *passwords) RequestReset(login string) error {
+	if p.notifier == nil {
+		return ErrPasswordResetDisabled
+	}
+	if err := p.linker.Logins.AttemptReset(login); err != nil {
+		return err
+	}
+
+	user, err := p.linker.Users.Get(login)
+	if err != nil {
+		log.Printf("[DEBUG] Password reset requested for unknown login `%s` - %s\n", login, err)
+		return nil
+	}
+
+	token, err := newSessionToken()
+	if err != nil {
+		return err
+	}
+	reset := &PasswordReset{
+		UserID:    user.ID,
+		Login:     user.Login,
+		Token:     token,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(p.resetTTL),
+	}
+	if err = p.linker.storage.AddPasswordReset(reset); err != nil {
+		return err
+	}
+
+	// ошибку доставки только логируем: ответ не должен отличаться от ответа на неизвестный логин
+	if err = p.notifier.NotifyPasswordReset(reset); err != nil {
+		log.Println("[ERROR] Failed to deliver password reset token -", err)
+	}
+
+	return nil
+}
+
+// Reset задаёт новый пароль по одноразовому токену сброса и завершает все сессии пользователя
+func (p *passwords) Reset(token, newPassword string) error {
+	if p.notifier == nil {
+		return ErrPasswordResetDisabled
+	}
+
+	// токен погашен, даже если срок истёк: повторно его не предъявить
+	reset, err := p.linker.storage.TakePasswordReset(hashToken(token))
+	if err != nil {
+		return err
+	}
+	if reset.ExpiresAt.Before(time.Now()) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := p.linker.Users.Get(reset.UserID)
+	if err != nil {
+		return err
+	}
+	if err = p.linker.Users.ValidatePassword(user.Login, newPassword); err != nil {
+		// неподходящий пароль не должен сжигать токен: вернём его, чтобы пользователь попробовал другой пароль
+		if addErr := p.linker.storage.AddPasswordReset(reset); addErr != nil {
+			log.Println("[ERROR] Failed to restore password reset token -", addErr)
+		}
+		return err
+	}
+
+	if err = p.linker.Users.UpdatePassword(reset.UserID, newPassword); err != nil {
+		return err
+	}
+
+	return p.linker.Sessions.DeleteUserSessions(reset.UserID, "")
+}
@@ -0,0 +1,178 @@
+package gophermart_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+type recordingNotifier struct {
+	resets []*gophermart.PasswordReset
+}
+
+func (n *recordingNotifier) NotifyPasswordReset(reset *gophermart.PasswordReset) error {
+	n.resets = append(n.resets, reset)
+	return nil
+}
+
+func TestChangePassword(t *testing.T) {
+	gm := gophermart.New(basicstorage.New())
+	creds := &gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}
+	current, err := gm.Register(creds, nil)
+	require.NoError(t, err)
+	other, err := gm.Login(creds, "", nil)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, gm.ChangePassword(current.UserID, "wrong", "N3wPassw0rd", current.ID), gophermart.ErrWrongPassword)
+	assert.ErrorIs(t, gm.ChangePassword(current.UserID, creds.Password, "", current.ID), gophermart.ErrInvalidPassword)
+	assert.ErrorIs(t, gm.ChangePassword(current.UserID, creds.Password, creds.Password, current.ID), gophermart.ErrInvalidPassword)
+	require.NoError(t, gm.ChangePassword(current.UserID, creds.Password, "N3wPassw0rd", current.ID))
+
+	// сессия, сменившая пароль, остаётся, остальные завершены
+	_, _, err = gm.Authenticate(current.Token)
+	assert.NoError(t, err)
+	_, _, err = gm.Authenticate(other.Token)
+	assert.Error(t, err)
+
+	_, err = gm.Login(creds, "", nil)
+	assert.ErrorIs(t, err, gophermart.ErrInvalidPair)
+	_, err = gm.Login(&gophermart.Credentials{Login: "gopher", Password: "N3wPassw0rd"}, "", nil)
+	assert.NoError(t, err)
+}
+
+func TestPasswordThrottling(t *testing.T) {
+	limits := gophermart.LoginLimits{FreeAttempts: 2, BaseDelay: time.Minute, Window: time.Minute}
+	notifier := &recordingNotifier{}
+	gm := gophermart.New(basicstorage.New(),
+		gophermart.WithLoginLimits(limits, gophermart.LoginLimits{}),
+		gophermart.WithPasswordReset(notifier, time.Hour),
+	)
+	creds := &gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}
+	session, err := gm.Register(creds, nil)
+	require.NoError(t, err)
+
+	// перебор текущего пароля через смену пароля упирается в те же ограничения, что и вход
+	for i := 0; i < limits.FreeAttempts+1; i++ {
+		assert.ErrorIs(t, gm.ChangePassword(session.UserID, "wrong", "N3wPassw0rd", session.ID), gophermart.ErrWrongPassword)
+	}
+	assert.ErrorIs(t, gm.ChangePassword(session.UserID, creds.Password, "N3wPassw0rd", session.ID), gophermart.ErrTooManyLoginAttempts)
+	_, err = gm.Login(creds, "", nil)
+	assert.ErrorIs(t, err, gophermart.ErrTooManyLoginAttempts)
+
+	// запросы сброса ограничены по логину, в том числе по несуществующему
+	for _, login := range []string{"gopher", "rabbit"} {
+		for i := 0; i < limits.FreeAttempts+1; i++ {
+			require.NoError(t, gm.RequestPasswordReset(login))
+		}
+		assert.ErrorIs(t, gm.RequestPasswordReset(login), gophermart.ErrTooManyLoginAttempts)
+	}
+	assert.Len(t, notifier.resets, limits.FreeAttempts+1)
+}
+
+func TestResetPassword(t *testing.T) {
+	assert.ErrorIs(t, gophermart.New(basicstorage.New()).RequestPasswordReset("gopher"), gophermart.ErrPasswordResetDisabled)
+
+	const ttl = 100 * time.Millisecond
+	notifier := &recordingNotifier{}
+	gm := gophermart.New(basicstorage.New(), gophermart.WithPasswordReset(notifier, ttl))
+	creds := &gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}
+	session, err := gm.Register(creds, nil)
+	require.NoError(t, err)
+
+	// о неизвестном логине не сообщаем
+	require.NoError(t, gm.RequestPasswordReset("rabbit"))
+	assert.Empty(t, notifier.resets)
+
+	// новый запрос отменяет прежний токен
+	require.NoError(t, gm.RequestPasswordReset("gopher"))
+	require.NoError(t, gm.RequestPasswordReset("gopher"))
+	require.Len(t, notifier.resets, 2)
+	first, second := notifier.resets[0].Token, notifier.resets[1].Token
+	assert.ErrorIs(t, gm.ResetPassword(first, "N3wPassw0rd"), gophermart.ErrInvalidResetToken)
+
+	assert.ErrorIs(t, gm.ResetPassword(second, ""), gophermart.ErrInvalidPassword)
+	require.NoError(t, gm.ResetPassword(second, "N3wPassw0rd"))
+	assert.ErrorIs(t, gm.ResetPassword(second, "An0therPassw0rd"), gophermart.ErrInvalidResetToken, "token is single-use")
+
+	_, _, err = gm.Authenticate(session.Token)
+	assert.Error(t, err, "all sessions revoked")
+	_, err = gm.Login(&gophermart.Credentials{Login: "gopher", Password: "N3wPassw0rd"}, "", nil)
+	assert.NoError(t, err)
+
+	// истёкший токен не действует
+	require.NoError(t, gm.RequestPasswordReset("gopher"))
+	time.Sleep(ttl * 2)
+	assert.ErrorIs(t, gm.ResetPassword(notifier.resets[2].Token, "An0therPassw0rd"), gophermart.ErrInvalidResetToken)
+}
+
+func TestCredentialsPolicy(t *testing.T) {
+	policy := gophermart.DefaultCredentialsPolicy()
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantErr  error
+	}{
+		{name: "valid", login: "gopher", password: "Passw0rd33"},
+		{name: "email login", login: "gopher@example.com", password: "Passw0rd33"},
+		{name: "short login", login: "go", password: "Passw0rd33", wantErr: gophermart.ErrInvalidLogin},
+		{name: "login with spaces", login: "go pher", password: "Passw0rd33", wantErr: gophermart.ErrInvalidLogin},
+		{name: "empty password", login: "gopher", password: "", wantErr: gophermart.ErrInvalidPassword},
+		{name: "short password", login: "gopher", password: "Pa55", wantErr: gophermart.ErrInvalidPassword},
+		{name: "single class", login: "gopher", password: "passwordpassword", wantErr: gophermart.ErrInvalidPassword},
+		{name: "contains login", login: "gopher", password: "Gopher2024", wantErr: gophermart.ErrInvalidPassword},
+		{name: "common password", login: "gopher", password: "Password123", wantErr: gophermart.ErrInvalidPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.ValidateLogin(tt.login)
+			if err == nil {
+				err = policy.ValidatePassword(tt.login, tt.password)
+			}
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRehashOnLogin(t *testing.T) {
+	st := basicstorage.New()
+	creds := &gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}
+	bcrypt4, err := gophermart.NewBcryptHasher(4)
+	require.NoError(t, err)
+	_, err = gophermart.New(st, gophermart.WithPasswordHasher(bcrypt4)).Register(creds, nil)
+	require.NoError(t, err)
+
+	// хранилище то же, настройки хэширования сменились: хэш пересчитывается при входе
+	argon2id, err := gophermart.NewArgon2idHasher(1, 64, 1)
+	require.NoError(t, err)
+	gm := gophermart.New(st, gophermart.WithPasswordHasher(argon2id))
+	user, err := gm.Users.Get(creds.Login)
+	require.NoError(t, err)
+	require.True(t, gm.Users.NeedsRehash(user))
+
+	_, err = gm.Login(creds, "", nil)
+	require.NoError(t, err)
+	user, err = gm.Users.Get(creds.Login)
+	require.NoError(t, err)
+	assert.False(t, gm.Users.NeedsRehash(user))
+	assert.True(t, user.CheckPassword(creds.Password))
+	assert.False(t, user.CheckPassword("wrong"))
+
+	bcrypt5, err := gophermart.NewBcryptHasher(5)
+	require.NoError(t, err)
+	gm = gophermart.New(st, gophermart.WithPasswordHasher(bcrypt5))
+	_, err = gm.Login(creds, "", nil)
+	require.NoError(t, err)
+	user, err = gm.Users.Get(creds.Login)
+	require.NoError(t, err)
+	assert.False(t, gm.Users.NeedsRehash(user))
+}
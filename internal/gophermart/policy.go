@@ -0,0 +1,101 @@
+package gophermart
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultLoginMinLength     = 3
+	defaultLoginMaxLength     = 64
+	defaultPasswordMinLength  = 8
+	defaultPasswordMinClasses = 2
+	// bcrypt учитывает только первые 72 байта пароля, остальное молча отбрасывается
+	passwordMaxBytes = 72
+)
+
+// commonPasswords самые распространённые пароли, их подбирают первыми
+var commonPasswords = map[string]struct{}{
+	"password": {}, "password1": {}, "password123": {}, "passw0rd": {}, "12345678": {}, "123456789": {},
+	"1234567890": {}, "qwerty123": {}, "qwertyuiop": {}, "1q2w3e4r": {}, "1qaz2wsx": {}, "abc12345": {},
+	"iloveyou": {}, "11111111": {}, "00000000": {}, "welcome1": {}, "letmein1": {}, "admin123": {},
+}
+
+// CredentialsPolicy требования к логину и паролю при регистрации и смене пароля.
+// Пароль должен содержать символы не менее PasswordMinClasses классов из четырёх: строчные и прописные
+// буквы, цифры, остальные символы; пароль не может содержать логин и быть распространённым паролем
+type CredentialsPolicy struct {
+	LoginMinLength     int
+	LoginMaxLength     int
+	PasswordMinLength  int
+	PasswordMinClasses int
+}
+
+func DefaultCredentialsPolicy() CredentialsPolicy {
+	return CredentialsPolicy{
+		LoginMinLength:     defaultLoginMinLength,
+		LoginMaxLength:     defaultLoginMaxLength,
+		PasswordMinLength:  defaultPasswordMinLength,
+		PasswordMinClasses: defaultPasswordMinClasses,
+	}
+}
+
+// ValidateLogin логин из букв, цифр и символов `.`, `_`, `-`, `@`, чтобы подходил и адрес почты
+func (p CredentialsPolicy) ValidateLogin(login string) error {
+	length := utf8.RuneCountInString(login)
+	if length < p.LoginMinLength || length > p.LoginMaxLength {
+		return fmt.Errorf("%w: from %d to %d characters needed", ErrInvalidLogin, p.LoginMinLength, p.LoginMaxLength)
+	}
+	for _, r := range login {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._-@", r) {
+			return fmt.Errorf("%w: only letters, digits and `._-@` allowed", ErrInvalidLogin)
+		}
+	}
+
+	return nil
+}
+
+func (p CredentialsPolicy) ValidatePassword(login, password string) error {
+	if utf8.RuneCountInString(password) < p.PasswordMinLength {
+		return fmt.Errorf("%w: at least %d characters needed", ErrInvalidPassword, p.PasswordMinLength)
+	}
+	if len(password) > passwordMaxBytes {
+		return fmt.Errorf("%w: at most %d bytes allowed", ErrInvalidPassword, passwordMaxBytes)
+	}
+
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	classes := 0
+	for _, has := range []bool{lower, upper, digit, other} {
+		if has {
+			classes++
+		}
+	}
+	if classes < p.PasswordMinClasses {
+		return fmt.Errorf("%w: characters of at least %d classes needed: lowercase, uppercase, digits, symbols",
+			ErrInvalidPassword, p.PasswordMinClasses)
+	}
+
+	lowered := strings.ToLower(password)
+	if login != "" && strings.Contains(lowered, strings.ToLower(login)) {
+		return fmt.Errorf("%w: password should not contain login", ErrInvalidPassword)
+	}
+	if _, ok := commonPasswords[lowered]; ok {
+		return fmt.Errorf("%w: password is too common", ErrInvalidPassword)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package gophermart
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/go-resty/resty/v2"
@@ -22,9 +23,9 @@ const (
 )
 
 type accrualOrder struct {
-	Order   string  `json:"order"`
-	Status  string  `json:"status"`
-	Accrual float64 `json:"accrual"`
+	Order   string `json:"order"`
+	Status  string `json:"status"`
+	Accrual Amount `json:"accrual"`
 }
 
 type queueOrder struct {
@@ -48,7 +49,6 @@ func (qo *queueOrder) Do() error {
 		SetHeader("Accept-Encoding", "gzip").
 		SetHeader("Content-Length", "0").
 		SetContext(ctx).
-		SetResult(&ao).
 		Get(url)
 	if err != nil {
 		return err
@@ -76,6 +76,13 @@ func (qo *queueOrder) Do() error {
 		return fmt.Errorf("unknown status code %d", resp.StatusCode())
 	}
 
+	// ответ разбираем сами: ошибка в данных одного заказа не должна останавливать обработку остальных
+	if err = json.Unmarshal(resp.Body(), ao); err != nil {
+		// некритичная ошибка, заказ останется в очереди до следующего опроса
+		log.Printf("[WARNING] Failed to decode accrual for order %d - %s\n", order.ID, err)
+		return nil
+	}
+
 	if fmt.Sprint(order.ID) != ao.Order {
 		// некритичная ошибка
 		log.Printf("[WARNING] Order ID not match, want %d, got %s\n", order.ID, ao.Order)
@@ -95,18 +102,20 @@ func (qo *queueOrder) Do() error {
 	}
 
 	order.Status = ao.Status
-	order.Accrual = uint64(ao.Accrual * 100)
+	order.Accrual = uint64(ao.Accrual)
 
 	// запрос успешно выполнен, обновим заказ
 	if err = qo.storage.UpdateOrder(order); err != nil {
 		return fmt.Errorf("failed to update order ID %d - %w", order.ID, err)
 	}
+	qo.linker.Orders.updated(order)
 	log.Printf("[DEBUG] Order successfully updated: order %v\n", order)
 
 	return nil
 }
 
 type Queue struct {
+	linker    *GopherMart
 	url       string
 	storage   Storer
 	limit     uint32
@@ -114,12 +123,13 @@ type Queue struct {
 	pool      map[uint64]*Order
 }
 
-func NewQueue(st Storer, addr string) *Queue {
+func NewQueue(gm *GopherMart, addr string) *Queue {
 
 	return &Queue{
+		linker:  gm,
 		limit:   limitDefault,
 		url:     addr + "/api/orders/",
-		storage: st,
+		storage: gm.storage,
 	}
 }
 
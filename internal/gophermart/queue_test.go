@@ -0,0 +1,27 @@
+package gophermart
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueOrderDecodeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"order":"12345678903","status":"PROCESSED","accrual":500.125}`))
+	}))
+	defer srv.Close()
+
+	// хранилище не нужно: заказ с неразборчивым начислением не обновляется
+	q := &Queue{url: srv.URL + "/api/orders/", limit: limitDefault}
+	qo := &queueOrder{Queue: q, ctx: context.Background(), order: &Order{ID: 12345678903, Status: StatusNew}}
+
+	// ошибка разбора одного заказа не должна останавливать errgroup и включать минутную паузу
+	assert.NoError(t, qo.Do())
+	assert.Equal(t, StatusNew, qo.order.Status)
+	assert.Equal(t, uint64(0), qo.order.Accrual)
+}
@@ -1,85 +1,381 @@
 package gophermart
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+const (
+	defaultSessionIdleTTL        = 10 * time.Minute
+	defaultSessionAbsoluteTTL    = 24 * time.Hour
+	defaultRememberMeIdleTTL     = 7 * 24 * time.Hour
+	defaultRememberMeAbsoluteTTL = 30 * 24 * time.Hour
+
+	sessionTokenLen = 32 // байт случайных данных в токене сессии
+	// с какой точностью хранится время последнего использования сессии: чаще в хранилище не пишем
+	lastUseResolution = time.Minute
+
+	SessionTypeCookie  = "cookie"
+	SessionTypeRefresh = "refresh"
+)
+
+// SessionClient откуда открыта сессия
+type SessionClient struct {
+	IP        string
+	UserAgent string
+}
+
 type Session struct {
+	// идентификатор для списка сессий пользователя, в отличие от токена его можно показывать;
+	// у refresh-токенов это семейство и при обновлении он не меняется
+	ID     string
 	UserID uint64
-	Token  string
-	Expiry time.Time
+	// токен знает только клиент: хранилище и кэш видят его хэш, а сессия из них приходит без токена
+	Token     string
+	TokenHash string
+	Expiry    time.Time // срок бездействия: сдвигается при активности, но не дальше AbsoluteExpiry
+	// крайний срок сессии от момента входа, нулевой у refresh-токенов — их срок задаёт семейство
+	AbsoluteExpiry time.Time
+	Remember       bool   // вход с «запомнить меня»: длинные сроки и постоянная кука
+	Family         string // семейство refresh-токенов, пустое у сессий по куке
+	CreatedAt      time.Time
+	LastUsedAt     time.Time
+	IP             string
+	UserAgent      string
+	// refresh-токен уже обменян на новую пару; хранится до срока семейства, чтобы распознать повторное предъявление
+	Used bool
+}
+
+// SessionProxy сессия в списке сессий пользователя
+type SessionProxy struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+	IP         string `json:"ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	Current    bool   `json:"current"` // сессия, которой выполнен запрос
 }
 
 func (s Session) IsExpired() bool {
-	return s.Expiry.Before(time.Now())
+	return s.ExpiredAt(time.Now())
+}
+
+// ExpiredAt истёк ли к моменту now срок бездействия или абсолютный срок сессии
+func (s Session) ExpiredAt(now time.Time) bool {
+	if !s.AbsoluteExpiry.IsZero() && s.AbsoluteExpiry.Before(now) {
+		return true
+	}
+
+	return s.Expiry.Before(now)
 }
 
+// sessionTTL сроки сессии: бездействия и абсолютный от момента входа
+type sessionTTL struct {
+	idle     time.Duration
+	absolute time.Duration
+}
+
+// sessions кэшированные в памяти сессии пользователей. Сессия в кэше не меняется:
+// продление кладёт в кэш новую копию, поэтому прочитанную сессию можно использовать без блокировок
 type sessions struct {
-	mu             sync.RWMutex
-	storage        Storer
-	bySessionToken map[string]*Session // кэш
+	mu          sync.RWMutex
+	storage     Storer
+	byTokenHash map[string]*Session // кэш
+	ttl         sessionTTL
+	rememberTTL sessionTTL
 }
 
-func newSessions(st Storer) *sessions {
+func newSessions(gm *GopherMart) *sessions {
 	return &sessions{
-		storage:        st,
-		bySessionToken: make(map[string]*Session),
+		storage:     gm.storage,
+		byTokenHash: make(map[string]*Session),
+		ttl:         gm.sessionTTL,
+		rememberTTL: gm.rememberMeTTL,
 	}
 }
 
+func (sns *sessions) ttlOf(remember bool) sessionTTL {
+	if remember {
+		return sns.rememberTTL
+	}
+
+	return sns.ttl
+}
+
+// New открывает сессию пользователя после входа
+func (sns *sessions) New(userID uint64, remember bool, client *SessionClient) (*Session, error) {
+	token, err := newSessionToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := sns.ttlOf(remember)
+	now := time.Now()
+	session := &Session{
+		ID:             uuid.NewString(),
+		UserID:         userID,
+		Token:          token,
+		AbsoluteExpiry: now.Add(ttl.absolute),
+		Remember:       remember,
+		CreatedAt:      now,
+		LastUsedAt:     now,
+	}
+	session.setClient(client)
+	session.Expiry = session.slide(now, ttl.idle)
+
+	if err := sns.Add(session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *Session) setClient(client *SessionClient) {
+	if client != nil {
+		s.IP = client.IP
+		s.UserAgent = client.UserAgent
+	}
+}
+
+// slide срок бездействия, отсчитанный от now и ограниченный абсолютным сроком
+func (s *Session) slide(now time.Time, idle time.Duration) time.Time {
+	expiry := now.Add(idle)
+	if !s.AbsoluteExpiry.IsZero() && expiry.After(s.AbsoluteExpiry) {
+		return s.AbsoluteExpiry
+	}
+
+	return expiry
+}
+
+// Renew отмечает использование сессии и продлевает её срок бездействия. Чтобы не писать в хранилище
+// на каждый запрос, сессия продлевается, только когда прошла половина срока бездействия, а время
+// использования обновляется с точностью до минуты; возвращает сессию и признак продления.
+// Refresh-токены не продлеваются
+func (sns *sessions) Renew(session *Session) (*Session, bool, error) {
+	if session.Family != "" || session.AbsoluteExpiry.IsZero() {
+		return session, false, nil
+	}
+
+	idle := sns.ttlOf(session.Remember).idle
+	now := time.Now()
+	expiry := session.slide(now, idle)
+	slide := expiry.Sub(session.Expiry) >= idle/2
+	if !slide && now.Sub(session.LastUsedAt) < lastUseResolution {
+		return session, false, nil
+	}
+
+	renewed := *session
+	renewed.LastUsedAt = now
+	if slide {
+		renewed.Expiry = expiry
+	}
+	if err := sns.storage.TouchSession(renewed.TokenHash, renewed.Expiry, renewed.LastUsedAt); err != nil {
+		return nil, false, fmt.Errorf("failed to renew session - %w", err)
+	}
+
+	// сессию могли удалить, пока она продлевалась, — возвращать её в кэш нельзя
+	cached := renewed
+	cached.Token = ""
+	sns.mu.Lock()
+	if _, ok := sns.byTokenHash[cached.TokenHash]; ok {
+		sns.byTokenHash[cached.TokenHash] = &cached
+	}
+	sns.mu.Unlock()
+
+	return &renewed, slide, nil
+}
+
+// Add сохраняет сессию с выданным клиенту токеном: в хранилище и кэш попадает только хэш токена
 func (sns *sessions) Add(session *Session) error {
+	session.TokenHash = hashToken(session.Token)
+	stored := *session
+	stored.Token = ""
+
 	// проверим наличие сессии в кэше
 	sns.mu.RLock()
-	_, ok := sns.bySessionToken[session.Token]
+	_, ok := sns.byTokenHash[stored.TokenHash]
 	sns.mu.RUnlock()
 	if ok {
 		return fmt.Errorf("session already exists")
 	}
 
-	err := sns.storage.AddSession(session)
+	err := sns.storage.AddSession(&stored)
 	if err != nil {
 		return err
 	}
 
 	// закэшируем созданную сессию
 	sns.mu.Lock()
-	sns.bySessionToken[session.Token] = session
+	sns.byTokenHash[stored.TokenHash] = &stored
 	sns.mu.Unlock()
 
 	return nil
 }
 
+// Get находит сессию по токену клиента; у найденной сессии поле Token пустое
 func (sns *sessions) Get(token string) (*Session, error) {
 	var err error
+	tokenHash := hashToken(token)
 
 	sns.mu.RLock()
-	session, ok := sns.bySessionToken[token]
+	session, ok := sns.byTokenHash[tokenHash]
 	sns.mu.RUnlock()
 	if !ok {
-		session, err = sns.storage.GetSession(token)
+		session, err = sns.storage.GetSession(tokenHash)
 		if err != nil {
 			return nil, fmt.Errorf("token session not found - %w", err)
 		}
 		// закэшируем полученную сессию
 		sns.mu.Lock()
-		sns.bySessionToken[session.Token] = session
+		sns.byTokenHash[session.TokenHash] = session
 		sns.mu.Unlock()
 	}
 
 	return session, nil
 }
 
+// Use отмечает refresh-токен использованным. Отметка — момент использования токена: из двух одновременных
+// обновлений пройдёт только одно, второе получит ErrSessionNotFound
+func (sns *sessions) Use(token string) error {
+	tokenHash := hashToken(token)
+
+	sns.mu.Lock()
+	delete(sns.byTokenHash, tokenHash)
+	sns.mu.Unlock()
+
+	return sns.storage.UseSession(tokenHash)
+}
+
+// DeleteFamily отзывает все refresh-токены семейства
+func (sns *sessions) DeleteFamily(family string) error {
+	if family == "" {
+		return nil
+	}
+
+	sns.evict(func(session *Session) bool {
+		return session.Family == family
+	})
+
+	return sns.storage.DeleteSessionFamily(family)
+}
+
+// GetUserSessions действующие сессии пользователя
+func (sns *sessions) GetUserSessions(userID uint64) ([]*Session, error) {
+	return sns.storage.GetUserSessions(userID, time.Now())
+}
+
+// DeleteUserSession завершает сессию пользователя по идентификатору из списка сессий
+func (sns *sessions) DeleteUserSession(userID uint64, id string) error {
+	sns.evict(func(session *Session) bool {
+		return session.UserID == userID && session.ID == id
+	})
+
+	return sns.storage.DeleteUserSession(userID, id)
+}
+
+// DeleteUserSessions завершает все сессии пользователя, включая refresh-токены, кроме сессии exceptID;
+// с пустым exceptID завершаются все сессии
+func (sns *sessions) DeleteUserSessions(userID uint64, exceptID string) error {
+	sns.evict(func(session *Session) bool {
+		return session.UserID == userID && session.ID != exceptID
+	})
+
+	return sns.storage.DeleteUserSessions(userID, exceptID)
+}
+
+// evict удаляет из кэша сессии, подходящие под условие
+func (sns *sessions) evict(match func(*Session) bool) {
+	sns.mu.Lock()
+	for tokenHash, session := range sns.byTokenHash {
+		if match(session) {
+			delete(sns.byTokenHash, tokenHash)
+		}
+	}
+	sns.mu.Unlock()
+}
+
 func (sns *sessions) Delete(token string) error {
+	tokenHash := hashToken(token)
+
 	sns.mu.Lock()
-	delete(sns.bySessionToken, token)
+	delete(sns.byTokenHash, tokenHash)
 	sns.mu.Unlock()
 
-	err := sns.storage.DeleteSession(token)
+	err := sns.storage.DeleteSession(tokenHash)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// DeleteExpired удаляет истёкшие сессии из хранилища и кэша и обновляет число действующих сессий
+func (sns *sessions) DeleteExpired() error {
+	now := time.Now()
+	deleted, err := sns.storage.DeleteExpiredSessions(now)
+	if err != nil {
+		return err
+	}
+
+	sns.evict(func(session *Session) bool {
+		return session.ExpiredAt(now)
+	})
+
+	active, err := sns.storage.CountActiveSessions(now)
+	if err != nil {
+		return err
+	}
+
+	sessionsExpired.Add(deleted)
+	sessionsActive.Set(active)
+	if deleted > 0 {
+		log.Printf("[DEBUG] %d expired sessions deleted, %d active\n", deleted, active)
+	}
+
+	return nil
+}
+
+// Sweep периодически удаляет истёкшие сессии, пока не будет отменён контекст
+func (sns *sessions) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sns.DeleteExpired(); err != nil {
+				log.Println("[ERROR] Failed to delete expired sessions -", err)
+			}
+		}
+	}
+}
+
+// newSessionToken случайный токен сессии из криптографически стойкого генератора
+func newSessionToken() (string, error) {
+	b := make([]byte, sessionTokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session token - %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken под этим хэшем токен сессии или refresh-токен хранится и ищется: утечка базы не даёт
+// предъявить чужой токен. Токен случайный и длинный, поэтому соль и медленный хэш не нужны
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
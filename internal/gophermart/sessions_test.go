@@ -0,0 +1,143 @@
+package gophermart_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestSessionExpiry(t *testing.T) {
+	const idle, absolute = 200 * time.Millisecond, 500 * time.Millisecond
+	gm := gophermart.New(basicstorage.New(), gophermart.WithSessionTTL(idle, absolute))
+	creds := &gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}
+	_, err := gm.Register(creds, nil)
+	require.NoError(t, err)
+
+	// сессия продлевается, только когда прошла половина срока бездействия
+	session, err := gm.Login(creds, "", nil)
+	require.NoError(t, err)
+	_, renewed, err := gm.Authenticate(session.Token)
+	require.NoError(t, err)
+	assert.False(t, renewed)
+
+	time.Sleep(idle * 3 / 4)
+	renewedSession, renewed, err := gm.Authenticate(session.Token)
+	require.NoError(t, err)
+	assert.True(t, renewed)
+	assert.True(t, renewedSession.Expiry.After(session.Expiry))
+
+	// активность не продлевает сессию дальше абсолютного срока
+	for time.Now().Add(idle / 2).Before(session.AbsoluteExpiry) {
+		time.Sleep(idle / 4)
+		_, _, err = gm.Authenticate(session.Token)
+		require.NoError(t, err)
+	}
+	time.Sleep(time.Until(session.AbsoluteExpiry) + idle/4)
+	_, _, err = gm.Authenticate(session.Token)
+	assert.ErrorIs(t, err, gophermart.ErrSessionExpired)
+
+	// без активности сессия истекает через срок бездействия
+	idleSession, err := gm.Login(creds, "", nil)
+	require.NoError(t, err)
+	time.Sleep(idle + idle/4)
+	_, _, err = gm.Authenticate(idleSession.Token)
+	assert.ErrorIs(t, err, gophermart.ErrSessionExpired)
+
+	// «запомнить меня» открывает сессию с длинными сроками
+	creds.Remember = true
+	remembered, err := gm.Login(creds, "", nil)
+	require.NoError(t, err)
+	assert.True(t, remembered.Remember)
+	assert.True(t, remembered.Expiry.After(time.Now().Add(24*time.Hour)))
+}
+
+func TestSessionsDeleteExpired(t *testing.T) {
+	const idle = 50 * time.Millisecond
+	gm := gophermart.New(basicstorage.New(), gophermart.WithSessionTTL(idle, 0))
+	creds := &gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}
+	expired, err := gm.Register(creds, nil)
+	require.NoError(t, err)
+	creds.Remember = true
+	active, err := gm.Login(creds, "", nil)
+	require.NoError(t, err)
+
+	time.Sleep(idle * 2)
+	require.NoError(t, gm.Sessions.DeleteExpired())
+
+	// истёкшая сессия удалена и из кэша, и из хранилища
+	_, err = gm.Sessions.Get(expired.Token)
+	assert.Error(t, err)
+	_, err = gm.Sessions.Get(active.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", gophermart.Metrics.Get("sessions_active").String())
+}
+
+func TestSessionTokenHashed(t *testing.T) {
+	st := basicstorage.New()
+	gm := gophermart.New(st)
+	session, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+	assert.Len(t, session.Token, 43, "32 random bytes in base64")
+
+	// хранилище знает сессию только по хэшу токена
+	_, err = st.GetSession(session.Token)
+	assert.Error(t, err)
+	stored, err := st.GetSession(session.TokenHash)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Token)
+
+	authenticated, _, err := gm.Authenticate(session.Token)
+	require.NoError(t, err)
+	assert.Equal(t, session.Token, authenticated.Token)
+	_, _, err = gm.Authenticate(session.TokenHash)
+	assert.ErrorIs(t, err, gophermart.ErrSessionNotFound)
+}
+
+func TestUserSessions(t *testing.T) {
+	key, err := gophermart.NewHS256Key("k1", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	gm := gophermart.New(basicstorage.New(), gophermart.WithJWT([]*gophermart.SigningKey{key}, 0, 0))
+	creds := &gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}
+	laptop, err := gm.Register(creds, &gophermart.SessionClient{IP: "192.0.2.1", UserAgent: "laptop"})
+	require.NoError(t, err)
+	phone, err := gm.Login(creds, "", &gophermart.SessionClient{IP: "192.0.2.2", UserAgent: "phone"})
+	require.NoError(t, err)
+	pair, err := gm.IssueTokens(creds, &gophermart.SessionClient{IP: "192.0.2.3", UserAgent: "app"})
+	require.NoError(t, err)
+
+	// семейство refresh-токенов остаётся одной сессией и после обновления
+	pair, err = gm.RefreshTokens(pair.RefreshToken)
+	require.NoError(t, err)
+
+	sessions, err := gm.GetSessions(1, laptop.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	byAgent := make(map[string]*gophermart.SessionProxy)
+	for _, s := range sessions {
+		byAgent[s.UserAgent] = s
+	}
+	assert.True(t, byAgent["laptop"].Current)
+	assert.Equal(t, "192.0.2.2", byAgent["phone"].IP)
+	assert.Equal(t, gophermart.SessionTypeRefresh, byAgent["app"].Type)
+
+	_, err = gm.GetSessions(2, "")
+	assert.ErrorIs(t, err, gophermart.ErrNoContent)
+	assert.ErrorIs(t, gm.RevokeSession(2, phone.ID), gophermart.ErrSessionNotFound, "other user's session")
+
+	require.NoError(t, gm.RevokeSession(1, phone.ID))
+	_, _, err = gm.Authenticate(phone.Token)
+	assert.Error(t, err)
+	_, _, err = gm.Authenticate(laptop.Token)
+	assert.NoError(t, err)
+
+	require.NoError(t, gm.RevokeAllSessions(1))
+	_, _, err = gm.Authenticate(laptop.Token)
+	assert.Error(t, err)
+	_, err = gm.RefreshTokens(pair.RefreshToken)
+	assert.ErrorIs(t, err, gophermart.ErrInvalidRefreshToken)
+}
@@ -0,0 +1,67 @@
+package gophermart
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	StatementAccrual    = "accrual"
+	StatementWithdrawal = "withdrawal"
+	StatementAdjustment = "adjustment"
+	StatementTransfer   = "transfer"
+	StatementHold       = "hold"
+
+	StatementCredit = "credit"
+	StatementDebit  = "debit"
+)
+
+// StatementEntry строка выписки по счёту: сумма со знаком и баланс после операции
+type StatementEntry struct {
+	Type        string
+	Reference   string // номер заказа или ID операции
+	Amount      int64
+	Balance     int64
+	ProcessedAt time.Time
+}
+
+// StatementFilter ограничения выписки; нулевые значения означают отсутствие ограничения
+type StatementFilter struct {
+	From   time.Time
+	To     time.Time
+	Limit  uint64
+	Offset uint64
+}
+
+type StatementEntryProxy struct {
+	Type        string `json:"type"`
+	Reference   string `json:"reference"`
+	Direction   string `json:"direction"`
+	Sum         Amount `json:"sum"`
+	Balance     Amount `json:"balance"`
+	ProcessedAt string `json:"processed_at"`
+}
+
+func newStatementEntryProxy(userID uint64, e *StatementEntry) *StatementEntryProxy {
+	sePr := &StatementEntryProxy{
+		Type:        e.Type,
+		Reference:   e.Reference,
+		Direction:   StatementCredit,
+		Sum:         Amount(e.Amount),
+		ProcessedAt: e.ProcessedAt.Format(time.RFC3339),
+	}
+	if e.Amount < 0 {
+		sePr.Direction = StatementDebit
+		sePr.Sum = Amount(-e.Amount)
+	}
+	// баланс не уходит в минус ни одной операцией, поэтому отрицательный остаток в выписке означает
+	// расхождение истории операций с балансом: в ответе показываем ноль, а расхождение пишем в журнал
+	if e.Balance < 0 {
+		log.Printf("[WARNING] Negative running balance %d in statement of user %d after %s %s\n",
+			e.Balance, userID, e.Type, e.Reference)
+	} else {
+		sePr.Balance = Amount(e.Balance)
+	}
+
+	return sePr
+}
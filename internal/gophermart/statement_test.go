@@ -0,0 +1,48 @@
+package gophermart_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestGetStatement(t *testing.T) {
+	const userID = 1
+
+	st := newStubStorage()
+	gm := gophermart.New(st)
+	start := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// конец периода раньше начала: до хранилища запрос не доходит
+	_, err := gm.GetStatement(userID, &gophermart.StatementFilter{From: start, To: start.Add(-time.Hour)})
+	assert.ErrorIs(t, err, gophermart.ErrInvalidPeriod)
+	assert.Nil(t, st.statementFilter)
+
+	filter := &gophermart.StatementFilter{From: start, To: start, Limit: 10, Offset: 20}
+	_, err = gm.GetStatement(userID, filter)
+	assert.ErrorIs(t, err, gophermart.ErrNoContent)
+	assert.Equal(t, filter, st.statementFilter)
+
+	st.statement = []*gophermart.StatementEntry{
+		{Type: gophermart.StatementAccrual, Reference: "12345678903", Amount: 1000_00, Balance: 1000_00, ProcessedAt: start},
+		{Type: gophermart.StatementWithdrawal, Reference: "2377225624", Amount: -250_50, Balance: 749_50, ProcessedAt: start},
+		{Type: gophermart.StatementTransfer, Reference: "7", Amount: -1000_00, Balance: -250_50, ProcessedAt: start},
+	}
+	sePr, err := gm.GetStatement(userID, &gophermart.StatementFilter{})
+	require.NoError(t, err)
+
+	processedAt := start.Format(time.RFC3339)
+	assert.Equal(t, []*gophermart.StatementEntryProxy{
+		{Type: gophermart.StatementAccrual, Reference: "12345678903", Direction: gophermart.StatementCredit,
+			Sum: 1000_00, Balance: 1000_00, ProcessedAt: processedAt},
+		{Type: gophermart.StatementWithdrawal, Reference: "2377225624", Direction: gophermart.StatementDebit,
+			Sum: 250_50, Balance: 749_50, ProcessedAt: processedAt},
+		// расхождение истории с балансом не выдаётся клиенту отрицательным остатком
+		{Type: gophermart.StatementTransfer, Reference: "7", Direction: gophermart.StatementDebit,
+			Sum: 1000_00, Balance: 0, ProcessedAt: processedAt},
+	}, sePr)
+}
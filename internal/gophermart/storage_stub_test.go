@@ -0,0 +1,213 @@
+package gophermart_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+// stubStorage хранилище в памяти, дополненное тем, чего basicstorage не поддерживает: резервами, переводами,
+// выпиской и выгрузкой истории.
+// Баланс заглушка ведёт сама и повторяет семантику транзакций Postgres-хранилища
+type stubStorage struct {
+	*basicstorage.Storage
+
+	mu        sync.Mutex
+	balances  map[uint64]*gophermart.Balance
+	holds     map[uint64]*gophermart.Hold
+	transfers []*gophermart.Transfer
+
+	// выписку и историю заглушка не собирает: отдаёт заданные строки и запоминает фильтр
+	statement       []*gophermart.StatementEntry
+	statementFilter *gophermart.StatementFilter
+	history         []*gophermart.HistoryRecord
+	historyFilter   *gophermart.HistoryFilter
+}
+
+func newStubStorage() *stubStorage {
+	return &stubStorage{
+		Storage:  basicstorage.New(),
+		balances: make(map[uint64]*gophermart.Balance),
+		holds:    make(map[uint64]*gophermart.Hold),
+	}
+}
+
+// fund зачисляет баллы на счёт пользователя
+func (s *stubStorage) fund(userID, sum uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.balance(userID).Current += sum
+}
+
+// expire сдвигает срок резерва в прошлое
+func (s *stubStorage) expire(holdID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.holds[holdID].ExpiresAt = time.Now().Add(-time.Second)
+}
+
+func (s *stubStorage) balance(userID uint64) *gophermart.Balance {
+	b, ok := s.balances[userID]
+	if !ok {
+		b = &gophermart.Balance{UserID: userID}
+		s.balances[userID] = b
+	}
+	return b
+}
+
+func (s *stubStorage) GetBalance(userID uint64) (*gophermart.Balance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := *s.balance(userID)
+	return &b, nil
+}
+
+func (s *stubStorage) AddHold(h *gophermart.Hold) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, v := range s.holds {
+		if v.OrderID == h.OrderID {
+			return 0, gophermart.ErrHoldAlreadyExists
+		}
+	}
+	b := s.balance(h.UserID)
+	if b.Current < h.Sum {
+		return 0, gophermart.ErrNotEnoughFunds
+	}
+	b.Current -= h.Sum
+
+	hold := *h
+	hold.ID = uint64(len(s.holds) + 1)
+	s.holds[hold.ID] = &hold
+
+	return hold.ID, nil
+}
+
+func (s *stubStorage) activeHold(holdID, userID uint64) (*gophermart.Hold, error) {
+	h, ok := s.holds[holdID]
+	if !ok || h.UserID != userID {
+		return nil, gophermart.ErrHoldNotFound
+	}
+	if h.Status != gophermart.HoldStatusHeld {
+		return nil, gophermart.ErrHoldNotActive
+	}
+	return h, nil
+}
+
+func (s *stubStorage) CaptureHold(holdID, userID uint64) (*gophermart.Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, err := s.activeHold(holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	b := s.balance(userID)
+	if !h.ExpiresAt.After(time.Now()) {
+		h.Status = gophermart.HoldStatusExpired
+		b.Current += h.Sum
+		return nil, gophermart.ErrHoldExpired
+	}
+	h.Status = gophermart.HoldStatusCaptured
+	b.Withdrawn += h.Sum
+
+	hold := *h
+	return &hold, nil
+}
+
+func (s *stubStorage) ReleaseHold(holdID, userID uint64) (*gophermart.Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, err := s.activeHold(holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	h.Status = gophermart.HoldStatusReleased
+	s.balance(userID).Current += h.Sum
+
+	hold := *h
+	return &hold, nil
+}
+
+func (s *stubStorage) ReleaseExpiredHolds(now time.Time) ([]uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[uint64]bool)
+	userIDs := make([]uint64, 0)
+	for _, h := range s.holds {
+		if h.Status != gophermart.HoldStatusHeld || h.ExpiresAt.After(now) {
+			continue
+		}
+		h.Status = gophermart.HoldStatusExpired
+		s.balance(h.UserID).Current += h.Sum
+		if !seen[h.UserID] {
+			seen[h.UserID] = true
+			userIDs = append(userIDs, h.UserID)
+		}
+	}
+
+	return userIDs, nil
+}
+
+func (s *stubStorage) AddTransfer(t *gophermart.Transfer, dayStart time.Time, dailyLimit uint64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, to := s.balance(t.FromUserID), s.balance(t.ToUserID)
+	if from.Current < t.Sum {
+		return 0, gophermart.ErrNotEnoughFunds
+	}
+
+	if dailyLimit > 0 {
+		var sent uint64
+		for _, v := range s.transfers {
+			if v.FromUserID == t.FromUserID && !v.CreatedAt.Before(dayStart) {
+				sent += v.Sum
+			}
+		}
+		if sent+t.Sum > dailyLimit {
+			return 0, gophermart.ErrTransferLimitExceeded
+		}
+	}
+
+	from.Current -= t.Sum
+	to.Current += t.Sum
+
+	transfer := *t
+	transfer.ID = uint64(len(s.transfers) + 1)
+	s.transfers = append(s.transfers, &transfer)
+
+	return transfer.ID, nil
+}
+
+func (s *stubStorage) GetUserStatement(_ uint64, filter *gophermart.StatementFilter) ([]*gophermart.StatementEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statementFilter = filter
+	return s.statement, nil
+}
+
+func (s *stubStorage) ExportHistory(filter *gophermart.HistoryFilter, fn func(*gophermart.HistoryRecord) error) error {
+	s.mu.Lock()
+	s.historyFilter = filter
+	history := s.history
+	s.mu.Unlock()
+
+	for _, hr := range history {
+		if err := fn(hr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,323 @@
+package gophermart
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	SigningMethodHS256 = "HS256"
+	SigningMethodEdDSA = "EdDSA"
+
+	tokenIssuer       = "gophermart"
+	tokenTypeBearer   = "Bearer"
+	minHS256KeyLength = 32
+	refreshSecretLen  = 32
+)
+
+// SigningKey ключ подписи токенов доступа; в заголовке токена передаётся идентификатор ключа `kid`,
+// по которому токен проверяется и после ротации
+type SigningKey struct {
+	ID        string
+	method    jwt.SigningMethod
+	signKey   interface{} // []byte для HS256, ed25519.PrivateKey для EdDSA
+	verifyKey interface{} // []byte для HS256, ed25519.PublicKey для EdDSA
+}
+
+func NewHS256Key(id string, secret []byte) (*SigningKey, error) {
+	if id == "" || len(secret) < minHS256KeyLength {
+		return nil, fmt.Errorf("%w: HS256 key needs ID and at least %d bytes secret", ErrInvalidSigningKey, minHS256KeyLength)
+	}
+
+	return &SigningKey{ID: id, method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+func NewEdDSAKey(id string, seed []byte) (*SigningKey, error) {
+	if id == "" || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%w: EdDSA key needs ID and %d bytes seed", ErrInvalidSigningKey, ed25519.SeedSize)
+	}
+	private := ed25519.NewKeyFromSeed(seed)
+
+	return &SigningKey{ID: id, method: jwt.SigningMethodEdDSA, signKey: private, verifyKey: private.Public()}, nil
+}
+
+// ParseSigningKeys разбирает список ключей `kid:алгоритм:base64,...`, например `k2:EdDSA:...,k1:HS256:...`;
+// первый ключ подписывает новые токены, остальные только проверяют выданные ранее
+func ParseSigningKeys(s string) ([]*SigningKey, error) {
+	var keys []*SigningKey
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%w: `kid:algorithm:base64` needed", ErrInvalidSigningKey)
+		}
+		material, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: key `%s` is not base64 - %s", ErrInvalidSigningKey, parts[0], err)
+		}
+
+		var key *SigningKey
+		switch parts[1] {
+		case SigningMethodHS256:
+			key, err = NewHS256Key(parts[0], material)
+		case SigningMethodEdDSA:
+			key, err = NewEdDSAKey(parts[0], material)
+		default:
+			err = fmt.Errorf("%w: unknown algorithm `%s`", ErrInvalidSigningKey, parts[1])
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// TokenPair ответ на выдачу и обновление токенов
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // время жизни токена доступа в секундах
+	RefreshToken string `json:"refresh_token"`
+}
+
+type accessClaims struct {
+	Family string `json:"fam"`
+	jwt.RegisteredClaims
+}
+
+// tokens режим JWT: короткоживущие подписанные токены доступа проверяются без обращения к хранилищу,
+// а непрозрачные refresh-токены хранятся в сессиях. Refresh-токен одноразовый: при обновлении выдаётся новый
+// того же семейства, а повторное предъявление уже использованного токена отзывает всё семейство
+type tokens struct {
+	linker     *GopherMart
+	keys       map[string]*SigningKey
+	active     *SigningKey
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func newTokens(gm *GopherMart) *tokens {
+	t := &tokens{
+		linker:     gm,
+		keys:       make(map[string]*SigningKey, len(gm.signingKeys)),
+		accessTTL:  gm.accessTokenTTL,
+		refreshTTL: gm.refreshTokenTTL,
+	}
+	for _, key := range gm.signingKeys {
+		t.keys[key.ID] = key
+	}
+	if len(gm.signingKeys) > 0 {
+		t.active = gm.signingKeys[0]
+	}
+
+	return t
+}
+
+// Enabled режим JWT включается заданием хотя бы одного ключа подписи
+func (t *tokens) Enabled() bool {
+	return t.active != nil
+}
+
+// Issue открывает новое семейство refresh-токенов пользователя
+func (t *tokens) Issue(userID uint64, client *SessionClient) (*TokenPair, error) {
+	if !t.Enabled() {
+		return nil, ErrJWTDisabled
+	}
+
+	now := time.Now()
+	family := uuid.NewString()
+	refresh := &Session{
+		ID:        family,
+		UserID:    userID,
+		Expiry:    now.Add(t.refreshTTL),
+		Family:    family,
+		CreatedAt: now,
+	}
+	refresh.setClient(client)
+
+	return t.issue(refresh)
+}
+
+// issue выдаёт пару токенов в семействе refresh-сессии; срок семейства задаёт первый refresh-токен
+// и при обновлении не продлевается
+func (t *tokens) issue(refresh *Session) (*TokenPair, error) {
+	now := time.Now()
+	secret := make([]byte, refreshSecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	refresh.Token = refresh.Family + "." + base64.RawURLEncoding.EncodeToString(secret)
+	refresh.LastUsedAt = now
+	if err := t.linker.Sessions.Add(refresh); err != nil {
+		return nil, err
+	}
+
+	claims := &accessClaims{
+		Family: refresh.Family,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   strconv.FormatUint(refresh.UserID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.accessTTL)),
+		},
+	}
+	token := jwt.NewWithClaims(t.active.method, claims)
+	token.Header["kid"] = t.active.ID
+	access, err := token.SignedString(t.active.signKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token - %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    int64(t.accessTTL / time.Second),
+		RefreshToken: refresh.Token,
+	}, nil
+}
+
+// Refresh меняет refresh-токен на новую пару токенов того же семейства
+func (t *tokens) Refresh(refreshToken string) (*TokenPair, error) {
+	if !t.Enabled() {
+		return nil, ErrJWTDisabled
+	}
+	family, ok := refreshFamily(refreshToken)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// использованные токены хранятся до срока семейства, поэтому ненайденный токен подделан или истёк:
+	// семейство по нему не отзываем
+	session, err := t.linker.Sessions.Get(refreshToken)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.Family != family {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.IsExpired() {
+		return nil, ErrSessionExpired
+	}
+	if session.Used {
+		return nil, t.revokeReused(family)
+	}
+
+	err = t.linker.Sessions.Use(refreshToken)
+	if errors.Is(err, ErrSessionNotFound) {
+		// токен успели использовать или отозвать параллельным запросом
+		return nil, t.revokeReused(family)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return t.issue(&Session{
+		ID:        family,
+		UserID:    session.UserID,
+		Expiry:    session.Expiry,
+		Family:    family,
+		CreatedAt: session.CreatedAt,
+		IP:        session.IP,
+		UserAgent: session.UserAgent,
+	})
+}
+
+// revokeReused отзывает семейство, в котором предъявлен уже использованный токен: предъявить его может только тот,
+// кто его украл. Если отозвать не удалось, клиент получит ошибку сервера и сможет повторить запрос
+func (t *tokens) revokeReused(family string) error {
+	log.Printf("[WARNING] Reuse of refresh token detected, revoking family %s\n", family)
+	if err := t.linker.Sessions.DeleteFamily(family); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family - %w", err)
+	}
+
+	return ErrInvalidRefreshToken
+}
+
+// Revoke отзывает семейство, к которому относится refresh-токен: выход на устройстве, получившем это семейство.
+// Выданные токены доступа продолжают действовать до истечения своего короткого срока
+func (t *tokens) Revoke(refreshToken string) error {
+	if !t.Enabled() {
+		return ErrJWTDisabled
+	}
+	family, ok := refreshFamily(refreshToken)
+	if !ok {
+		return ErrInvalidRefreshToken
+	}
+
+	return t.linker.Sessions.DeleteFamily(family)
+}
+
+// Verify проверяет подпись и срок токена доступа и возвращает сессию, не обращаясь к хранилищу
+func (t *tokens) Verify(accessToken string) (*Session, error) {
+	claims := &accessClaims{}
+	_, err := jwt.ParseWithClaims(accessToken, claims, t.keyFunc,
+		jwt.WithValidMethods([]string{SigningMethodHS256, SigningMethodEdDSA}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w - %s", ErrInvalidToken, err)
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &Session{
+		ID:     claims.Family,
+		UserID: userID,
+		Expiry: claims.ExpiresAt.Time,
+		Family: claims.Family,
+	}, nil
+}
+
+func (t *tokens) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := t.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key `%s`", kid)
+	}
+	// алгоритм определяет ключ, а не заголовок токена: HS256-токен не пройдёт проверку EdDSA-ключом и наоборот
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("signing method %s does not match key `%s`", token.Method.Alg(), kid)
+	}
+
+	return key.verifyKey, nil
+}
+
+// refreshFamily извлекает семейство из refresh-токена вида `<семейство>.<секрет>`
+func refreshFamily(refreshToken string) (string, bool) {
+	family, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || family == "" || secret == "" {
+		return "", false
+	}
+
+	return family, true
+}
+
+// isJWT токен доступа состоит из трёх частей через точку, токены сессий и refresh-токены — нет
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
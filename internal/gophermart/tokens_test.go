@@ -0,0 +1,125 @@
+package gophermart_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestParseSigningKeys(t *testing.T) {
+	keys, err := gophermart.ParseSigningKeys(
+		"k2:EdDSA:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=, k1:HS256:MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=",
+	)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k2", keys[0].ID)
+
+	for _, spec := range []string{"k1:HS256:c2hvcnQ=", "k1:RS256:AAAA", "k1:HS256", ":HS256:MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="} {
+		_, err = gophermart.ParseSigningKeys(spec)
+		assert.ErrorIs(t, err, gophermart.ErrInvalidSigningKey, spec)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	hs, err := gophermart.NewHS256Key("k1", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+	ed, err := gophermart.NewEdDSAKey("k2", bytes.Repeat([]byte("e"), 32))
+	require.NoError(t, err)
+
+	gm := gophermart.New(basicstorage.New(), gophermart.WithJWT([]*gophermart.SigningKey{ed, hs}, 0, 0))
+	_, err = gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+
+	_, err = gm.IssueTokens(&gophermart.Credentials{Login: "gopher", Password: "wrong"}, nil)
+	assert.ErrorIs(t, err, gophermart.ErrInvalidPair)
+
+	pair, err := gm.IssueTokens(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+	session, _, err := gm.Authenticate(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), session.UserID)
+
+	// refresh-токен не заменяет токен доступа, подделанный токен доступа не проходит проверку
+	_, _, err = gm.Authenticate(pair.RefreshToken)
+	assert.Error(t, err)
+	_, _, err = gm.Authenticate(pair.AccessToken[:strings.LastIndex(pair.AccessToken, ".")] + ".forged")
+	assert.ErrorIs(t, err, gophermart.ErrInvalidToken)
+
+	// токен, подписанный прежним ключом, действует после ротации
+	old := gophermart.New(basicstorage.New(), gophermart.WithJWT([]*gophermart.SigningKey{hs}, 0, 0))
+	oldPair, err := old.Tokens.Issue(1, nil)
+	require.NoError(t, err)
+	_, _, err = gm.Authenticate(oldPair.AccessToken)
+	assert.NoError(t, err)
+
+	refreshed, err := gm.RefreshTokens(pair.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, pair.RefreshToken, refreshed.RefreshToken)
+
+	// неизвестный токен того же семейства отклоняется, но семейство не отзывает
+	family := pair.RefreshToken[:strings.Index(pair.RefreshToken, ".")]
+	_, err = gm.RefreshTokens(family + ".forged")
+	assert.ErrorIs(t, err, gophermart.ErrInvalidRefreshToken)
+	sessions, err := gm.GetSessions(session.UserID, "")
+	require.NoError(t, err)
+	var refreshSessions int
+	for _, s := range sessions {
+		if s.Type == gophermart.SessionTypeRefresh {
+			refreshSessions++
+		}
+	}
+	assert.Equal(t, 1, refreshSessions, "used refresh token is not listed")
+
+	// повторное использование уже использованного токена отзывает всё семейство
+	_, err = gm.RefreshTokens(pair.RefreshToken)
+	assert.ErrorIs(t, err, gophermart.ErrInvalidRefreshToken)
+	_, err = gm.RefreshTokens(refreshed.RefreshToken)
+	assert.ErrorIs(t, err, gophermart.ErrInvalidRefreshToken)
+
+	another, err := gm.IssueTokens(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, gm.RevokeTokens(another.RefreshToken))
+	_, err = gm.RefreshTokens(another.RefreshToken)
+	assert.ErrorIs(t, err, gophermart.ErrInvalidRefreshToken)
+}
+
+// failingUseStorage хранилище, в котором не удаётся отметить refresh-токен использованным
+type failingUseStorage struct {
+	*basicstorage.Storage
+}
+
+func (s failingUseStorage) UseSession(string) error {
+	return errors.New("storage is unavailable")
+}
+
+func TestTokensRefreshStorageError(t *testing.T) {
+	hs, err := gophermart.NewHS256Key("k1", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+	st := basicstorage.New()
+	pair, err := gophermart.New(st, gophermart.WithJWT([]*gophermart.SigningKey{hs}, 0, 0)).Tokens.Issue(1, nil)
+	require.NoError(t, err)
+
+	// ошибка хранилища не отзывает семейство: токен действует, когда хранилище снова доступно
+	failing := gophermart.New(failingUseStorage{st}, gophermart.WithJWT([]*gophermart.SigningKey{hs}, 0, 0))
+	_, err = failing.RefreshTokens(pair.RefreshToken)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, gophermart.ErrInvalidRefreshToken)
+
+	_, err = gophermart.New(st, gophermart.WithJWT([]*gophermart.SigningKey{hs}, 0, 0)).RefreshTokens(pair.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestTokensDisabled(t *testing.T) {
+	gm := gophermart.New(basicstorage.New())
+	_, err := gm.IssueTokens(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	assert.ErrorIs(t, err, gophermart.ErrJWTDisabled)
+	_, err = gm.RefreshTokens("family.secret")
+	assert.ErrorIs(t, err, gophermart.ErrJWTDisabled)
+}
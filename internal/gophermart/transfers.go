@@ -0,0 +1,79 @@
+package gophermart
+
+import (
+	"time"
+)
+
+const (
+	TransferDirectionIn  = "in"
+	TransferDirectionOut = "out"
+)
+
+// Transfer перевод баллов с баланса одного пользователя на баланс другого
+type Transfer struct {
+	ID         uint64
+	FromUserID uint64
+	ToUserID   uint64
+	Sum        uint64
+	CreatedAt  time.Time
+	// логины участников хранилище подставляет при чтении истории, чтобы не запрашивать каждого отдельно
+	FromLogin string
+	ToLogin   string
+}
+
+type TransferProxy struct {
+	Login       string `json:"login"` // логин получателя для исходящего перевода, отправителя — для входящего
+	Sum         Amount `json:"sum"`
+	UserID      uint64 `json:"-"`
+	Direction   string `json:"direction,omitempty"`
+	ProcessedAt string `json:"processed_at,omitempty"`
+}
+
+type transfers struct {
+	linker *GopherMart
+}
+
+func newTransfers(linker *GopherMart) *transfers {
+	return &transfers{
+		linker: linker,
+	}
+}
+
+func (ts *transfers) Add(transfer *Transfer) error {
+	if transfer.Sum == 0 {
+		return ErrInvalidAmount
+	}
+	if transfer.FromUserID == transfer.ToUserID {
+		return ErrTransferToSelf
+	}
+
+	// суточный лимит считаем с начала текущих суток
+	now := time.Now()
+	transfer.CreatedAt = now
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	id, err := ts.linker.storage.AddTransfer(transfer, dayStart, ts.linker.transferDailyLimit)
+	if err != nil {
+		return err
+	}
+	transfer.ID = id
+
+	// изменились балансы обоих пользователей
+	ts.linker.Events.Publish(&BalanceChanged{UserID: transfer.FromUserID, Reason: "transfer"})
+	ts.linker.Events.Publish(&BalanceChanged{UserID: transfer.ToUserID, Reason: "transfer"})
+
+	return nil
+}
+
+func (ts *transfers) GetTransfers(userID uint64) ([]*Transfer, error) {
+	trs, err := ts.linker.storage.GetUserTransfers(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(trs) == 0 {
+		return nil, ErrNoContent
+	}
+
+	return trs, nil
+}
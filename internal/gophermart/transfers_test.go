@@ -0,0 +1,77 @@
+package gophermart_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestPostTransfer(t *testing.T) {
+	hasher, err := gophermart.NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+
+	st := newStubStorage()
+	gm := gophermart.New(st,
+		gophermart.WithPasswordHasher(hasher),
+		gophermart.WithTransferDailyLimit(500_00),
+	)
+	sender, err := gm.Register(&gophermart.Credentials{Login: "sender", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+	recipient, err := gm.Register(&gophermart.Credentials{Login: "recipient", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+	st.fund(sender.UserID, 1000_00)
+	st.fund(recipient.UserID, 100_00)
+	evs := recordEvents(gm)
+
+	tests := []struct {
+		name    string
+		userID  uint64
+		login   string
+		sum     gophermart.Amount
+		wantErr error
+	}{
+		{name: "zero sum", userID: sender.UserID, login: "recipient", sum: 0, wantErr: gophermart.ErrInvalidAmount},
+		{name: "to self", userID: sender.UserID, login: "sender", sum: 100_00, wantErr: gophermart.ErrTransferToSelf},
+		{name: "unknown recipient", userID: sender.UserID, login: "nobody", sum: 100_00, wantErr: gophermart.ErrUserNotFound},
+		{name: "not enough funds", userID: recipient.UserID, login: "sender", sum: 200_00, wantErr: gophermart.ErrNotEnoughFunds},
+		{name: "ok", userID: sender.UserID, login: "recipient", sum: 300_00},
+		{name: "ok up to limit", userID: sender.UserID, login: "recipient", sum: 200_00},
+		// у отправителя ещё 500 баллов, но суточный лимит исчерпан
+		{name: "daily limit", userID: sender.UserID, login: "recipient", sum: 1, wantErr: gophermart.ErrTransferLimitExceeded},
+		// лимит считается для каждого отправителя отдельно
+		{name: "reverse", userID: recipient.UserID, login: "sender", sum: 50_00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*evs = (*evs)[:0]
+			err := gm.PostTransfer(&gophermart.TransferProxy{Login: tt.login, Sum: tt.sum, UserID: tt.userID})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, *evs)
+				return
+			}
+			require.NoError(t, err)
+
+			// балансы обоих участников изменились: кэш должен сброситься для каждого
+			recipientID := sender.UserID
+			if tt.userID == sender.UserID {
+				recipientID = recipient.UserID
+			}
+			assert.Equal(t, []gophermart.Event{
+				&gophermart.BalanceChanged{UserID: tt.userID, Reason: "transfer"},
+				&gophermart.BalanceChanged{UserID: recipientID, Reason: "transfer"},
+			}, *evs)
+		})
+	}
+
+	bl, err := gm.GetBalance(sender.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.Amount(550_00), bl.Current)
+	bl, err = gm.GetBalance(recipient.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, gophermart.Amount(550_00), bl.Current)
+}
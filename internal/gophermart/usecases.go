@@ -1,21 +1,20 @@
 package gophermart
 
 import (
+	"errors"
 	"fmt"
-	"github.com/google/uuid"
 	"log"
 	"strconv"
-	"strings"
 	"time"
 )
 
-func (g *GopherMart) Register(creds *Credentials) (*Session, error) {
+func (g *GopherMart) Register(creds *Credentials, client *SessionClient) (*Session, error) {
 	_, err := g.Users.Add(creds)
 	if err != nil {
 		return nil, err
 	}
 
-	session, err := g.Login(creds, "")
+	session, err := g.Login(creds, "", client)
 	if err != nil {
 		return nil, err
 	}
@@ -23,9 +22,23 @@ func (g *GopherMart) Register(creds *Credentials) (*Session, error) {
 	return session, nil
 }
 
-func (g *GopherMart) Login(creds *Credentials, oldToken string) (*Session, error) {
+// checkCredentials находит пользователя по логину и проверяет пароль; хэш, полученный с прежними
+// настройками хэширования, пересчитывается — пароль в открытом виде известен только в этот момент.
+// После череды неудач вход по логину или с адреса клиента временно закрыт, даже с верным паролем
+func (g *GopherMart) checkCredentials(creds *Credentials, client *SessionClient) (*User, error) {
+	// попытка учитывается как неудачная, пока пароль не проверен
+	if err := g.Logins.Attempt(creds.Login, client); err != nil {
+		return nil, err
+	}
+
 	user, err := g.Users.Get(creds.Login)
 	if err != nil {
+		// перебор несуществующих логинов тоже считается
+		if !errors.Is(err, ErrUserNotFound) {
+			g.Logins.Cancel(creds.Login, client)
+			return nil, err
+		}
+		g.Users.CheckDummyPassword(creds.Password)
 		return nil, err
 	}
 
@@ -33,6 +46,24 @@ func (g *GopherMart) Login(creds *Credentials, oldToken string) (*Session, error
 	if !check {
 		return nil, ErrInvalidPair
 	}
+	g.Logins.Succeed(creds.Login, client)
+
+	if g.Users.NeedsRehash(user) {
+		// пароль верный, поэтому хэш пересчитываем сейчас; если сохранить его не удалось, вход не отклоняем —
+		// хэш пересчитается при следующем входе
+		if err = g.Users.UpdatePassword(user.ID, creds.Password); err != nil {
+			log.Println("[ERROR] Failed to rehash user password -", err)
+		}
+	}
+
+	return user, nil
+}
+
+func (g *GopherMart) Login(creds *Credentials, oldToken string, client *SessionClient) (*Session, error) {
+	user, err := g.checkCredentials(creds, client)
+	if err != nil {
+		return nil, err
+	}
 
 	if oldToken != "" {
 		err = g.Sessions.Delete(oldToken)
@@ -41,31 +72,127 @@ func (g *GopherMart) Login(creds *Credentials, oldToken string) (*Session, error
 		}
 	}
 
-	// Создадим новый токен при помощи библиотеки github.com/google/uuid
-	newToken := uuid.NewString()
-	expiresAt := time.Now().Add(600 * time.Second)
+	return g.Sessions.New(user.ID, creds.Remember, client)
+}
+
+func (g *GopherMart) Logout(token string) error {
+	err := g.Sessions.Delete(token)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Authenticate проверяет токен, предъявленный клиентом: в режиме JWT — подписанный токен доступа,
+// иначе токен сессии; refresh-токен для доступа к API не годится. Действующая сессия продлевается
+// на срок бездействия, renewed сообщает, что клиенту нужно обновить срок куки
+func (g *GopherMart) Authenticate(token string) (session *Session, renewed bool, err error) {
+	if g.Tokens.Enabled() && isJWT(token) {
+		session, err = g.Tokens.Verify(token)
+		return session, false, err
+	}
+
+	session, err = g.Sessions.Get(token)
+	if err != nil || session.Family != "" {
+		return nil, false, ErrSessionNotFound
+	}
 
-	// Запишем сессию в хранилище
-	s := &Session{
-		UserID: user.ID,
-		Token:  newToken,
-		Expiry: expiresAt,
+	// Удаляем сессию, если истёк срок бездействия или абсолютный срок
+	if session.IsExpired() {
+		g.Sessions.Delete(token)
+		return nil, false, ErrSessionExpired
 	}
-	err = g.Sessions.Add(s)
+
+	// из кэша сессия приходит без токена, вернём её с токеном, предъявленным клиентом
+	presented := *session
+	presented.Token = token
+	session = &presented
+
+	renewedSession, renewed, err := g.Sessions.Renew(session)
+	if err != nil {
+		// непродлённая сессия ещё действует, запрос не отклоняем
+		log.Println("[ERROR]", err)
+		return session, false, nil
+	}
+
+	return renewedSession, renewed, nil
+}
+
+// IssueTokens выдаёт пару токенов в режиме JWT по логину и паролю
+func (g *GopherMart) IssueTokens(creds *Credentials, client *SessionClient) (*TokenPair, error) {
+	if !g.Tokens.Enabled() {
+		return nil, ErrJWTDisabled
+	}
+
+	user, err := g.checkCredentials(creds, client)
 	if err != nil {
 		return nil, err
 	}
 
-	return s, nil
+	return g.Tokens.Issue(user.ID, client)
 }
 
-func (g *GopherMart) Logout(token string) error {
-	err := g.Sessions.Delete(token)
+func (g *GopherMart) RefreshTokens(refreshToken string) (*TokenPair, error) {
+	return g.Tokens.Refresh(refreshToken)
+}
+
+func (g *GopherMart) RevokeTokens(refreshToken string) error {
+	return g.Tokens.Revoke(refreshToken)
+}
+
+// GetSessions действующие сессии пользователя; currentID отмечает сессию, которой выполнен запрос
+func (g *GopherMart) GetSessions(userID uint64, currentID string) ([]*SessionProxy, error) {
+	sessions, err := g.Sessions.GetUserSessions(userID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, ErrNoContent
 	}
 
-	return nil
+	proxies := make([]*SessionProxy, 0, len(sessions))
+	for _, s := range sessions {
+		sessionType := SessionTypeCookie
+		if s.Family != "" {
+			sessionType = SessionTypeRefresh
+		}
+		proxies = append(proxies, &SessionProxy{
+			ID:         s.ID,
+			Type:       sessionType,
+			CreatedAt:  s.CreatedAt.Format(time.RFC3339),
+			LastUsedAt: s.LastUsedAt.Format(time.RFC3339),
+			ExpiresAt:  s.Expiry.Format(time.RFC3339),
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			Current:    s.ID == currentID,
+		})
+	}
+
+	return proxies, nil
+}
+
+// RevokeSession завершает сессию пользователя; токены доступа JWT отозванного семейства действуют до своего срока
+func (g *GopherMart) RevokeSession(userID uint64, id string) error {
+	return g.Sessions.DeleteUserSession(userID, id)
+}
+
+// RevokeAllSessions выход на всех устройствах
+func (g *GopherMart) RevokeAllSessions(userID uint64) error {
+	return g.Sessions.DeleteUserSessions(userID, "")
+}
+
+// ChangePassword меняет пароль пользователя; сессия currentID, которой выполнен запрос, остаётся действовать
+func (g *GopherMart) ChangePassword(userID uint64, oldPassword, newPassword, currentID string) error {
+	return g.Passwords.Change(userID, oldPassword, newPassword, currentID)
+}
+
+func (g *GopherMart) RequestPasswordReset(login string) error {
+	return g.Passwords.RequestReset(login)
+}
+
+func (g *GopherMart) ResetPassword(token, newPassword string) error {
+	return g.Passwords.Reset(token, newPassword)
 }
 
 func (g *GopherMart) PostOrders(orderID, userID uint64) error {
@@ -77,32 +204,33 @@ func (g *GopherMart) PostOrders(orderID, userID uint64) error {
 	return nil
 }
 
-func (g *GopherMart) GetOrders(userID uint64) ([]*OrderProxy, error) {
-	ors, err := g.Orders.GetUserOrders(userID)
+func (g *GopherMart) PostOrdersBatch(numbers []string, userID uint64) ([]*OrderBatchResult, error) {
+	results, err := g.Orders.AddBatch(numbers, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	//{ need
-	//	"number": "346436439",
-	//	"status": "INVALID",
-	//	"uploaded_at": "2020-12-09T16:09:53+03:00"
-	//}
-	layout := "2006-01-02T15:04:05-07:00"
+	return results, nil
+}
+
+// GetOrders возвращает страницу заказов пользователя и курсор следующей страницы, если она есть
+func (g *GopherMart) GetOrders(userID uint64, filter *OrdersFilter) ([]*OrderProxy, string, error) {
+	ors, cursor, err := g.Orders.GetUserOrders(userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
 
 	orsPr := make([]*OrderProxy, 0)
 	for _, o := range ors {
-		po := &OrderProxy{
-			Number:     fmt.Sprint(o.ID),
-			Status:     strings.TrimSpace(o.Status),
-			Accrual:    float64(o.Accrual) / 100,
-			UploadedAt: o.UploadedAt.Format(layout),
-		}
+		orsPr = append(orsPr, newOrderProxy(o))
+	}
 
-		orsPr = append(orsPr, po)
+	var next string
+	if cursor != nil {
+		next = cursor.Encode()
 	}
 
-	return orsPr, nil
+	return orsPr, next, nil
 }
 
 func (g *GopherMart) PostWithdraw(wpr *WithdrawProxy) error {
@@ -114,7 +242,7 @@ func (g *GopherMart) PostWithdraw(wpr *WithdrawProxy) error {
 	withdraw := &Withdraw{
 		OrderID: uint64(orderID),
 		UserID:  wpr.UserID,
-		Sum:     uint64(wpr.Sum * 100),
+		Sum:     uint64(wpr.Sum),
 	}
 
 	err = g.Withdrawals.Add(withdraw)
@@ -131,11 +259,11 @@ func (g *GopherMart) GetWithdrawals(userID uint64) ([]*WithdrawProxy, error) {
 		return nil, err
 	}
 
-	wdsPr := make([]*WithdrawProxy, len(wds))
+	wdsPr := make([]*WithdrawProxy, 0, len(wds))
 	for _, v := range wds {
 		wpr := &WithdrawProxy{
 			Order:       fmt.Sprint(v.OrderID),
-			Sum:         float64(v.Sum) / 100,
+			Sum:         Amount(v.Sum),
 			ProcessedAt: v.ProcessedAt.Format(time.RFC3339),
 		}
 		wdsPr = append(wdsPr, wpr)
@@ -150,11 +278,252 @@ func (g *GopherMart) GetBalance(userID uint64) (*BalanceProxy, error) {
 		return nil, err
 	}
 
-	// храним баланс в копейках, отдаём в рублях: поэтому делим на 100
+	// храним баланс в копейках, отдаём в рублях: перевод делает тип Amount
 	blPr := &BalanceProxy{
-		Current:   float64(bl.Current) / 100,
-		Withdrawn: float64(bl.Withdrawn) / 100,
+		Current:   Amount(bl.Current),
+		Withdrawn: Amount(bl.Withdrawn),
 	}
 
 	return blPr, nil
 }
+
+func (g *GopherMart) PostHold(hpr *HoldProxy) (*HoldProxy, error) {
+	orderID, err := strconv.Atoi(hpr.Order)
+	if err != nil {
+		return nil, ErrOrderInvalidFormat
+	}
+
+	hold := &Hold{
+		OrderID: uint64(orderID),
+		UserID:  hpr.UserID,
+		Sum:     uint64(hpr.Sum),
+	}
+
+	err = g.Holds.Add(hold)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHoldProxy(hold), nil
+}
+
+func (g *GopherMart) CaptureHold(holdID, userID uint64) (*HoldProxy, error) {
+	hold, err := g.Holds.Capture(holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHoldProxy(hold), nil
+}
+
+func (g *GopherMart) ReleaseHold(holdID, userID uint64) (*HoldProxy, error) {
+	hold, err := g.Holds.Release(holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHoldProxy(hold), nil
+}
+
+func newHoldProxy(h *Hold) *HoldProxy {
+	return &HoldProxy{
+		ID:        h.ID,
+		Order:     fmt.Sprint(h.OrderID),
+		Sum:       Amount(h.Sum),
+		UserID:    h.UserID,
+		Status:    h.Status,
+		CreatedAt: h.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: h.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+func (g *GopherMart) PostTransfer(tpr *TransferProxy) error {
+	recipient, err := g.Users.Get(tpr.Login)
+	if err != nil {
+		return err
+	}
+
+	transfer := &Transfer{
+		FromUserID: tpr.UserID,
+		ToUserID:   recipient.ID,
+		Sum:        uint64(tpr.Sum),
+	}
+
+	err = g.Transfers.Add(transfer)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (g *GopherMart) GetTransfers(userID uint64) ([]*TransferProxy, error) {
+	trs, err := g.Transfers.GetTransfers(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	trsPr := make([]*TransferProxy, 0, len(trs))
+	for _, v := range trs {
+		direction, counterparty := TransferDirectionOut, v.ToLogin
+		if v.ToUserID == userID {
+			direction, counterparty = TransferDirectionIn, v.FromLogin
+		}
+
+		tpr := &TransferProxy{
+			Login:       counterparty,
+			Sum:         Amount(v.Sum),
+			Direction:   direction,
+			ProcessedAt: v.CreatedAt.Format(time.RFC3339),
+		}
+		trsPr = append(trsPr, tpr)
+	}
+
+	return trsPr, nil
+}
+
+// PostAdjustment ручная корректировка баланса пользователя, доступна только администраторам
+func (g *GopherMart) PostAdjustment(apr *AdjustmentProxy) (*AdjustmentProxy, error) {
+	user, err := g.Users.Get(apr.Login)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustment := &Adjustment{
+		UserID:     user.ID,
+		Type:       apr.Type,
+		Sum:        uint64(apr.Sum),
+		Reason:     apr.Reason,
+		OperatorID: apr.OperatorID,
+	}
+
+	err = g.Adjustments.Add(adjustment)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Balance of user `%s` adjusted by operator `%s`: %s %s, reason: %s\n",
+		user.Login, adjustment.OperatorID, adjustment.Type, Amount(adjustment.Sum), adjustment.Reason)
+
+	apr.ID = adjustment.ID
+	apr.ProcessedAt = adjustment.CreatedAt.Format(time.RFC3339)
+
+	return apr, nil
+}
+
+// GetAdjustments история корректировок для самого пользователя: без идентификатора оператора
+func (g *GopherMart) GetAdjustments(userID uint64) ([]*AdjustmentProxy, error) {
+	ads, err := g.Adjustments.GetAdjustments(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	adsPr := make([]*AdjustmentProxy, 0, len(ads))
+	for _, v := range ads {
+		apr := &AdjustmentProxy{
+			ID:          v.ID,
+			Type:        v.Type,
+			Sum:         Amount(v.Sum),
+			Reason:      v.Reason,
+			ProcessedAt: v.CreatedAt.Format(time.RFC3339),
+		}
+		adsPr = append(adsPr, apr)
+	}
+
+	return adsPr, nil
+}
+
+// GetStatement выписка по счёту: начисления, списания, корректировки, переводы и активные резервы
+// в хронологическом порядке с балансом после каждой операции
+func (g *GopherMart) GetStatement(userID uint64, filter *StatementFilter) ([]*StatementEntryProxy, error) {
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, ErrInvalidPeriod
+	}
+
+	entries, err := g.storage.GetUserStatement(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrNoContent
+	}
+
+	sePr := make([]*StatementEntryProxy, 0, len(entries))
+	for _, e := range entries {
+		sePr = append(sePr, newStatementEntryProxy(userID, e))
+	}
+
+	return sePr, nil
+}
+
+// PostWebhook создаёт подписку пользователя, а при нулевом userID — глобальную подписку
+func (g *GopherMart) PostWebhook(whpr *WebhookProxy, userID uint64) (*WebhookProxy, error) {
+	wh := &Webhook{
+		UserID: userID,
+		URL:    whpr.URL,
+		Events: whpr.Events,
+	}
+
+	err := g.Webhooks.Add(wh)
+	if err != nil {
+		return nil, err
+	}
+
+	whpr = newWebhookProxy(wh)
+	whpr.Secret = wh.Secret
+
+	return whpr, nil
+}
+
+func (g *GopherMart) GetWebhooks(userID uint64) ([]*WebhookProxy, error) {
+	hooks, err := g.Webhooks.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	whsPr := make([]*WebhookProxy, 0, len(hooks))
+	for _, wh := range hooks {
+		whsPr = append(whsPr, newWebhookProxy(wh))
+	}
+
+	return whsPr, nil
+}
+
+func (g *GopherMart) DeleteWebhook(webhookID, userID uint64) error {
+	return g.Webhooks.Delete(webhookID, userID)
+}
+
+func (g *GopherMart) GetWebhookAttempts(webhookID, userID uint64) ([]*WebhookAttemptProxy, error) {
+	attempts, err := g.Webhooks.GetAttempts(webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	attemptsPr := make([]*WebhookAttemptProxy, 0, len(attempts))
+	for _, a := range attempts {
+		attemptsPr = append(attemptsPr, &WebhookAttemptProxy{
+			DeliveryID:  a.DeliveryID,
+			Event:       a.Event,
+			Attempt:     a.Attempt,
+			StatusCode:  a.StatusCode,
+			Error:       a.Error,
+			AttemptedAt: a.AttemptedAt.Format(time.RFC3339),
+		})
+	}
+
+	return attemptsPr, nil
+}
+
+func newWebhookProxy(wh *Webhook) *WebhookProxy {
+	events := wh.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	return &WebhookProxy{
+		ID:        wh.ID,
+		URL:       wh.URL,
+		Events:    events,
+		CreatedAt: wh.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -2,7 +2,7 @@ package gophermart
 
 import (
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
+	"log"
 	"sync"
 )
 
@@ -13,38 +13,68 @@ type User struct {
 }
 
 func (u *User) CheckPassword(password string) bool {
-	if err := bcrypt.CompareHashAndPassword(u.Password, []byte(password)); err != nil {
-		return false
-	}
-
-	return true
+	return verifyPassword(u.Password, password)
 }
 
 type Users struct {
 	mu      sync.RWMutex
 	storage Storer
+	events  *events
+	hasher  *PasswordHasher
+	policy  CredentialsPolicy
 	byLogin map[string]*User
 	byID    map[uint64]*User
+
+	// хэш-пустышка для проверки пароля несуществующего пользователя, считается при первой такой проверке
+	dummyOnce sync.Once
+	dummyHash []byte
 }
 
-func newUsers(st Storer) *Users {
+func newUsers(gm *GopherMart) *Users {
 	return &Users{
-		storage: st,
+		storage: gm.storage,
+		events:  gm.Events,
+		hasher:  gm.passwordHasher,
+		policy:  gm.credentialsPolicy,
 		byLogin: make(map[string]*User),
 		byID:    make(map[uint64]*User),
 	}
 }
 
-func HashPass(password string) ([]byte, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 8)
-	if err != nil {
-		return nil, err
-	}
+// ValidatePassword проверяет новый пароль пользователя по требованиям к паролю
+func (urs *Users) ValidatePassword(login, password string) error {
+	return urs.policy.ValidatePassword(login, password)
+}
+
+// NeedsRehash хэш пароля пользователя получен не с текущими настройками хэширования
+func (urs *Users) NeedsRehash(u *User) bool {
+	return urs.hasher.NeedsRehash(u.Password)
+}
+
+// CheckDummyPassword сверяет пароль с хэшем-пустышкой, полученным с текущими настройками хэширования:
+// вход под несуществующим логином длится столько же, сколько вход с неверным паролем, и по времени ответа
+// нельзя узнать, зарегистрирован ли логин
+func (urs *Users) CheckDummyPassword(password string) {
+	urs.dummyOnce.Do(func() {
+		hash, err := urs.hasher.Hash("dummy password")
+		if err != nil {
+			log.Println("[ERROR] Failed to hash dummy password -", err)
+			return
+		}
+		urs.dummyHash = hash
+	})
 
-	return hashedPassword, nil
+	verifyPassword(urs.dummyHash, password)
 }
 
 func (urs *Users) Add(creds *Credentials) (uint64, error) {
+	if err := urs.policy.ValidateLogin(creds.Login); err != nil {
+		return 0, err
+	}
+	if err := urs.policy.ValidatePassword(creds.Login, creds.Password); err != nil {
+		return 0, err
+	}
+
 	// проверим наличие пользователя в кэше
 	urs.mu.RLock()
 	_, ok := urs.byLogin[creds.Login]
@@ -53,7 +83,7 @@ func (urs *Users) Add(creds *Credentials) (uint64, error) {
 		return 0, ErrLoginAlreadyTaken
 	}
 
-	hashedPassword, err := HashPass(creds.Password)
+	hashedPassword, err := urs.hasher.Hash(creds.Password)
 	if err != nil {
 		return 0, err
 	}
@@ -75,6 +105,8 @@ func (urs *Users) Add(creds *Credentials) (uint64, error) {
 	urs.byID[u.ID] = u
 	urs.mu.Unlock()
 
+	urs.events.Publish(NewUserRegistered(u))
+
 	return u.ID, nil
 }
 
@@ -110,6 +142,35 @@ func (urs *Users) Get(byKey interface{}) (*User, error) {
 	return u, nil
 }
 
+// UpdatePassword задаёт пользователю новый пароль без проверки требований: пересчёт хэша при входе
+// сохраняет и пароль, не отвечающий новым требованиям. В кэше пользователь заменяется копией,
+// чтобы не менять структуру, которую уже читают обработчики запросов
+func (urs *Users) UpdatePassword(userID uint64, password string) error {
+	u, err := urs.Get(userID)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := urs.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	err = urs.storage.UpdateUserPassword(userID, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	updated := *u
+	updated.Password = hashedPassword
+	urs.mu.Lock()
+	urs.byLogin[updated.Login] = &updated
+	urs.byID[updated.ID] = &updated
+	urs.mu.Unlock()
+
+	return nil
+}
+
 func (urs *Users) Delete(login string) error {
 	urs.mu.Lock()
 	delete(urs.byLogin, login)
@@ -0,0 +1,68 @@
+package gophermart
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const webhookResolveTimeout = 5 * time.Second
+
+// sharedAddressSpace адреса операторского NAT (RFC 6598): снаружи недоступны, как и частные сети
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// webhookAddressAllowed можно ли отправлять вебхуки на адрес: иначе подписка превращается в способ
+// обращаться от имени сервиса к его внутренней сети, в том числе к сервису метаданных облака
+func webhookAddressAllowed(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() && !sharedAddressSpace.Contains(ip)
+}
+
+// checkWebhookHost проверяет при создании подписки все адреса, в которые разрешается хост вебхука
+func checkWebhookHost(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookResolveTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: failed to resolve host", ErrInvalidWebhookURL)
+	}
+	for _, addr := range addrs {
+		if !webhookAddressAllowed(addr.IP) {
+			return fmt.Errorf("%w: host resolves to a private address", ErrInvalidWebhookURL)
+		}
+	}
+
+	return nil
+}
+
+// webhookTransport проверяет адрес непосредственно перед соединением: хост мог сменить адрес после создания
+// подписки, а подписчик — перенаправить запрос на внутренний адрес. Прокси не используем, иначе проверялся бы
+// адрес прокси, а не подписчика
+func webhookTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   dispatcherTimeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !webhookAddressAllowed(ip) {
+				return fmt.Errorf("webhook address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
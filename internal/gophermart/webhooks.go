@@ -0,0 +1,161 @@
+package gophermart
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryFailed    = "FAILED"
+
+	webhookSecretBytes   = 32
+	webhookAttemptsLimit = 100 // сколько последних попыток доставки отдавать в журнале
+)
+
+// Webhook подписка на события: пользовательская или, при нулевом UserID, глобальная
+type Webhook struct {
+	ID        uint64
+	UserID    uint64
+	URL       string
+	Secret    string
+	Events    []string // пустой список означает подписку на все события
+	CreatedAt time.Time
+}
+
+type WebhookProxy struct {
+	ID        uint64   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Secret    string   `json:"secret,omitempty"` // секрет подписи показываем только при создании
+	CreatedAt string   `json:"created_at"`
+}
+
+// WebhookDelivery доставка одного события одному подписчику
+type WebhookDelivery struct {
+	ID        uint64
+	OutboxID  uint64
+	WebhookID uint64
+	URL       string
+	Secret    string
+	Event     string
+	Payload   []byte
+	Attempts  uint32
+}
+
+// WebhookAttempt запись журнала о попытке доставки
+type WebhookAttempt struct {
+	DeliveryID  uint64
+	Event       string
+	Attempt     uint32
+	StatusCode  int
+	Error       string
+	AttemptedAt time.Time
+}
+
+type WebhookAttemptProxy struct {
+	DeliveryID  uint64 `json:"delivery_id"`
+	Event       string `json:"event"`
+	Attempt     uint32 `json:"attempt"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	Error       string `json:"error,omitempty"`
+	AttemptedAt string `json:"attempted_at"`
+}
+
+// SignWebhook подпись тела вебхука: HMAC-SHA256 от строки `<timestamp>.<body>` в hex,
+// метка времени в подписи не даёт повторно использовать перехваченный запрос
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhooks struct {
+	linker *GopherMart
+	// разрешить вебхуки на адреса внутренних сетей: только для локальной разработки и тестов
+	allowPrivate bool
+}
+
+func newWebhooks(linker *GopherMart) *webhooks {
+	return &webhooks{
+		linker:       linker,
+		allowPrivate: linker.webhooksAllowPrivate,
+	}
+}
+
+func (whs *webhooks) Add(wh *Webhook) error {
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	if !whs.allowPrivate {
+		if err = checkWebhookHost(u.Hostname()); err != nil {
+			return err
+		}
+	}
+
+	events := make([]string, 0, len(wh.Events))
+	for _, event := range wh.Events {
+		event = strings.TrimSpace(event)
+		if !IsOutboxEvent(event) {
+			return ErrInvalidWebhookEvent
+		}
+		events = append(events, event)
+	}
+	wh.Events = events
+
+	secret := make([]byte, webhookSecretBytes)
+	if _, err = rand.Read(secret); err != nil {
+		return err
+	}
+	wh.Secret = hex.EncodeToString(secret)
+	wh.CreatedAt = time.Now()
+
+	id, err := whs.linker.storage.AddWebhook(wh)
+	if err != nil {
+		return err
+	}
+	wh.ID = id
+
+	return nil
+}
+
+func (whs *webhooks) Get(userID uint64) ([]*Webhook, error) {
+	hooks, err := whs.linker.storage.GetWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hooks) == 0 {
+		return nil, ErrNoContent
+	}
+
+	return hooks, nil
+}
+
+func (whs *webhooks) Delete(webhookID, userID uint64) error {
+	return whs.linker.storage.DeleteWebhook(webhookID, userID)
+}
+
+func (whs *webhooks) GetAttempts(webhookID, userID uint64) ([]*WebhookAttempt, error) {
+	attempts, err := whs.linker.storage.GetWebhookAttempts(webhookID, userID, webhookAttemptsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(attempts) == 0 {
+		return nil, ErrNoContent
+	}
+
+	return attempts, nil
+}
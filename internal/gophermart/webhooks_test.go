@@ -0,0 +1,75 @@
+package gophermart
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"event":"order.processed"}`)
+
+	// эталон: echo -n '1700000000.{"event":"order.processed"}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=72bc88175ee04ab1dc7920d68159ea12673d969c95646f773ea186080944b90b"
+	got := SignWebhook("secret", 1700000000, body)
+	assert.Equal(t, want, got)
+	assert.NotEqual(t, got, SignWebhook("secret", 1700000001, body))
+	assert.NotEqual(t, got, SignWebhook("another", 1700000000, body))
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt uint32
+		want    time.Duration
+	}{
+		{attempt: 1, want: 10 * time.Second},
+		{attempt: 2, want: 20 * time.Second},
+		{attempt: 4, want: 80 * time.Second},
+		{attempt: 10, want: time.Hour},
+		{attempt: 100, want: time.Hour},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, backoff(tt.attempt))
+	}
+}
+
+func TestWebhookAddressAllowed(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "100.64.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "fd00::1"},
+		{ip: "fe80::1"},
+		{ip: "::ffff:127.0.0.1"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, webhookAddressAllowed(net.ParseIP(tt.ip)), tt.ip)
+	}
+}
+
+func TestWebhookPrivateAddresses(t *testing.T) {
+	whs := &webhooks{}
+	for _, u := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://[::1]/hook", "http://169.254.169.254/latest"} {
+		assert.ErrorIs(t, whs.Add(&Webhook{URL: u}), ErrInvalidWebhookURL, u)
+	}
+
+	// адрес проверяется и при соединении: хост мог сменить адрес после создания подписки
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, err := (&http.Client{Transport: webhookTransport()}).Get(srv.URL)
+	assert.Error(t, err)
+}
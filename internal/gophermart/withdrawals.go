@@ -14,10 +14,10 @@ type Withdraw struct {
 }
 
 type WithdrawProxy struct {
-	Order       string  `json:"order"`
-	Sum         float64 `json:"sum"`
-	UserID      uint64  `json:"-"`
-	ProcessedAt string  `json:"processed_at"`
+	Order       string `json:"order"`
+	Sum         Amount `json:"sum"`
+	UserID      uint64 `json:"-"`
+	ProcessedAt string `json:"processed_at"`
 }
 
 type withdrawals struct {
@@ -55,10 +55,7 @@ func (ws *withdrawals) Add(withdraw *Withdraw) error {
 		return err
 	}
 
-	// баланс изменился, удалим запись из кэша баланса
-	ws.linker.Balances.mu.Lock()
-	delete(ws.linker.Balances.byUserID, withdraw.UserID)
-	ws.linker.Balances.mu.Unlock()
+	ws.linker.Events.Publish(NewWithdrawalMade(withdraw))
 
 	return nil
 }
@@ -0,0 +1,37 @@
+package gophermart_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestGetWithdrawals(t *testing.T) {
+	st := basicstorage.New()
+	gm := gophermart.New(st)
+	session, err := gm.Register(&gophermart.Credentials{Login: "gopher", Password: "Passw0rd33"}, nil)
+	require.NoError(t, err)
+
+	_, err = gm.GetWithdrawals(session.UserID)
+	assert.ErrorIs(t, err, gophermart.ErrNoContent)
+
+	require.NoError(t, st.UpdateOrder(&gophermart.Order{
+		ID: 12345678903, UserID: session.UserID, Status: gophermart.StatusProcessed, Accrual: 1000_00,
+	}))
+	for _, order := range []string{"2377225624", "79927398713"} {
+		require.NoError(t, gm.PostWithdraw(&gophermart.WithdrawProxy{Order: order, Sum: 100_00, UserID: session.UserID}))
+	}
+
+	// список содержит только списания, без пустых элементов перед ними
+	wsPr, err := gm.GetWithdrawals(session.UserID)
+	require.NoError(t, err)
+	require.Len(t, wsPr, 2)
+	for _, w := range wsPr {
+		require.NotNil(t, w)
+		assert.Equal(t, gophermart.Amount(100_00), w.Sum)
+	}
+}
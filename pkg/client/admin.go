@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Методы административного API требуют токен, заданный опцией WithAdminToken
+
+// AdminAdjust ручная корректировка баланса пользователя: тип credit или debit и причина обязательны,
+// оператора сервис определяет по токену администратора
+func (c *Client) AdminAdjust(ctx context.Context, adjustment *Adjustment) (*Adjustment, error) {
+	result := &Adjustment{}
+	req := c.adminRequest(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(adjustment).
+		SetResult(result)
+	if _, err := do(req, http.MethodPost, "/api/admin/balance/adjustments"); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// AdminExport выгружает историю всех пользователей
+func (c *Client) AdminExport(ctx context.Context, query *ExportQuery, w io.Writer) error {
+	return c.export(ctx, "/api/admin/export", query, w, true)
+}
+
+// AdminCreateWebhook глобальная подписка на события всех пользователей
+func (c *Client) AdminCreateWebhook(ctx context.Context, url string, events ...string) (*Webhook, error) {
+	return createWebhook(c.adminRequest(ctx), "/api/admin", url, events)
+}
+
+func (c *Client) AdminGetWebhooks(ctx context.Context) ([]*Webhook, error) {
+	return getWebhooks(c.adminRequest(ctx), "/api/admin")
+}
+
+func (c *Client) AdminDeleteWebhook(ctx context.Context, webhookID uint64) error {
+	return deleteWebhook(c.adminRequest(ctx), "/api/admin", webhookID)
+}
+
+func (c *Client) AdminGetWebhookDeliveries(ctx context.Context, webhookID uint64) ([]*WebhookAttempt, error) {
+	return getWebhookDeliveries(c.adminRequest(ctx), "/api/admin", webhookID)
+}
+
+// AdminGetMetrics счётчики сервиса, например число действующих сессий `sessions_active`
+func (c *Client) AdminGetMetrics(ctx context.Context) (map[string]int64, error) {
+	metrics := make(map[string]int64)
+	if _, err := do(c.adminRequest(ctx).SetResult(&metrics), http.MethodGet, "/api/admin/metrics"); err != nil {
+		return nil, err
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StatementQuery параметры выписки, пустые поля не передаются
+type StatementQuery struct {
+	From   time.Time
+	To     time.Time
+	Limit  uint64
+	Offset uint64
+}
+
+func (c *Client) GetBalance(ctx context.Context) (*Balance, error) {
+	balance := &Balance{}
+	if _, err := do(c.request(ctx).SetResult(balance), http.MethodGet, "/api/user/balance"); err != nil {
+		return nil, err
+	}
+
+	return balance, nil
+}
+
+// Withdraw списывает баллы в счёт оплаты заказа; при нехватке средств возвращает ErrNotEnoughFunds
+func (c *Client) Withdraw(ctx context.Context, order string, sum Amount) error {
+	if err := checkOrder(order); err != nil {
+		return err
+	}
+
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&Withdrawal{Order: order, Sum: sum})
+	_, err := do(req, http.MethodPost, "/api/user/balance/withdraw")
+
+	return err
+}
+
+func (c *Client) GetWithdrawals(ctx context.Context) ([]*Withdrawal, error) {
+	var withdrawals []*Withdrawal
+	_, err := do(c.request(ctx).SetResult(&withdrawals), http.MethodGet, "/api/user/balance/withdrawals")
+	if err != nil && !errors.Is(err, errNoContent) {
+		return nil, err
+	}
+
+	return withdrawals, nil
+}
+
+// Transfer переводит баллы пользователю с указанным логином
+func (c *Client) Transfer(ctx context.Context, login string, sum Amount) error {
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&Transfer{Login: login, Sum: sum})
+	_, err := do(req, http.MethodPost, "/api/user/balance/transfer")
+
+	return err
+}
+
+func (c *Client) GetTransfers(ctx context.Context) ([]*Transfer, error) {
+	var transfers []*Transfer
+	_, err := do(c.request(ctx).SetResult(&transfers), http.MethodGet, "/api/user/balance/transfers")
+	if err != nil && !errors.Is(err, errNoContent) {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (c *Client) GetAdjustments(ctx context.Context) ([]*Adjustment, error) {
+	var adjustments []*Adjustment
+	_, err := do(c.request(ctx).SetResult(&adjustments), http.MethodGet, "/api/user/balance/adjustments")
+	if err != nil && !errors.Is(err, errNoContent) {
+		return nil, err
+	}
+
+	return adjustments, nil
+}
+
+func (c *Client) GetStatement(ctx context.Context, query *StatementQuery) ([]*StatementEntry, error) {
+	var entries []*StatementEntry
+	req := c.request(ctx).SetResult(&entries)
+	if query != nil {
+		setPeriod(req.QueryParam, query.From, query.To)
+		if query.Limit > 0 {
+			req.SetQueryParam("limit", strconv.FormatUint(query.Limit, 10))
+		}
+		if query.Offset > 0 {
+			req.SetQueryParam("offset", strconv.FormatUint(query.Offset, 10))
+		}
+	}
+
+	_, err := do(req, http.MethodGet, "/api/user/balance/statement")
+	if err != nil && !errors.Is(err, errNoContent) {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,190 @@
+// Package client клиент HTTP API накопительной системы лояльности «Гофермарт»:
+// хранит куку сессии, проверяет номера заказов по алгоритму Луна до отправки,
+// переводит коды ответов в типизированные ошибки и повторяет запросы при 429 и 5xx:
+// при 429 ждёт столько, сколько просит сервер в `Retry-After`, но не дольше, чем позволяют настройки повторов.
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+	"github.com/sergeysynergy/hardtest/pkg/loon"
+)
+
+const (
+	defaultTimeout       = 10 * time.Second
+	defaultRetryCount    = 3
+	defaultRetryWait     = 100 * time.Millisecond
+	defaultRetryMaxWait  = 2 * time.Second
+	contentTypeJSON      = "application/json"
+	contentTypeTextPlain = "text/plain"
+	headerNextCursor     = "X-Next-Cursor"
+	headerCSRFToken      = "X-CSRF-Token"
+)
+
+// куки с CSRF-токеном сессии, имя зависит от того, включён ли на сервере префикс `__Host-`
+var csrfCookies = map[string]bool{"csrf_token": true, "__Host-csrf_token": true}
+
+// Типы запросов и ответов совпадают с теми, что использует сервер, поэтому не расходятся с API
+type (
+	Amount           = gophermart.Amount
+	Credentials      = gophermart.Credentials
+	TokenPair        = gophermart.TokenPair
+	Session          = gophermart.SessionProxy
+	Order            = gophermart.OrderProxy
+	OrderBatchResult = gophermart.OrderBatchResult
+	Balance          = gophermart.BalanceProxy
+	Withdrawal       = gophermart.WithdrawProxy
+	Transfer         = gophermart.TransferProxy
+	Adjustment       = gophermart.AdjustmentProxy
+	StatementEntry   = gophermart.StatementEntryProxy
+	Hold             = gophermart.HoldProxy
+	Webhook          = gophermart.WebhookProxy
+	WebhookAttempt   = gophermart.WebhookAttemptProxy
+	HistoryRecord    = gophermart.HistoryRecordProxy
+	Notification     = gophermart.Notification
+)
+
+// ParseAmount разбирает сумму в рублях, например `729.98`
+var ParseAmount = gophermart.ParseAmount
+
+type Client struct {
+	client     *resty.Client
+	adminToken string
+}
+
+type Option func(*Client)
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		// resty по умолчанию хранит куки в собственном cookie jar: кука сессии подставляется сама
+		client: resty.New().
+			SetTransport(newTransport()).
+			SetBaseURL(baseURL).
+			SetTimeout(defaultTimeout).
+			SetRetryCount(defaultRetryCount).
+			SetRetryWaitTime(defaultRetryWait).
+			SetRetryMaxWaitTime(defaultRetryMaxWait).
+			SetRetryAfter(retryAfterWait),
+	}
+	c.client.AddRetryCondition(c.retryable)
+	c.client.OnBeforeRequest(setCSRFToken)
+	// применяем в цикле каждую опцию
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// setCSRFToken повторяет CSRF-токен из куки в заголовке, как это делает браузерный клиент
+func setCSRFToken(client *resty.Client, req *resty.Request) error {
+	jar := client.GetClient().Jar
+	if jar == nil {
+		return nil
+	}
+	baseURL, err := url.Parse(client.BaseURL)
+	if err != nil {
+		return nil
+	}
+
+	for _, cookie := range jar.Cookies(baseURL) {
+		if csrfCookies[cookie.Name] {
+			req.SetHeader(headerCSRFToken, cookie.Value)
+		}
+	}
+
+	return nil
+}
+
+// WithAdminToken токен административного API
+func WithAdminToken(token string) Option {
+	return func(c *Client) {
+		c.adminToken = token
+	}
+}
+
+// WithRetries число повторов запроса и пауза перед первым повтором, дальше пауза растёт экспоненциально
+func WithRetries(count int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.client.SetRetryCount(count).SetRetryWaitTime(wait)
+	}
+}
+
+// WithRetryMaxWait наибольшая пауза между повторами; если сервер в `Retry-After` просит ждать дольше,
+// запрос не повторяется и сразу возвращается ErrTooManyRequests
+func WithRetryMaxWait(wait time.Duration) Option {
+	return func(c *Client) {
+		c.client.SetRetryMaxWaitTime(wait)
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.client.SetTimeout(timeout)
+	}
+}
+
+// retryable повторяем запросы, отклонённые из-за лимита, если сервер не просит ждать дольше допустимой паузы,
+// а ошибки сети и сервера — только для идемпотентных методов: повтор перевода или загрузки заказа может
+// выполнить операцию дважды
+func (c *Client) retryable(resp *resty.Response, err error) bool {
+	if resp == nil || resp.Request == nil {
+		return false
+	}
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		wait, _ := retryAfter(resp.Header())
+		return wait <= c.client.RetryMaxWaitTime
+	}
+
+	switch resp.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return err != nil || resp.StatusCode() >= http.StatusInternalServerError
+	}
+
+	return false
+}
+
+func (c *Client) request(ctx context.Context) *resty.Request {
+	return c.client.R().SetContext(ctx)
+}
+
+func (c *Client) adminRequest(ctx context.Context) *resty.Request {
+	return c.request(ctx).SetAuthToken(c.adminToken)
+}
+
+// checkOrder проверяет номер заказа до отправки, чтобы не тратить на заведомо неверный номер запрос
+func checkOrder(number string) error {
+	if !loon.IsValid(number) {
+		return ErrInvalidOrderNumber
+	}
+
+	return nil
+}
+
+// errNoContent ответ 204: для списков это не ошибка, а пустой результат
+var errNoContent = errors.New("no content")
+
+// do выполняет запрос и возвращает ошибку для любого неуспешного ответа
+func do(req *resty.Request, method, url string) (*resty.Response, error) {
+	resp, err := req.Execute(method, url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		e := newError(resp.StatusCode(), resp.Body())
+		e.RetryAfter, _ = retryAfter(resp.Header())
+		return nil, e
+	}
+	if resp.StatusCode() == http.StatusNoContent {
+		return resp, errNoContent
+	}
+
+	return resp, nil
+}
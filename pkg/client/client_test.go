@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/hardtest/internal/api/handlers"
+	"github.com/sergeysynergy/hardtest/internal/basicstorage"
+	"github.com/sergeysynergy/hardtest/internal/gophermart"
+)
+
+func TestClient(t *testing.T) {
+	// клиент сам передаёт CSRF-токен из куки
+	srv := httptest.NewServer(handlers.New(gophermart.New(basicstorage.New()), handlers.WithCSRF(true)).GetRouter())
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := New(srv.URL)
+
+	require.NoError(t, c.Register(ctx, "gopher", "Passw0rd33"))
+	assert.ErrorIs(t, c.Register(ctx, "gopher", "Passw0rd33"), ErrConflict)
+	assert.ErrorIs(t, New(srv.URL).Login(ctx, "gopher", "wrong"), ErrUnauthorized)
+
+	_, err := c.PostOrder(ctx, "12345678901")
+	assert.ErrorIs(t, err, ErrInvalidOrderNumber)
+
+	accepted, err := c.PostOrder(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.True(t, accepted)
+	accepted, err = c.PostOrder(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.False(t, accepted)
+
+	other := New(srv.URL)
+	require.NoError(t, other.Register(ctx, "rabbit", "Passw0rd33"))
+	_, err = other.PostOrder(ctx, "12345678903")
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	assert.NotEmpty(t, apiErr.Message)
+
+	orders, _, err := c.GetOrders(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "12345678903", orders[0].Number)
+
+	balance, err := c.GetBalance(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Amount(0), balance.Current)
+
+	assert.ErrorIs(t, c.Withdraw(ctx, "2377225624", 751_00), ErrNotEnoughFunds)
+	withdrawals, err := c.GetWithdrawals(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, withdrawals)
+
+	// вход на втором устройстве виден в списке сессий и завершается с первого
+	device := New(srv.URL)
+	require.NoError(t, device.Login(ctx, "gopher", "Passw0rd33"))
+	sessions, err := c.GetSessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	for _, s := range sessions {
+		if !s.Current {
+			require.NoError(t, c.RevokeSession(ctx, s.ID))
+		}
+	}
+	_, err = device.GetBalance(ctx)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.ErrorIs(t, c.RevokeSession(ctx, "unknown"), ErrNotFound)
+
+	assert.ErrorIs(t, c.ChangePassword(ctx, "wrong", "N3wPassw0rd"), ErrForbidden)
+	require.NoError(t, c.ChangePassword(ctx, "Passw0rd33", "N3wPassw0rd"))
+	assert.ErrorIs(t, c.RequestPasswordReset(ctx, "gopher"), ErrNotFound, "password reset is disabled")
+
+	require.NoError(t, c.Logout(ctx))
+	_, err = c.GetBalance(ctx)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statuses   []int
+		retryAfter string
+		calls      int32
+		wantErr    error
+	}{
+		{name: "get retried on server error", method: http.MethodGet, statuses: []int{503, 503, 200}, calls: 3},
+		{name: "post not retried on server error", method: http.MethodPost, statuses: []int{503, 200}, calls: 1, wantErr: ErrServer},
+		{name: "post retried on rate limit", method: http.MethodPost, statuses: []int{429, 200}, calls: 2},
+		{name: "retried after short Retry-After", method: http.MethodGet, statuses: []int{429, 200}, retryAfter: "0", calls: 2},
+		{name: "not retried after long Retry-After", method: http.MethodGet, statuses: []int{429, 200}, retryAfter: "3600", calls: 1, wantErr: ErrTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			c := New(srv.URL, WithRetries(3, time.Millisecond))
+			_, err := do(c.request(context.Background()), tt.method, "/")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				if tt.retryAfter == "3600" {
+					var apiErr *Error
+					require.ErrorAs(t, err, &apiErr)
+					assert.Equal(t, time.Hour, apiErr.RetryAfter)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.calls, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestClientTokens(t *testing.T) {
+	key, err := gophermart.NewHS256Key("k1", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	gm := gophermart.New(basicstorage.New(), gophermart.WithJWT([]*gophermart.SigningKey{key}, 0, 0))
+	srv := httptest.NewServer(handlers.New(gm).GetRouter())
+	defer srv.Close()
+
+	ctx := context.Background()
+	require.NoError(t, New(srv.URL).Register(ctx, "gopher", "Passw0rd33"))
+
+	// клиент без куки сессии работает только по токену доступа
+	c := New(srv.URL)
+	pair, err := c.IssueTokens(ctx, "gopher", "Passw0rd33")
+	require.NoError(t, err)
+	_, err = c.GetBalance(ctx)
+	require.NoError(t, err)
+
+	refreshed, err := c.RefreshTokens(ctx, pair.RefreshToken)
+	require.NoError(t, err)
+	_, err = c.RefreshTokens(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	require.NoError(t, c.RevokeTokens(ctx, refreshed.RefreshToken))
+	_, err = c.GetBalance(ctx)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
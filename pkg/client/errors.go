@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Ошибки, соответствующие кодам ответа API; проверять следует через errors.Is
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrNotEnoughFunds  = errors.New("not enough funds on account")
+	ErrForbidden       = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrGone            = errors.New("gone")
+	ErrUnprocessable   = errors.New("unprocessable entity")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrServer          = errors.New("server error")
+
+	// ErrInvalidOrderNumber номер заказа не прошёл проверку по алгоритму Луна, запрос не отправлялся
+	ErrInvalidOrderNumber = errors.New("invalid order number")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusPaymentRequired:     ErrNotEnoughFunds,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusConflict:            ErrConflict,
+	http.StatusGone:                ErrGone,
+	http.StatusUnprocessableEntity: ErrUnprocessable,
+	http.StatusTooManyRequests:     ErrTooManyRequests,
+}
+
+// Error неуспешный ответ API с кодом и сообщением сервера; RetryAfter — через сколько сервер
+// разрешает повторить запрос, если он это сообщил
+type Error struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+}
+
+func newError(statusCode int, body []byte) *Error {
+	e := &Error{StatusCode: statusCode}
+
+	// сервер описывает ошибку телом {"Error": "...", "StatusCode": ...}
+	var eb struct {
+		Error string
+	}
+	if err := json.Unmarshal(body, &eb); err == nil && eb.Error != "" {
+		e.Message = eb.Error
+	} else {
+		e.Message = http.StatusText(e.StatusCode)
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gophermart: %d %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	if err, ok := statusErrors[e.StatusCode]; ok {
+		return err
+	}
+	if e.StatusCode >= http.StatusInternalServerError {
+		return ErrServer
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Hold резервирует баллы под оплату заказа до списания или возврата
+func (c *Client) Hold(ctx context.Context, order string, sum Amount) (*Hold, error) {
+	if err := checkOrder(order); err != nil {
+		return nil, err
+	}
+
+	hold := &Hold{}
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&Hold{Order: order, Sum: sum}).
+		SetResult(hold)
+	if _, err := do(req, http.MethodPost, "/api/user/balance/holds"); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// CaptureHold списывает зарезервированные баллы; для просроченного резерва возвращает ErrGone
+func (c *Client) CaptureHold(ctx context.Context, holdID uint64) (*Hold, error) {
+	return c.finishHold(ctx, holdID, "capture")
+}
+
+// ReleaseHold возвращает зарезервированные баллы на счёт
+func (c *Client) ReleaseHold(ctx context.Context, holdID uint64) (*Hold, error) {
+	return c.finishHold(ctx, holdID, "release")
+}
+
+func (c *Client) finishHold(ctx context.Context, holdID uint64, action string) (*Hold, error) {
+	hold := &Hold{}
+	req := c.request(ctx).
+		SetPathParam("holdID", strconv.FormatUint(holdID, 10)).
+		SetResult(hold)
+	if _, err := do(req, http.MethodPost, "/api/user/balance/holds/{holdID}/"+action); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
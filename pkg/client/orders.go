@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OrdersQuery параметры списка заказов, пустые поля не передаются
+type OrdersQuery struct {
+	Statuses []string
+	From     time.Time
+	To       time.Time
+	Sort     string // asc или desc
+	Limit    uint64
+	Cursor   string // курсор следующей страницы из предыдущего ответа
+}
+
+// PostOrder загружает номер заказа; accepted ложно, если этот пользователь уже загружал номер
+func (c *Client) PostOrder(ctx context.Context, number string) (accepted bool, err error) {
+	if err = checkOrder(number); err != nil {
+		return false, err
+	}
+
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeTextPlain).
+		SetBody(number)
+	resp, err := do(req, http.MethodPost, "/api/user/orders")
+	if err != nil {
+		return false, err
+	}
+
+	return resp.StatusCode() == http.StatusAccepted, nil
+}
+
+// PostOrdersBatch загружает пакет номеров; неверные номера сервер отмечает в результате, а не ошибкой
+func (c *Client) PostOrdersBatch(ctx context.Context, numbers []string) ([]*OrderBatchResult, error) {
+	var results []*OrderBatchResult
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(numbers).
+		SetResult(&results)
+	if _, err := do(req, http.MethodPost, "/api/user/orders/batch"); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// GetOrders возвращает страницу заказов и курсор следующей страницы, пустой для последней
+func (c *Client) GetOrders(ctx context.Context, query *OrdersQuery) ([]*Order, string, error) {
+	var orders []*Order
+	req := c.request(ctx).SetResult(&orders)
+	if query != nil {
+		if len(query.Statuses) > 0 {
+			req.SetQueryParam("status", strings.Join(query.Statuses, ","))
+		}
+		setPeriod(req.QueryParam, query.From, query.To)
+		if query.Sort != "" {
+			req.SetQueryParam("sort", query.Sort)
+		}
+		if query.Limit > 0 {
+			req.SetQueryParam("limit", strconv.FormatUint(query.Limit, 10))
+		}
+		if query.Cursor != "" {
+			req.SetQueryParam("cursor", query.Cursor)
+		}
+	}
+
+	resp, err := do(req, http.MethodGet, "/api/user/orders")
+	if err != nil {
+		if errors.Is(err, errNoContent) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	return orders, resp.Header().Get(headerNextCursor), nil
+}
+
+// setPeriod задаёт параметры периода from и to в формате RFC3339
+func setPeriod(q url.Values, from, to time.Time) {
+	if !from.IsZero() {
+		q.Set("from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		q.Set("to", to.Format(time.RFC3339))
+	}
+}
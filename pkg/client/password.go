@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type passwordResetRequest struct {
+	Login string `json:"login"`
+}
+
+type passwordResetConfirmRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePassword меняет пароль; сессии на других устройствах завершаются, этот клиент остаётся в системе
+func (c *Client) ChangePassword(ctx context.Context, oldPassword, newPassword string) error {
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&changePasswordRequest{OldPassword: oldPassword, NewPassword: newPassword})
+	_, err := do(req, http.MethodPost, "/api/user/password")
+
+	return err
+}
+
+// RequestPasswordReset запрашивает токен сброса пароля; сервер отвечает одинаково и для неизвестного логина
+func (c *Client) RequestPasswordReset(ctx context.Context, login string) error {
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&passwordResetRequest{Login: login})
+	_, err := do(req, http.MethodPost, "/api/user/password/reset")
+
+	return err
+}
+
+// ResetPassword задаёт новый пароль по токену сброса; после сброса нужно войти заново
+func (c *Client) ResetPassword(ctx context.Context, token, newPassword string) error {
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&passwordResetConfirmRequest{Token: token, NewPassword: newPassword})
+	_, err := do(req, http.MethodPost, "/api/user/password/reset/confirm")
+
+	return err
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// GetSessions действующие сессии пользователя на всех устройствах
+func (c *Client) GetSessions(ctx context.Context) ([]*Session, error) {
+	var sessions []*Session
+	_, err := do(c.request(ctx).SetResult(&sessions), http.MethodGet, "/api/user/sessions")
+	if err != nil && !errors.Is(err, errNoContent) {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию по идентификатору из GetSessions
+func (c *Client) RevokeSession(ctx context.Context, id string) error {
+	_, err := do(c.request(ctx).SetPathParam("sessionID", id), http.MethodDelete, "/api/user/sessions/{sessionID}")
+
+	return err
+}
+
+// RevokeAllSessions выход на всех устройствах, включая этот клиент
+func (c *Client) RevokeAllSessions(ctx context.Context) error {
+	_, err := do(c.request(ctx), http.MethodDelete, "/api/user/sessions")
+
+	return err
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ExportQuery параметры выгрузки истории, пустые поля не передаются
+type ExportQuery struct {
+	Format string // json или csv, по умолчанию json
+	From   time.Time
+	To     time.Time
+}
+
+// stream выполняет GET-запрос, ответ которого читается дольше общего таймаута клиента:
+// используем те же транспорт и cookie jar, но без таймаута, ограничивая запрос только контекстом
+func (c *Client) stream(ctx context.Context, path string, query url.Values, admin bool) (*http.Response, error) {
+	hc := *c.client.GetClient()
+	hc.Timeout = 0
+
+	target := c.client.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case admin:
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	case c.client.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.client.Token)
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newError(resp.StatusCode, body)
+	}
+
+	return resp, nil
+}
+
+// Export построчно записывает историю заказов и списаний пользователя в w
+func (c *Client) Export(ctx context.Context, query *ExportQuery, w io.Writer) error {
+	return c.export(ctx, "/api/user/export", query, w, false)
+}
+
+func (c *Client) export(ctx context.Context, path string, query *ExportQuery, w io.Writer, admin bool) error {
+	q := url.Values{}
+	if query != nil {
+		if query.Format != "" {
+			q.Set("format", query.Format)
+		}
+		setPeriod(q, query.From, query.To)
+	}
+
+	resp, err := c.stream(ctx, path, q, admin)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("export interrupted - %w", err)
+	}
+
+	return nil
+}
+
+// Events читает поток уведомлений об изменении заказов и баланса и передаёт каждое в fn,
+// пока не будет отменён контекст, fn не вернёт ошибку или сервер не закроет поток по истечении сессии
+func (c *Client) Events(ctx context.Context, fn func(*Notification) error) error {
+	resp, err := c.stream(ctx, "/api/user/events", nil, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		// тип события дублируется в данных, поэтому достаточно строк `data:`; комментарии-пульс пропускаем
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		n := &Notification{}
+		if err = json.Unmarshal([]byte(data), n); err != nil {
+			return fmt.Errorf("failed to unmarshal event - %w", err)
+		}
+		if err = fn(n); err != nil {
+			return err
+		}
+	}
+
+	if err = scanner.Err(); err != nil && !errors.Is(err, ctx.Err()) {
+		return err
+	}
+
+	return ctx.Err()
+}
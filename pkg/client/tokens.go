@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// IssueTokens получает токен доступа и refresh-токен (режим JWT на сервере) и дальше авторизует запросы
+// токеном доступа вместо куки сессии
+func (c *Client) IssueTokens(ctx context.Context, login, password string) (*TokenPair, error) {
+	return c.tokens(ctx, "/api/user/token", &Credentials{Login: login, Password: password})
+}
+
+// RefreshTokens меняет refresh-токен на новую пару; прежний refresh-токен после этого недействителен
+func (c *Client) RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	return c.tokens(ctx, "/api/user/token/refresh", &refreshTokenRequest{RefreshToken: refreshToken})
+}
+
+// RevokeTokens отзывает семейство refresh-токенов и забывает токен доступа
+func (c *Client) RevokeTokens(ctx context.Context, refreshToken string) error {
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&refreshTokenRequest{RefreshToken: refreshToken})
+	if _, err := do(req, http.MethodPost, "/api/user/token/revoke"); err != nil {
+		return err
+	}
+	c.SetAccessToken("")
+
+	return nil
+}
+
+// SetAccessToken задаёт токен доступа для последующих запросов, пустой токен возвращает авторизацию по куке
+func (c *Client) SetAccessToken(token string) {
+	c.client.SetAuthToken(token)
+}
+
+func (c *Client) tokens(ctx context.Context, url string, body interface{}) (*TokenPair, error) {
+	pair := &TokenPair{}
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(body).
+		SetResult(pair)
+	if _, err := do(req, http.MethodPost, url); err != nil {
+		return nil, err
+	}
+	c.SetAccessToken(pair.AccessToken)
+
+	return pair, nil
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+var (
+	headerEnd           = []byte("\r\n\r\n")
+	headerContentLength = []byte("\r\ncontent-length:")
+	zeroContentLength   = []byte("Content-Length: 0\r\n")
+)
+
+// newTransport транспорт HTTP/1.1, передающий у GET-запросов заголовок `Content-Length: 0`, как требует API.
+// net/http у запросов без тела этот заголовок не отправляет ни при каких настройках запроса,
+// поэтому он дописывается в заголовки запроса при записи в соединение
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	// соединения свои, а HTTP/2 собирает заголовки сам
+	t.ForceAttemptHTTP2 = false
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &contentLengthConn{Conn: conn}, nil
+	}
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return &contentLengthConn{Conn: tlsConn}, nil
+	}
+
+	return t
+}
+
+// contentLengthConn дописывает `Content-Length: 0` в GET-запрос без этого заголовка. Транспорт записывает
+// заголовки запроса одним вызовом Write, если они помещаются в его буфер в 4 КБ
+type contentLengthConn struct {
+	net.Conn
+}
+
+func (c *contentLengthConn) Write(p []byte) (int, error) {
+	if !bytes.HasPrefix(p, []byte(http.MethodGet+" ")) {
+		return c.Conn.Write(p)
+	}
+	end := bytes.Index(p, headerEnd)
+	if end < 0 || bytes.Contains(bytes.ToLower(p[:end+2]), headerContentLength) {
+		return c.Conn.Write(p)
+	}
+
+	patched := make([]byte, 0, len(p)+len(zeroContentLength))
+	patched = append(patched, p[:end+2]...)
+	patched = append(patched, zeroContentLength...)
+	patched = append(patched, p[end+2:]...)
+	if _, err := c.Conn.Write(patched); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// retryAfter пауза из заголовка `Retry-After`: число секунд или дата
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// retryAfterWait пауза перед повтором по заголовку `Retry-After`; без заголовка resty ждёт по экспоненте
+func retryAfterWait(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	wait, _ := retryAfter(resp.Header())
+	return wait, nil
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Register регистрирует пользователя и сразу открывает для него сессию
+func (c *Client) Register(ctx context.Context, login, password string) error {
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&Credentials{Login: login, Password: password})
+	_, err := do(req, http.MethodPost, "/api/user/register")
+
+	return err
+}
+
+func (c *Client) Login(ctx context.Context, login, password string) error {
+	req := c.request(ctx).
+		SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&Credentials{Login: login, Password: password})
+	_, err := do(req, http.MethodPost, "/api/user/login")
+
+	return err
+}
+
+// Logout завершает сессию: сервер отзывает куку, и cookie jar её удаляет
+func (c *Client) Logout(ctx context.Context) error {
+	_, err := do(c.request(ctx), http.MethodPost, "/api/user/logout")
+
+	return err
+}
+
+func (c *Client) Welcome(ctx context.Context) (string, error) {
+	resp, err := do(c.request(ctx), http.MethodGet, "/api/user/welcome")
+	if err != nil {
+		return "", err
+	}
+
+	return resp.String(), nil
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// CreateWebhook подписывает URL на события пользователя, без перечисления событий — на все;
+// секрет подписи доставок возвращается только здесь
+func (c *Client) CreateWebhook(ctx context.Context, url string, events ...string) (*Webhook, error) {
+	return createWebhook(c.request(ctx), "/api/user", url, events)
+}
+
+func (c *Client) GetWebhooks(ctx context.Context) ([]*Webhook, error) {
+	return getWebhooks(c.request(ctx), "/api/user")
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID uint64) error {
+	return deleteWebhook(c.request(ctx), "/api/user", webhookID)
+}
+
+func (c *Client) GetWebhookDeliveries(ctx context.Context, webhookID uint64) ([]*WebhookAttempt, error) {
+	return getWebhookDeliveries(c.request(ctx), "/api/user", webhookID)
+}
+
+// общие для пользовательских и глобальных подписок запросы отличаются только префиксом маршрута
+
+func createWebhook(req *resty.Request, prefix, url string, events []string) (*Webhook, error) {
+	if events == nil {
+		events = []string{}
+	}
+
+	webhook := &Webhook{}
+	req.SetHeader("Content-Type", contentTypeJSON).
+		SetBody(&Webhook{URL: url, Events: events}).
+		SetResult(webhook)
+	if _, err := do(req, http.MethodPost, prefix+"/webhooks"); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func getWebhooks(req *resty.Request, prefix string) ([]*Webhook, error) {
+	var webhooks []*Webhook
+	_, err := do(req.SetResult(&webhooks), http.MethodGet, prefix+"/webhooks")
+	if err != nil && !errors.Is(err, errNoContent) {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func deleteWebhook(req *resty.Request, prefix string, webhookID uint64) error {
+	req.SetPathParam("webhookID", strconv.FormatUint(webhookID, 10))
+	_, err := do(req, http.MethodDelete, prefix+"/webhooks/{webhookID}")
+
+	return err
+}
+
+func getWebhookDeliveries(req *resty.Request, prefix string, webhookID uint64) ([]*WebhookAttempt, error) {
+	var attempts []*WebhookAttempt
+	req.SetPathParam("webhookID", strconv.FormatUint(webhookID, 10)).SetResult(&attempts)
+	_, err := do(req, http.MethodGet, prefix+"/webhooks/{webhookID}/deliveries")
+	if err != nil && !errors.Is(err, errNoContent) {
+		return nil, err
+	}
+
+	return attempts, nil
+}